@@ -1,13 +1,18 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
 	"math"
 	"os"
 	"path"
+	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
-	"syscall"
-	"time"
+	"sync"
 
 	"github.com/skratchdot/open-golang/open"
 	"github.com/veandco/go-sdl2/sdl"
@@ -18,6 +23,7 @@ type ItemType int32
 const (
 	ItemTypeFile ItemType = iota
 	ItemTypeFolder
+	ItemTypeLink
 )
 
 type Favorite struct {
@@ -31,16 +37,26 @@ type Item struct {
 	Name     string
 	IsHidden bool
 
+	LinkTarget   string
+	TargetType   ItemType // Type of the item the link points to
+	IsBrokenLink bool
+
 	IsSelected       bool
 	IsFavorite       bool
 	RenameInProgress bool
 }
 
+// Returns true for folders and for links to folders
+func (item *Item) IsFolder() bool {
+	return item.Type == ItemTypeFolder || item.Type == ItemTypeLink && item.TargetType == ItemTypeFolder && !item.IsBrokenLink
+}
+
 type ItemView struct {
 	Items        []Item
 	ActiveItem   int32
 	ActiveColumn int32
 	CurrentPath  string
+	FS           FileSystem // The archive or remote location the view shows, nil when it shows the local disk
 
 	Favorites []Favorite
 
@@ -64,6 +80,9 @@ type ItemView struct {
 
 	Scrollbar Scrollbar
 
+	CompareStates map[string]CompareState // Set while the view is compared with another one, see App.ToggleComparison
+	VerifyStates  map[string]VerifyState  // Set after the folder was verified against a checksum file
+
 	NormalKeyMap map[byte][]Shortcut
 	GotoKeyMap   map[byte]Shortcut
 }
@@ -96,6 +115,15 @@ func NewItemView(rect sdl.Rect, app *App) (result *ItemView) {
 	result.NormalKeyMap['l'] = []Shortcut{{Ctrl: false, Alt: false, Callback: func() {
 		result.NavigateRight()
 	}}}
+	result.NormalKeyMap['l'] = append(result.NormalKeyMap['l'], Shortcut{Ctrl: true, Alt: false, Callback: func() {
+		result.LinkToOtherView(true)
+	}})
+	result.NormalKeyMap['l'] = append(result.NormalKeyMap['l'], Shortcut{Ctrl: false, Alt: true, Callback: func() {
+		result.ToggleFollowLinks()
+	}})
+	result.NormalKeyMap['L'] = []Shortcut{{Ctrl: false, Alt: false, Callback: func() {
+		result.LinkToOtherView(false)
+	}}}
 	result.NormalKeyMap['G'] = []Shortcut{{Ctrl: false, Alt: false, Callback: func() {
 		result.NavigateLastInColumn()
 	}}}
@@ -118,6 +146,15 @@ func NewItemView(rect sdl.Rect, app *App) (result *ItemView) {
 	result.NormalKeyMap['x'] = append(result.NormalKeyMap['x'], Shortcut{Ctrl: true, Alt: false, Callback: func() {
 		result.ExtractFilesFromFolder()
 	}})
+	result.NormalKeyMap['z'] = []Shortcut{{Ctrl: false, Alt: false, Callback: func() {
+		result.CompressSelected()
+	}}}
+	result.NormalKeyMap['z'] = append(result.NormalKeyMap['z'], Shortcut{Ctrl: false, Alt: true, Callback: func() {
+		result.ExtractActive(true)
+	}})
+	result.NormalKeyMap['Z'] = []Shortcut{{Ctrl: false, Alt: false, Callback: func() {
+		result.ExtractActive(false)
+	}}}
 	result.NormalKeyMap['X'] = []Shortcut{{Ctrl: false, Alt: false, Callback: func() {
 		result.DeleteActiveForced()
 	}}}
@@ -128,15 +165,30 @@ func NewItemView(rect sdl.Rect, app *App) (result *ItemView) {
 			// result.CopySelected()
 		}
 	}}}
+	result.NormalKeyMap['Y'] = []Shortcut{{Ctrl: false, Alt: false, Callback: func() {
+		result.CopyToOtherView()
+	}}}
 	result.NormalKeyMap['p'] = []Shortcut{{Ctrl: false, Alt: false, Callback: func() {
 		result.Paste()
 	}}}
+	result.NormalKeyMap['u'] = []Shortcut{{Ctrl: false, Alt: false, Callback: func() {
+		result.App.Undo()
+	}}}
+	result.NormalKeyMap['U'] = []Shortcut{{Ctrl: false, Alt: false, Callback: func() {
+		result.App.Redo()
+	}}}
 	result.NormalKeyMap['D'] = []Shortcut{{Ctrl: false, Alt: false, Callback: func() {
 		result.DuplicateActive()
 	}}}
 	result.NormalKeyMap['r'] = []Shortcut{{Ctrl: false, Alt: false, Callback: func() {
 		result.RenameActive()
 	}}}
+	result.NormalKeyMap['r'] = append(result.NormalKeyMap['r'], Shortcut{Ctrl: false, Alt: true, Callback: func() {
+		result.BatchRename()
+	}})
+	result.NormalKeyMap['R'] = []Shortcut{{Ctrl: false, Alt: false, Callback: func() {
+		result.BulkRename()
+	}}}
 	result.NormalKeyMap['v'] = []Shortcut{{Ctrl: false, Alt: false, Callback: func() {
 		result.SelectActive()
 	}}}
@@ -158,35 +210,85 @@ func NewItemView(rect sdl.Rect, app *App) (result *ItemView) {
 	result.NormalKeyMap['/'] = []Shortcut{{Ctrl: false, Alt: false, Callback: func() {
 		result.App.FindInCurrentFolder(result.itemsToNames())
 	}}}
+	result.NormalKeyMap['?'] = []Shortcut{{Ctrl: false, Alt: false, Callback: func() {
+		if !result.isNotLocal() {
+			result.App.FindView.Open(result.CurrentPath)
+		}
+	}}}
 	result.NormalKeyMap['H'] = []Shortcut{{Ctrl: false, Alt: false, Callback: func() {
 		result.ShowHidden = !result.ShowHidden
-		result.ShowFolder(result.CurrentPath)
+		result.Refresh()
 	}}}
 	result.NormalKeyMap['.'] = []Shortcut{{Ctrl: true, Alt: false, Callback: func() {
-		result.App.MoveItemToNextView(result.Items[result.ActiveItem].Name, result.CurrentPath, result.Items[result.ActiveItem].Type)
-		result.DeleteActive()
+		if result.ActiveItem < 0 || result.ActiveItem >= int32(len(result.Items)) || result.isReadOnly() {
+			return
+		}
 
-		NotifyInfo("Moved " + path.Join(result.CurrentPath, result.Items[result.ActiveItem].Name))
+		result.App.MoveItemToNextView(result.Items[result.ActiveItem].Name, result.CurrentPath, result.Items[result.ActiveItem].Type, result.FS)
 	}}}
 	result.NormalKeyMap[','] = []Shortcut{{Ctrl: true, Alt: false, Callback: func() {
-		result.App.MoveItemToPrevView(result.Items[result.ActiveItem].Name, result.CurrentPath, result.Items[result.ActiveItem].Type)
-		result.DeleteActive()
+		if result.ActiveItem < 0 || result.ActiveItem >= int32(len(result.Items)) || result.isReadOnly() {
+			return
+		}
 
-		NotifyInfo("Moved " + path.Join(result.CurrentPath, result.Items[result.ActiveItem].Name))
+		result.App.MoveItemToPrevView(result.Items[result.ActiveItem].Name, result.CurrentPath, result.Items[result.ActiveItem].Type, result.FS)
 	}}}
 
 	result.GotoKeyMap = map[byte]Shortcut{}
 	result.GotoKeyMap['d'] = Shortcut{Ctrl: false, Alt: false, Callback: func() {
 		result.OpenItem(result.Items[result.ActiveItem].Name)
 	}}
+	result.GotoKeyMap['a'] = Shortcut{Ctrl: false, Alt: false, Callback: func() {
+		result.EditAttributes()
+	}}
+	result.GotoKeyMap['c'] = Shortcut{Ctrl: false, Alt: false, Callback: func() {
+		result.App.ToggleComparison(false)
+	}}
+	result.GotoKeyMap['C'] = Shortcut{Ctrl: false, Alt: false, Callback: func() {
+		result.App.ToggleComparison(true)
+	}}
+	result.GotoKeyMap['D'] = Shortcut{Ctrl: false, Alt: false, Callback: func() {
+		if !result.isNotLocal() {
+			result.App.DuplicatesView.Open(result.CurrentPath, result.App)
+		}
+	}}
 	result.GotoKeyMap['g'] = Shortcut{Ctrl: false, Alt: false, Callback: func() {
 		result.NavigateFirstInColumn()
 	}}
 	result.GotoKeyMap['h'] = Shortcut{Ctrl: false, Alt: false, Callback: func() {
 		result.App.ShowFileInfo(result.GetActiveFileInfo())
 	}}
+	result.GotoKeyMap['k'] = Shortcut{Ctrl: false, Alt: false, Callback: func() {
+		result.ShowChecksums()
+	}}
+	result.GotoKeyMap['K'] = Shortcut{Ctrl: false, Alt: false, Callback: func() {
+		result.VerifyChecksums()
+	}}
+	result.GotoKeyMap['l'] = Shortcut{Ctrl: false, Alt: false, Callback: func() {
+		result.RevealLinkTarget()
+	}}
+	result.GotoKeyMap['o'] = Shortcut{Ctrl: false, Alt: false, Callback: func() {
+		result.App.OpenLocationPrompt()
+	}}
+	result.GotoKeyMap['s'] = Shortcut{Ctrl: false, Alt: false, Callback: func() {
+		result.App.SyncWithOtherView(false)
+	}}
+	result.GotoKeyMap['S'] = Shortcut{Ctrl: false, Alt: false, Callback: func() {
+		result.App.SyncWithOtherView(true)
+	}}
+	result.GotoKeyMap['t'] = Shortcut{Ctrl: false, Alt: false, Callback: func() {
+		result.App.TrashView.Open()
+	}}
+	result.GotoKeyMap['u'] = Shortcut{Ctrl: false, Alt: false, Callback: func() {
+		if !result.isNotLocal() {
+			result.App.DiskUsageView.Open(result.CurrentPath, result.App)
+		}
+	}}
+	result.GotoKeyMap['v'] = Shortcut{Ctrl: false, Alt: false, Callback: func() {
+		result.SelectDifferences()
+	}}
 	result.GotoKeyMap['y'] = Shortcut{Ctrl: false, Alt: false, Callback: func() {
-		result.App.ShowPreview(result.CurrentPath, result.Items[result.ActiveItem].Name)
+		result.App.ShowPreview(result.FS, result.CurrentPath, result.Items[result.ActiveItem].Name)
 	}}
 
 	return
@@ -196,7 +298,7 @@ func (iv *ItemView) SetActiveByName(name string) {
 	for index, item := range iv.Items {
 		if item.Name == name {
 			iv.ActiveItem = int32(index)
-			iv.ActiveColumn = int32(index) / iv.MaxItemsPerColumn
+			iv.scrollToActive(iv.getFirstVisibleColumn())
 			break
 		}
 	}
@@ -206,44 +308,119 @@ func (iv *ItemView) SetFavorites(favorites []string) {
 	iv.Favorites = make([]Favorite, 0)
 
 	for _, favorite := range favorites {
+		// Remote favorites are only checked once they are opened, connecting to every host here would take too long
+		itemType := ItemTypeFolder
+		if !IsRemoteLocation(favorite) {
+			itemType = GetItemType(favorite)
+		}
+
 		iv.Favorites = append(iv.Favorites, Favorite{
 			FullPath: favorite,
-			Type:     GetItemType(favorite),
+			Type:     itemType,
 		})
 	}
 
+	iv.updateFavorites()
+}
+
+func (iv *ItemView) updateFavorites() {
 	for index, item := range iv.Items {
-		iv.Items[index].IsFavorite = iv.favoriteIndex(path.Join(iv.CurrentPath, item.Name)) >= 0
+		favoritePath := iv.getFavoritePath(item.Name)
+		iv.Items[index].IsFavorite = favoritePath != "" && iv.favoriteIndex(favoritePath) >= 0
 	}
 }
 
+// Shows a different folder, starting at its first item
 func (iv *ItemView) ShowFolder(fullPath string) bool {
-	items, success := ReadDirectory(fullPath)
+	items, success := iv.listFolder(fullPath)
 	if !success {
 		return false
 	}
 
+	iv.Items = items
+	iv.CurrentPath = fullPath
+	iv.updateFavorites()
+	iv.updateColumns()
+
+	iv.ActiveItem = 0
+	iv.scrollToActive(0)
+
+	// The comparison and the verification were made for the folder that was shown before
+	if iv.App.Comparison.Includes(iv) {
+		iv.App.EndComparison()
+	}
+	iv.VerifyStates = nil
+
+	return true
+}
+
+// Reads the items of the folder, sorted the way they are shown
+func (iv *ItemView) listFolder(fullPath string) ([]Item, bool) {
+	items, success := iv.readFolder(fullPath)
+	if !success {
+		return nil, false
+	}
+
 	var files []Item
 	var folders []Item
 
 	for _, file := range items {
-		hidden := IsFileHidden(path.Join(fullPath, file.Name()))
+		var hidden bool
+		if iv.FS != nil {
+			hidden = strings.HasPrefix(file.Name(), ".")
+		} else {
+			hidden = IsFileHidden(path.Join(fullPath, file.Name()))
+		}
 
 		if !iv.ShowHidden && hidden {
 			continue
 		}
 
-		if file.IsDir() {
+		// Links inside of archives can't be followed, so they are shown as files
+		if file.Type()&fs.ModeSymlink != 0 && (iv.FS == nil || canFollowLinks(iv.FS)) {
+			var target string
+			var targetType ItemType
+			var broken bool
+			if iv.FS == nil {
+				target, targetType, broken = ReadLink(path.Join(fullPath, file.Name()))
+			} else {
+				target, targetType, broken = ReadLinkInFileSystem(iv.FS, path.Join(fullPath, file.Name()))
+			}
+
+			item := Item{
+				Type:         ItemTypeLink,
+				Name:         file.Name(),
+				IsHidden:     hidden,
+				LinkTarget:   target,
+				TargetType:   targetType,
+				IsBrokenLink: broken,
+			}
+
+			// Links are listed together with the kind of item they point to
+			if targetType == ItemTypeFolder {
+				folders = append(folders, item)
+			} else {
+				files = append(files, item)
+			}
+		} else if file.IsDir() {
 			folders = append(folders, Item{
 				Type:     ItemTypeFolder,
 				Name:     file.Name(),
 				IsHidden: hidden,
 			})
 		} else {
+			fileType := FileType(GetFileType(file.Name()))
+			if fileType == FileTypeDefault {
+				info, err := file.Info()
+				if err == nil && IsExecutable(file.Name(), info.Mode()) {
+					fileType = FileTypeExe
+				}
+			}
+
 			files = append(files, Item{
 				Type:     ItemTypeFile,
 				Name:     file.Name(),
-				FileType: FileType(GetFileType(file.Name())),
+				FileType: fileType,
 				IsHidden: hidden,
 			})
 		}
@@ -257,322 +434,1423 @@ func (iv *ItemView) ShowFolder(fullPath string) bool {
 		return strings.ToLower(folders[i].Name) < strings.ToLower(folders[j].Name)
 	})
 
-	iv.Items = make([]Item, 0)
-	iv.Items = append(iv.Items, folders...)
-	iv.Items = append(iv.Items, files...)
+	result := make([]Item, 0, len(folders)+len(files))
+	result = append(result, folders...)
+	result = append(result, files...)
 
-	for index, item := range iv.Items {
-		iv.Items[index].IsFavorite = iv.favoriteIndex(path.Join(fullPath, item.Name)) >= 0
-	}
+	return result, true
+}
 
+func (iv *ItemView) updateColumns() {
 	cols := float64(len(iv.Items)) / float64(iv.MaxItemsPerColumn)
 	iv.Columns = int32(math.Ceil(cols))
-
-	iv.ActiveItem = 0
-	iv.CurrentPath = fullPath
-
-	return true
 }
 
-func (iv *ItemView) Refresh() {
-	iv.ShowFolder(iv.CurrentPath)
-}
-
-func (iv *ItemView) GetActiveFileInfo() (result Info) {
-	item := iv.Items[iv.ActiveItem]
+func (iv *ItemView) readFolder(fullPath string) ([]fs.DirEntry, bool) {
+	if iv.FS == nil {
+		return ReadDirectory(fullPath)
+	}
 
-	fullPath := path.Join(iv.CurrentPath, item.Name)
-	stats, err := os.Stat(fullPath)
+	infos, err := iv.FS.ReadDir(fullPath)
 	if err != nil {
 		NotifyError(err.Error())
+		return nil, false
 	}
 
-	result.Name = iv.Items[iv.ActiveItem].Name
-
-	if item.Type == ItemTypeFolder {
-		result.Size = "Calculating..."
-
-		// Getting the directory size might take a lot of time, therefore, we do it in a goroutine to prevent blocking the interface.
-		// When the directory size is finally calculated, it will be sent directly to the InfoView.
-		go func() {
-			size := bytesToString(GetDirectorySize(path.Join(iv.CurrentPath, item.Name)))
-			iv.App.SetFileInfoSize(size)
-		}()
-	} else {
-		result.Size = bytesToString(stats.Size())
+	result := make([]fs.DirEntry, len(infos))
+	for index, info := range infos {
+		result[index] = fs.FileInfoToDirEntry(info)
 	}
 
-	result.Created = time.Unix(0, stats.Sys().(*syscall.Win32FileAttributeData).CreationTime.Nanoseconds()).Format("2006-01-02 15:05:05")
-	result.Modified = stats.ModTime().Format("2006-01-02 15:04:05")
-
-	return
+	return result, true
 }
 
-func (iv *ItemView) OpenFavorite(fullPath string) {
-	index := iv.favoriteIndex(fullPath)
-	if index < 0 {
-		return
-	}
-
-	favorite := iv.Favorites[index]
-	if favorite.Type == ItemTypeFolder {
-		iv.App.Breadcrumbs[iv.App.ActiveView].Set(fullPath)
-		iv.ShowFolder(fullPath)
-	} else if favorite.Type == ItemTypeFile {
-		p := path.Dir(fullPath)
-
-		if strings.HasSuffix(p, ":") {
-			p += "/"
+// Reads the folder again and merges the new list into the current items. The cursor stays on the same item, or
+// where it was if the item is gone, selected items stay selected, the view only scrolls if the cursor would end up
+// outside of it and an item that is being renamed keeps its input.
+func (iv *ItemView) Refresh() {
+	if iv.FS == nil {
+		_, err := os.Stat(iv.CurrentPath)
+		if err != nil {
+			iv.showClosestExistingFolder()
+			return
 		}
+	}
 
-		iv.App.Breadcrumbs[iv.App.ActiveView].Set(p)
-		iv.ShowFolder(p)
+	items, success := iv.listFolder(iv.CurrentPath)
+	if !success {
+		return
+	}
 
-		base := path.Base(fullPath)
+	iv.mergeItems(items)
 
-		iv.SetActiveByName(base)
+	if iv.App.Comparison.Includes(iv) {
+		iv.App.runComparison()
 	}
 }
 
-func (iv *ItemView) OpenItem(name string) {
-	iv.SetActiveByName(name)
-
-	if iv.Items[iv.ActiveItem].Type == ItemTypeFolder {
-		iv.OpenFolder(name)
-	} else if iv.Items[iv.ActiveItem].Type == ItemTypeFile {
-		iv.OpenFile(name)
+// Used when the folder of the view was removed
+func (iv *ItemView) showClosestExistingFolder() {
+	parent := iv.CurrentPath
+	_, err := os.Stat(parent)
+	for err != nil && getParentPath(parent) != parent {
+		parent = getParentPath(parent)
+		_, err = os.Stat(parent)
 	}
-}
 
-func (iv *ItemView) OpenFolder(name string) {
-	iv.SetActiveByName(name)
+	iv.getBreadcrumbs().Set(parent)
+	iv.ShowFolder(parent)
+}
 
-	activeFolder := iv.GoInside()
-	if activeFolder != "" {
-		iv.App.Breadcrumbs[iv.App.ActiveView].Push(activeFolder)
+func (iv *ItemView) mergeItems(items []Item) {
+	activeName := ""
+	if iv.ActiveItem >= 0 && iv.ActiveItem < int32(len(iv.Items)) {
+		activeName = iv.Items[iv.ActiveItem].Name
 	}
-}
 
-func (iv *ItemView) OpenFile(name string) {
-	open.Start(path.Join(iv.CurrentPath, name))
-}
+	selectionStartName := ""
+	if iv.SelectionMode && iv.SelectionStart >= 0 && iv.SelectionStart < int32(len(iv.Items)) {
+		selectionStartName = iv.Items[iv.SelectionStart].Name
+	}
 
-func (iv *ItemView) NavigateDown() {
-	if iv.ActiveItem < int32(len(iv.Items)-1) && iv.ActiveItem < iv.MaxItemsPerColumn*(iv.ActiveColumn+1)-1 {
-		iv.ActiveItem++
-		iv.updateSelection()
+	previous := make(map[string]Item, len(iv.Items))
+	for _, item := range iv.Items {
+		previous[item.Name] = item
 	}
-}
 
-func (iv *ItemView) NavigateUp() {
-	if iv.ActiveItem > iv.MaxItemsPerColumn*iv.ActiveColumn {
-		iv.ActiveItem--
-		iv.updateSelection()
+	found := false
+	for index, item := range items {
+		old, ok := previous[item.Name]
+		if !ok {
+			continue
+		}
+
+		items[index].IsSelected = old.IsSelected
+		items[index].RenameInProgress = old.RenameInProgress
+
+		if item.Name == activeName {
+			found = true
+		}
 	}
-}
 
-func (iv *ItemView) NavigateRight() {
-	if iv.ActiveColumn >= iv.Columns-1 {
-		return
+	// The rename would be applied to whatever item ends up under the cursor, so it's cancelled while the old items are
+	// still there
+	if !found && iv.Input.IsOpen && activeName != "" && iv.Items[iv.ActiveItem].RenameInProgress {
+		iv.Input.Cancel()
 	}
 
-	iv.ActiveColumn++
+	firstColumn := iv.getFirstVisibleColumn()
+	lastActive := iv.ActiveItem
 
-	iv.ActiveViewportColumn++
-	if iv.ActiveViewportColumn == iv.MaxViewportColumns {
-		iv.ActiveViewportColumn = iv.MaxViewportColumns - 1
-		iv.ScrollOffset -= iv.ItemWidth
+	iv.Items = items
+	iv.updateFavorites()
+	iv.updateColumns()
+
+	iv.restoreActive(lastActive)
+	for index, item := range iv.Items {
+		if item.Name == activeName {
+			iv.ActiveItem = int32(index)
+		}
+
+		if item.Name == selectionStartName {
+			iv.SelectionStart = int32(index)
+		}
 	}
 
-	if iv.ActiveItem+iv.MaxItemsPerColumn >= int32(len(iv.Items)) {
-		iv.ActiveItem = int32(len(iv.Items) - 1)
-	} else {
-		iv.ActiveItem += iv.MaxItemsPerColumn
+	if iv.SelectionStart >= int32(len(iv.Items)) {
+		iv.SelectionStart = iv.ActiveItem
 	}
 
-	iv.updateSelection()
+	iv.scrollToActive(firstColumn)
 }
 
-func (iv *ItemView) NavigateLeft() {
-	if iv.ActiveColumn <= 0 {
-		return
+func (iv *ItemView) getFirstVisibleColumn() int32 {
+	if iv.ItemWidth == 0 {
+		return 0
 	}
 
-	iv.ActiveColumn--
-	iv.ActiveItem -= iv.MaxItemsPerColumn
+	return -iv.ScrollOffset / iv.ItemWidth
+}
 
-	iv.ActiveViewportColumn--
-	if iv.ActiveViewportColumn < 0 {
-		iv.ActiveViewportColumn = 0
-		iv.ScrollOffset += iv.ItemWidth
+// Scrolls the view as little as possible from the given first visible column, so that the active item is visible
+func (iv *ItemView) scrollToActive(firstColumn int32) {
+	iv.ActiveColumn = 0
+	if iv.ActiveItem > 0 {
+		iv.ActiveColumn = iv.ActiveItem / iv.MaxItemsPerColumn
 	}
 
-	iv.updateSelection()
+	visibleColumns := max(iv.MaxViewportColumns, 1)
+	if iv.ActiveColumn < firstColumn {
+		firstColumn = iv.ActiveColumn
+	} else if iv.ActiveColumn >= firstColumn+visibleColumns {
+		firstColumn = iv.ActiveColumn - visibleColumns + 1
+	}
+
+	// Items might have been removed from the end, which would leave empty columns on the right
+	firstColumn = max(min(firstColumn, iv.Columns-visibleColumns), 0)
+
+	iv.ActiveViewportColumn = iv.ActiveColumn - firstColumn
+	iv.ScrollOffset = -firstColumn * iv.ItemWidth
 }
 
-func (iv *ItemView) NavigateFirstInColumn() {
-	iv.ActiveItem = iv.ActiveColumn * iv.MaxItemsPerColumn
-	iv.updateSelection()
+// Reads the list of entries of the archive in a job and shows its root once it's done
+func (iv *ItemView) OpenArchive(name string) {
+	archivePath := path.Join(iv.CurrentPath, name)
+
+	var archive *ArchiveFileSystem
+	iv.App.Jobs.Add("Opening "+archivePath, func(job *Job) (err error) {
+		archive, err = OpenArchive(archivePath)
+		return
+	}, func(err error) {
+		if err != nil || iv.FS != nil || iv.CurrentPath != getParentPath(archivePath) {
+			// The user went somewhere else in the meantime
+			return
+		}
+
+		iv.FS = archive
+		iv.ShowFolder("/")
+		iv.getBreadcrumbs().Push(name)
+	})
 }
 
-func (iv *ItemView) NavigateLastInColumn() {
-	iv.ActiveItem = iv.ActiveColumn*iv.MaxItemsPerColumn + iv.MaxItemsPerColumn - 1
-	if iv.ActiveItem >= int32(len(iv.Items)) {
-		iv.ActiveItem = int32(len(iv.Items) - 1)
+// The breadcrumbs that belong to this view, which isn't necessarily the active one when a job finishes
+func (iv *ItemView) getBreadcrumbs() *Breadcrumbs {
+	for index, view := range iv.App.ItemViews {
+		if view == iv {
+			return &iv.App.Breadcrumbs[index]
+		}
 	}
 
-	iv.updateSelection()
+	return &iv.App.Breadcrumbs[iv.App.ActiveView]
 }
 
-func (iv *ItemView) GoInside() (result string) {
-	if iv.ActiveItem < 0 || iv.ActiveItem >= int32(len(iv.Items)) {
+// Goes back to the folder that contains the archive the view is inside of
+func (iv *ItemView) LeaveArchive() {
+	if _, ok := iv.FS.(*ArchiveFileSystem); !ok {
 		return
 	}
 
-	item := iv.Items[iv.ActiveItem]
+	archivePath := iv.FS.Location()
+	iv.FS = nil
+	iv.ShowFolder(getParentPath(archivePath))
+	iv.SetActiveByName(path.Base(archivePath))
+}
 
-	if item.Type == ItemTypeFolder {
-		iv.ShowFolder(path.Join(iv.CurrentPath, item.Name))
-		result = item.Name
+// Archives are browsed read-only, so the operations that change something check this first
+func (iv *ItemView) isReadOnly() bool {
+	if iv.FS == nil {
+		return false
 	}
 
-	return
-}
+	_, writable := iv.FS.(WritableFileSystem)
+	if writable {
+		return false
+	}
 
-func (iv *ItemView) GoOutside() {
-	split := strings.Split(iv.CurrentPath, "/")
-	lastName := split[len(split)-1]
-	iv.CurrentPath = strings.Join(split[:len(split)-1], "/")
+	NotifyError(iv.FS.Location() + " is read-only")
+	return true
+}
 
-	if strings.HasSuffix(iv.CurrentPath, ":") {
-		iv.CurrentPath += "/"
+// Some operations only work with folders on the local disk, e.g. because they need to be undone
+func (iv *ItemView) isNotLocal() bool {
+	if iv.FS == nil {
+		return false
 	}
 
-	iv.ShowFolder(iv.CurrentPath)
-	iv.SetActiveByName(lastName)
+	NotifyError("Not available in " + iv.FS.Location())
+	return true
 }
 
-func (iv *ItemView) MarkActiveAsFavorite() {
-	if iv.Items[iv.ActiveItem].IsFavorite {
-		iv.Items[iv.ActiveItem].IsFavorite = false
-
-		fullPath := path.Join(iv.CurrentPath, iv.Items[iv.ActiveItem].Name)
-		iv.RemoveFavorite(fullPath)
-		iv.App.Settings.RemoveFavorite(fullPath)
-	} else {
-		item := iv.Items[iv.ActiveItem]
-		iv.Items[iv.ActiveItem].IsFavorite = true
+// Shows a folder inside of a file system, e.g. a remote location. The view stays where it was if the folder can't
+// be read.
+func (iv *ItemView) ShowFileSystem(fsys FileSystem, fullPath string) bool {
+	previous := iv.FS
 
-		fullPath := path.Join(iv.CurrentPath, item.Name)
-		iv.Favorites = append(iv.Favorites, Favorite{FullPath: fullPath, Type: item.Type})
-		iv.App.Settings.AddFavorite(fullPath)
+	iv.FS = fsys
+	if !iv.ShowFolder(fullPath) {
+		iv.FS = previous
+		return false
 	}
+
+	iv.getBreadcrumbs().SetInFileSystem(fsys.Location(), fullPath)
+	return true
 }
 
-func (iv *ItemView) DeleteActive() {
-	if iv.ActiveItem < 0 || iv.ActiveItem >= int32(len(iv.Items)) {
-		return
+// The path the item is saved under in the favorites. Items inside of archives can't be favorites.
+func (iv *ItemView) getFavoritePath(name string) string {
+	if iv.FS == nil {
+		return path.Join(iv.CurrentPath, name)
 	}
 
-	err := os.Remove(path.Join(iv.CurrentPath, iv.Items[iv.ActiveItem].Name))
-	if err != nil {
-		NotifyError(err.Error())
-		return
+	if _, writable := iv.FS.(WritableFileSystem); !writable {
+		return ""
 	}
 
-	lastActive := iv.ActiveItem
+	return GetFileSystemPath(iv.FS, path.Join(iv.CurrentPath, name))
+}
+
+func (iv *ItemView) GetActiveFileInfo() (result Info) {
+	item := iv.Items[iv.ActiveItem]
 
-	// @TODO (!important) not really efficient, better way would probably be to modify the existing items list instead of overriding it
-	iv.ShowFolder(iv.CurrentPath)
-	iv.App.RefreshOtherViews(iv.CurrentPath)
+	fullPath := path.Join(iv.CurrentPath, item.Name)
+	if iv.FS != nil {
+		result = iv.getFileSystemInfo(fullPath)
+		if item.Type == ItemTypeLink && result.Name != "" {
+			result.Add("Target", item.LinkTarget)
+		}
 
-	iv.ActiveItem = lastActive
-	if iv.ActiveItem >= int32(len(iv.Items)) && len(iv.Items) > 0 {
-		iv.ActiveItem = int32(len(iv.Items)) - 1
-	} else if len(iv.Items) == 0 {
-		iv.ActiveItem = -1
+		return
 	}
-}
 
-func (iv *ItemView) DeleteActiveForced() {
-	if iv.ActiveItem < 0 || iv.ActiveItem >= int32(len(iv.Items)) {
-		return
+	stats, err := os.Stat(fullPath)
+	if err != nil && item.Type == ItemTypeLink {
+		// The target of a broken link doesn't exist, so the info is about the link itself
+		stats, err = os.Lstat(fullPath)
 	}
 
-	err := os.RemoveAll(path.Join(iv.CurrentPath, iv.Items[iv.ActiveItem].Name))
 	if err != nil {
 		NotifyError(err.Error())
 		return
 	}
 
-	lastActive := iv.ActiveItem
+	result.Name = iv.Items[iv.ActiveItem].Name
 
-	iv.ShowFolder(iv.CurrentPath)
-	iv.App.RefreshOtherViews(iv.CurrentPath)
+	isText := false
+	if item.IsFolder() {
+		files, folders, err := CountFolderItems(fullPath)
+		if err == nil {
+			result.Add("Contents", formatCount(folders, "folder", "folders")+", "+formatCount(files, "file", "files"))
+		}
+
+		result.Add("Size", "Calculating...")
+
+		// Getting the directory size might take a lot of time, therefore, we do it in a goroutine to prevent blocking the interface.
+		// When the directory size is finally calculated, it is handed over to the InfoView on the main thread.
+		go func() {
+			size := bytesToString(GetDirectorySize(fullPath))
+			iv.App.SetFileInfoRow(result.Name, "Size", size)
+		}()
+	} else {
+		mimeType := DetectMimeType(fullPath)
+		isText = isTextMimeType(mimeType)
+
+		result.Add("Type", mimeType)
+		result.Add("Size", bytesToString(stats.Size()))
+
+		// Only the headers are read, so images and media files don't need another thread
+		if stats.Mode().IsRegular() {
+			AddMediaInfo(&result, fullPath)
+		}
+	}
+
+	result.Add("Modified", stats.ModTime().Format("2006-01-02 15:04:05"))
+
+	created, ok := GetBirthTime(fullPath, stats)
+	if ok {
+		result.Add("Created", created.Format("2006-01-02 15:04:05"))
+	} else {
+		result.Add("Created", "Unknown")
+	}
+
+	if item.Type == ItemTypeLink {
+		target := item.LinkTarget
+		if item.IsBrokenLink {
+			target += " (broken)"
+		}
+
+		result.Add("Target", target)
+	}
+
+	// The permissions only mean something where the items have owners
+	if owner, group, ok := getOwnership(stats); ok {
+		result.Add("Permissions", FormatPermissions(stats.Mode()))
+		result.Add("Owner", owner+":"+group)
+	}
+
+	if identity, ok := getFileIdentity(stats); ok {
+		result.Add("Inode", strconv.FormatUint(identity.Inode, 10))
+		result.Add("Hard links", strconv.FormatUint(identity.Links, 10))
+	}
+
+	if mount, ok := FindMountPoint(fullPath); ok {
+		result.Add("File system", mount.Type+" at "+mount.Path)
+	}
+
+	if isText {
+		result.Add("Lines", "Counting...")
+		result.Add("Encoding", "Detecting...")
+
+		// Like the size of a folder, the whole file has to be read
+		go func() {
+			textStats, err := ReadTextStats(fullPath)
+			if err != nil {
+				iv.App.SetFileInfoRow(result.Name, "Lines", "Unknown")
+				iv.App.SetFileInfoRow(result.Name, "Encoding", "Unknown")
+				return
+			}
+
+			iv.App.SetFileInfoRow(result.Name, "Lines", strconv.FormatInt(textStats.Lines, 10))
+			iv.App.SetFileInfoRow(result.Name, "Encoding", textStats.Encoding)
+		}()
+	}
+
+	return
+}
+
+// Edits the permissions, the owner, the times and the extended attributes of the selected items, or of the active item
+func (iv *ItemView) EditAttributes() {
+	if iv.isNotLocal() {
+		return
+	}
+
+	names := iv.getSelectedItems()
+	if len(names) == 0 {
+		if iv.ActiveItem < 0 || iv.ActiveItem >= int32(len(iv.Items)) {
+			return
+		}
+
+		names = []string{iv.Items[iv.ActiveItem].Name}
+	}
+
+	iv.SelectionMode = false
+	iv.App.AttributesView.Open(iv.CurrentPath, names)
+}
+
+// Computes the checksums of the active file, or of the selected files, and shows them in the info view
+func (iv *ItemView) ShowChecksums() {
+	var names []string
+	for _, item := range iv.Items {
+		if item.IsSelected && !item.IsFolder() {
+			names = append(names, item.Name)
+		}
+	}
+
+	var info Info
+	if len(names) == 0 {
+		if iv.ActiveItem < 0 || iv.ActiveItem >= int32(len(iv.Items)) || iv.Items[iv.ActiveItem].IsFolder() {
+			return
+		}
+
+		names = []string{iv.Items[iv.ActiveItem].Name}
+		info = iv.GetActiveFileInfo()
+	} else {
+		info.Name = fmt.Sprintf("%d files", len(names))
+	}
+
+	info.Checksums = make([]FileChecksums, len(names))
+	for index, name := range names {
+		info.Checksums[index].Name = name
+	}
+
+	iv.SelectionMode = false
+	iv.App.ShowFileInfo(info)
+
+	fsys := iv.FS
+	directory := iv.CurrentPath
+
+	checksums := make([]FileChecksums, len(names))
+	iv.App.Jobs.Add("Computing checksums of "+info.Name, func(job *Job) error {
+		var bytes int64
+		for _, name := range names {
+			if stats, err := iv.statInView(fsys, path.Join(directory, name)); err == nil {
+				bytes += stats.Size()
+			}
+		}
+		job.SetTotal(int64(len(names)), bytes)
+
+		for index, name := range names {
+			checksums[index].Name = name
+
+			digests, err := ComputeChecksums(fsys, path.Join(directory, name), job)
+			if errors.Is(err, ErrJobCancelled) {
+				return err
+			}
+
+			if err != nil {
+				checksums[index].Err = err.Error()
+				continue
+			}
+
+			checksums[index].Digests = digests
+
+			err = job.AddFile()
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, func(err error) {
+		if err == nil {
+			iv.App.SetFileInfoChecksums(info.Name, checksums)
+		}
+	})
+}
+
+func (iv *ItemView) statInView(fsys FileSystem, fullPath string) (fs.FileInfo, error) {
+	if fsys == nil {
+		return os.Stat(fullPath)
+	}
+
+	return fsys.Stat(fullPath)
+}
+
+// Checks the files of the folder against the active checksum file, or against the first checksum file in the folder
+// if another item is active. Every item gets a mark that shows whether it passed.
+func (iv *ItemView) VerifyChecksums() {
+	if iv.isNotLocal() {
+		return
+	}
+
+	name := ""
+	if iv.ActiveItem >= 0 && iv.ActiveItem < int32(len(iv.Items)) && IsChecksumFile(iv.Items[iv.ActiveItem].Name) {
+		name = iv.Items[iv.ActiveItem].Name
+	} else {
+		for _, item := range iv.Items {
+			if !item.IsFolder() && IsChecksumFile(item.Name) {
+				name = item.Name
+				break
+			}
+		}
+	}
+
+	if name == "" {
+		NotifyError("There is no checksum file in " + iv.CurrentPath)
+		return
+	}
+
+	directory := iv.CurrentPath
+	fullPath := path.Join(directory, name)
+
+	var results map[string]VerifyState
+	iv.App.Jobs.Add("Verifying "+fullPath, func(job *Job) error {
+		entries, err := ParseChecksumFile(fullPath)
+		if err != nil {
+			return err
+		}
+
+		results, err = VerifyChecksums(directory, entries, job)
+		return err
+	}, func(err error) {
+		if err != nil {
+			return
+		}
+
+		// Files in subfolders mark the folder they are in, which fails if any of them failed
+		counts := map[VerifyState]int{}
+		states := make(map[string]VerifyState)
+		for relative, state := range results {
+			counts[state]++
+
+			first, _, _ := strings.Cut(path.Clean(relative), "/")
+			if previous, ok := states[first]; !ok || previous == VerifyPassed {
+				states[first] = state
+			}
+		}
+
+		if iv.CurrentPath == directory && iv.FS == nil {
+			iv.VerifyStates = states
+		}
+
+		summary := fmt.Sprintf("%d passed, %d failed, %d missing", counts[VerifyPassed], counts[VerifyFailed], counts[VerifyMissing])
+		if counts[VerifyFailed] > 0 || counts[VerifyMissing] > 0 {
+			NotifyError("Verified " + name + ": " + summary)
+		} else {
+			NotifyInfo("Verified " + name + ": " + summary)
+		}
+	})
+}
+
+// Same as GetActiveFileInfo, for an item inside of a file system. Archives and remote folders don't have the
+// creation time.
+func (iv *ItemView) getFileSystemInfo(fullPath string) (result Info) {
+	stats, err := iv.FS.Stat(fullPath)
+	if err != nil {
+		NotifyError(err.Error())
+		return
+	}
+
+	result.Name = stats.Name()
+
+	if stats.IsDir() {
+		result.Add("Size", "Calculating...")
+
+		// Listing a remote folder takes a round trip for every subfolder
+		fsys := iv.FS
+		go func() {
+			_, bytes := MeasureInFileSystem(fsys, fullPath)
+			iv.App.SetFileInfoRow(result.Name, "Size", bytesToString(bytes))
+		}()
+	} else {
+		// Reading the content would take a download, so the type is only known from the extension
+		result.Add("Type", MimeTypeByExtension(fullPath))
+		result.Add("Size", bytesToString(stats.Size()))
+	}
+
+	// Folders in object storages are only prefixes of the keys, so they don't have a time
+	if stats.ModTime().IsZero() {
+		result.Add("Modified", "Unknown")
+	} else {
+		result.Add("Modified", stats.ModTime().Format("2006-01-02 15:04:05"))
+	}
+
+	if object, ok := stats.(*s3ObjectInfo); ok {
+		result.Add("ETag", object.ETag)
+	}
+
+	return
+}
+
+func (iv *ItemView) OpenFavorite(fullPath string) {
+	index := iv.favoriteIndex(fullPath)
+	if index < 0 {
+		return
+	}
+
+	if IsRemoteLocation(fullPath) {
+		iv.App.GoToLocation(fullPath)
+		return
+	}
+
+	favorite := iv.Favorites[index]
+	if favorite.Type != ItemTypeLink {
+		iv.FS = nil
+	}
+
+	if favorite.Type == ItemTypeFolder {
+		iv.App.Breadcrumbs[iv.App.ActiveView].Set(fullPath)
+		iv.ShowFolder(fullPath)
+	} else if favorite.Type == ItemTypeFile {
+		p := path.Dir(fullPath)
+
+		if strings.HasSuffix(p, ":") {
+			p += "/"
+		}
+
+		iv.App.Breadcrumbs[iv.App.ActiveView].Set(p)
+		iv.ShowFolder(p)
+
+		base := path.Base(fullPath)
+
+		iv.SetActiveByName(base)
+	} else if favorite.Type == ItemTypeLink {
+		NotifyError(fullPath + " is a broken link")
+	}
+}
+
+func (iv *ItemView) OpenItem(name string) {
+	iv.SetActiveByName(name)
+
+	item := iv.Items[iv.ActiveItem]
+	if item.Type == ItemTypeFolder {
+		iv.OpenFolder(name)
+	} else if item.Type == ItemTypeFile && iv.FS == nil && IsArchive(name) {
+		iv.OpenArchive(name)
+	} else if item.Type == ItemTypeFile {
+		iv.OpenFile(name)
+	} else if item.Type == ItemTypeLink {
+		iv.OpenLink(item)
+	}
+}
+
+// Depending on the settings, the link is either opened as if it was the item it points to, or the view jumps to
+// the folder of the target and selects it
+func (iv *ItemView) OpenLink(item Item) {
+	if item.IsBrokenLink {
+		NotifyError(item.Name + " is a broken link to " + item.LinkTarget)
+		return
+	}
+
+	if !iv.App.Settings.FollowLinks {
+		iv.RevealLinkTarget()
+		return
+	}
+
+	if item.TargetType == ItemTypeFolder {
+		iv.ShowFolder(path.Join(iv.CurrentPath, item.Name))
+		iv.App.Breadcrumbs[iv.App.ActiveView].Push(item.Name)
+	} else {
+		iv.OpenFile(item.Name)
+	}
+}
+
+// Goes to the folder that contains the target of the active link and makes the target active
+func (iv *ItemView) RevealLinkTarget() {
+	if iv.ActiveItem < 0 || iv.ActiveItem >= int32(len(iv.Items)) {
+		return
+	}
+
+	item := iv.Items[iv.ActiveItem]
+	if item.Type != ItemTypeLink {
+		return
+	}
+
+	target := GetLinkTargetPath(path.Join(iv.CurrentPath, item.Name), item.LinkTarget)
+	if iv.FS != nil {
+		if !iv.ShowFileSystem(iv.FS, getParentPath(target)) {
+			return
+		}
+	} else if !iv.App.GoToPath(getParentPath(target)) {
+		return
+	}
+
+	iv.SetActiveByName(path.Base(target))
+}
+
+// Creates links to the selected items, or to the active item if nothing is selected, in the folder of the other view
+func (iv *ItemView) LinkToOtherView(hard bool) {
+	other := iv.App.GetOtherView()
+	if other == nil {
+		NotifyError("Open another view to create links in")
+		return
+	}
+
+	if iv.isNotLocal() || other.isNotLocal() {
+		return
+	}
+
+	names := iv.getSelectedItems()
+	if len(names) == 0 {
+		if iv.ActiveItem < 0 || iv.ActiveItem >= int32(len(iv.Items)) {
+			return
+		}
+
+		names = []string{iv.Items[iv.ActiveItem].Name}
+	}
+
+	iv.SelectionMode = false
+	directory := iv.CurrentPath
+	destination := other.CurrentPath
+
+	opType := JournalOpSymlink
+	if hard {
+		opType = JournalOpHardLink
+	}
+
+	var ops []JournalOp
+	iv.App.Jobs.Add("Linking items from "+directory, func(job *Job) error {
+		resolver := iv.App.NewConflictResolver()
+
+		var errs []error
+		for _, name := range names {
+			source := path.Join(directory, name)
+
+			target, resolveOps, err := resolver.Resolve(source, path.Join(destination, name))
+			ops = append(ops, resolveOps...)
+			if errors.Is(err, ErrJobCancelled) {
+				return err
+			}
+
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			if target == "" {
+				continue
+			}
+
+			if hard {
+				err = os.Link(source, target)
+			} else {
+				err = os.Symlink(source, target)
+			}
+
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			ops = append(ops, JournalOp{Type: opType, From: source, To: target})
+		}
+
+		return joinErrors(errs)
+	}, func(err error) {
+		iv.App.Journal.Record("Link "+names[0], ops...)
+		iv.App.RefreshViewsShowing(destination)
+	})
+}
+
+// Copies the active item, or the selected items if there are any, to the folder of the other view
+func (iv *ItemView) CopyToOtherView() {
+	other := iv.App.GetOtherView()
+	if other == nil {
+		NotifyError("Open another view to copy to")
+		return
+	}
+
+	if other.isReadOnly() {
+		return
+	}
+
+	names := iv.getSelectedItems()
+	if len(names) == 0 {
+		if iv.ActiveItem < 0 || iv.ActiveItem >= int32(len(iv.Items)) {
+			return
+		}
+
+		names = []string{iv.Items[iv.ActiveItem].Name}
+	}
+
+	iv.SelectionMode = false
+	directory := iv.CurrentPath
+
+	if iv.FS != nil || other.FS != nil {
+		for _, name := range names {
+			other.receiveFromFileSystem(iv.FS, directory, name, false)
+		}
+
+		return
+	}
+
+	destination := other.CurrentPath
+	if destination == directory {
+		return
+	}
+
+	title := "Copying " + path.Join(directory, names[0])
+	if len(names) > 1 {
+		title = fmt.Sprintf("Copying %d items from %s", len(names), directory)
+	}
+
+	var ops []JournalOp
+	iv.App.Jobs.Add(title, func(job *Job) error {
+		var files, bytes int64
+		for _, name := range names {
+			f, b := MeasureItem(path.Join(directory, name))
+			files += f
+			bytes += b
+		}
+		job.SetTotal(files, bytes)
+
+		resolver := iv.App.NewConflictResolver()
+
+		var errs []error
+		for _, name := range names {
+			source := path.Join(directory, name)
+
+			target, resolveOps, err := resolver.Resolve(source, path.Join(destination, name))
+			ops = append(ops, resolveOps...)
+			if errors.Is(err, ErrJobCancelled) {
+				return err
+			}
+
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			if target == "" {
+				continue
+			}
+
+			err = CopyPath(source, target, job)
+			if errors.Is(err, ErrJobCancelled) {
+				return err
+			}
+
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			ops = append(ops, JournalOp{Type: JournalOpCopy, From: source, To: target})
+		}
+
+		return joinErrors(errs)
+	}, func(err error) {
+		iv.App.Journal.Record("Copy "+names[0], ops...)
+		iv.App.RefreshViewsShowing(destination)
+	})
+}
+
+func (iv *ItemView) ToggleFollowLinks() {
+	iv.App.Settings.FollowLinks = !iv.App.Settings.FollowLinks
+	iv.App.Settings.Save(false)
+
+	if iv.App.Settings.FollowLinks {
+		NotifyInfo("Opening a link opens its target")
+	} else {
+		NotifyInfo("Opening a link jumps to its target")
+	}
+}
+
+func (iv *ItemView) OpenFolder(name string) {
+	iv.SetActiveByName(name)
+
+	activeFolder := iv.GoInside()
+	if activeFolder != "" {
+		iv.App.Breadcrumbs[iv.App.ActiveView].Push(activeFolder)
+	}
+}
+
+func (iv *ItemView) OpenFile(name string) {
+	if iv.FS == nil {
+		open.Start(path.Join(iv.CurrentPath, name))
+		return
+	}
+
+	// Files inside of archives are extracted to a temporary folder, so that other programs can open them
+	fsys := iv.FS
+	source := path.Join(iv.CurrentPath, name)
+
+	var target string
+	iv.App.Jobs.Add("Extracting "+GetFileSystemPath(fsys, source), func(job *Job) error {
+		stats, err := fsys.Stat(source)
+		if err != nil {
+			return err
+		}
+		job.SetTotal(1, stats.Size())
+
+		// Every file gets a folder of its own, files with the same name may come from different archives
+		directory, err := makeExtractedFolder()
+		if err != nil {
+			return err
+		}
+
+		target = path.Join(directory, name)
+		err = CopyFromFileSystem(fsys, source, target, job)
+		if err != nil {
+			os.RemoveAll(directory)
+		}
+
+		return err
+	}, func(err error) {
+		if err == nil {
+			open.Start(target)
+		}
+	})
+}
+
+// The temporary folder of this session, which holds the files that were extracted to be opened
+var extractedFiles = struct {
+	sync.Mutex
+	directory string
+}{}
+
+func makeExtractedFolder() (string, error) {
+	extractedFiles.Lock()
+	defer extractedFiles.Unlock()
+
+	if extractedFiles.directory == "" {
+		directory, err := os.MkdirTemp("", "bonfire-")
+		if err != nil {
+			return "", err
+		}
+
+		extractedFiles.directory = filepath.ToSlash(directory)
+	}
+
+	directory, err := os.MkdirTemp(extractedFiles.directory, "")
+	return filepath.ToSlash(directory), err
+}
+
+// Removes the files that were extracted to be opened, called when bonfire is closed
+func RemoveExtractedFiles() {
+	extractedFiles.Lock()
+	defer extractedFiles.Unlock()
+
+	if extractedFiles.directory != "" {
+		os.RemoveAll(extractedFiles.directory)
+		extractedFiles.directory = ""
+	}
+}
+
+func (iv *ItemView) NavigateDown() {
+	if iv.ActiveItem < int32(len(iv.Items)-1) && iv.ActiveItem < iv.MaxItemsPerColumn*(iv.ActiveColumn+1)-1 {
+		iv.ActiveItem++
+		iv.updateSelection()
+	}
+}
+
+func (iv *ItemView) NavigateUp() {
+	if iv.ActiveItem > iv.MaxItemsPerColumn*iv.ActiveColumn {
+		iv.ActiveItem--
+		iv.updateSelection()
+	}
+}
+
+func (iv *ItemView) NavigateRight() {
+	if iv.ActiveColumn >= iv.Columns-1 {
+		return
+	}
 
-	iv.ActiveItem = lastActive
-	if iv.ActiveItem >= int32(len(iv.Items)) && len(iv.Items) > 0 {
-		iv.ActiveItem = int32(len(iv.Items)) - 1
-	} else if len(iv.Items) == 0 {
-		iv.ActiveItem = -1
+	iv.ActiveColumn++
+
+	iv.ActiveViewportColumn++
+	if iv.ActiveViewportColumn == iv.MaxViewportColumns {
+		iv.ActiveViewportColumn = iv.MaxViewportColumns - 1
+		iv.ScrollOffset -= iv.ItemWidth
+	}
+
+	if iv.ActiveItem+iv.MaxItemsPerColumn >= int32(len(iv.Items)) {
+		iv.ActiveItem = int32(len(iv.Items) - 1)
+	} else {
+		iv.ActiveItem += iv.MaxItemsPerColumn
+	}
+
+	iv.updateSelection()
+}
+
+func (iv *ItemView) NavigateLeft() {
+	if iv.ActiveColumn <= 0 {
+		return
+	}
+
+	iv.ActiveColumn--
+	iv.ActiveItem -= iv.MaxItemsPerColumn
+
+	iv.ActiveViewportColumn--
+	if iv.ActiveViewportColumn < 0 {
+		iv.ActiveViewportColumn = 0
+		iv.ScrollOffset += iv.ItemWidth
+	}
+
+	iv.updateSelection()
+}
+
+func (iv *ItemView) NavigateFirstInColumn() {
+	iv.ActiveItem = iv.ActiveColumn * iv.MaxItemsPerColumn
+	iv.updateSelection()
+}
+
+func (iv *ItemView) NavigateLastInColumn() {
+	iv.ActiveItem = iv.ActiveColumn*iv.MaxItemsPerColumn + iv.MaxItemsPerColumn - 1
+	if iv.ActiveItem >= int32(len(iv.Items)) {
+		iv.ActiveItem = int32(len(iv.Items) - 1)
+	}
+
+	iv.updateSelection()
+}
+
+func (iv *ItemView) GoInside() (result string) {
+	if iv.ActiveItem < 0 || iv.ActiveItem >= int32(len(iv.Items)) {
+		return
+	}
+
+	item := iv.Items[iv.ActiveItem]
+
+	if item.Type == ItemTypeFolder {
+		iv.ShowFolder(path.Join(iv.CurrentPath, item.Name))
+		result = item.Name
 	}
+
+	return
+}
+
+func (iv *ItemView) GoOutside() {
+	parent := getParentPath(iv.CurrentPath)
+	if parent == iv.CurrentPath {
+		iv.LeaveArchive()
+		return
+	}
+
+	lastName := path.Base(iv.CurrentPath)
+	iv.ShowFolder(parent)
+	iv.SetActiveByName(lastName)
+}
+
+func (iv *ItemView) MarkActiveAsFavorite() {
+	if iv.isReadOnly() {
+		return
+	}
+
+	if iv.Items[iv.ActiveItem].IsFavorite {
+		iv.Items[iv.ActiveItem].IsFavorite = false
+
+		fullPath := iv.getFavoritePath(iv.Items[iv.ActiveItem].Name)
+		iv.RemoveFavorite(fullPath)
+		iv.App.Settings.RemoveFavorite(fullPath)
+	} else {
+		item := iv.Items[iv.ActiveItem]
+		iv.Items[iv.ActiveItem].IsFavorite = true
+
+		fullPath := iv.getFavoritePath(item.Name)
+		iv.Favorites = append(iv.Favorites, Favorite{FullPath: fullPath, Type: item.Type})
+		iv.App.Settings.AddFavorite(fullPath)
+	}
+}
+
+// Moves the active item to the trash
+func (iv *ItemView) DeleteActive() {
+	if iv.isReadOnly() {
+		return
+	}
+
+	if iv.ActiveItem < 0 || iv.ActiveItem >= int32(len(iv.Items)) {
+		return
+	}
+
+	iv.trashItems([]string{iv.Items[iv.ActiveItem].Name})
 }
 
+// Moves the selected items to the trash
 func (iv *ItemView) DeleteSelected() {
-	for i := 0; i < len(iv.Items); i++ {
-		if !iv.Items[i].IsSelected {
-			continue
-		}
+	if iv.isReadOnly() {
+		return
+	}
 
-		err := os.Remove(path.Join(iv.CurrentPath, iv.Items[i].Name))
-		if err != nil {
-			NotifyError(err.Error())
-			continue
+	iv.trashItems(iv.getSelectedItems())
+	iv.SelectionMode = false
+}
+
+// Deletes the active item, or the selected items if there are any, without moving them to the trash
+func (iv *ItemView) DeleteActiveForced() {
+	if iv.isReadOnly() {
+		return
+	}
+
+	names := iv.getSelectedItems()
+	if len(names) == 0 {
+		if iv.ActiveItem < 0 || iv.ActiveItem >= int32(len(iv.Items)) {
+			return
 		}
+
+		names = []string{iv.Items[iv.ActiveItem].Name}
 	}
 
 	iv.SelectionMode = false
+	directory := iv.CurrentPath
+
+	title := "Deleting " + path.Join(directory, names[0]) + " permanently"
+	if len(names) > 1 {
+		title = fmt.Sprintf("Deleting %d items in %s permanently", len(names), directory)
+	}
+
+	if iv.FS != nil {
+		iv.deleteInFileSystem(title, directory, names)
+		return
+	}
+
+	iv.App.Jobs.Add(title, func(job *Job) error {
+		var files, bytes int64
+		for _, name := range names {
+			f, b := MeasureItem(path.Join(directory, name))
+			files += f
+			bytes += b
+		}
+		job.SetTotal(files, bytes)
+
+		for _, name := range names {
+			err := RemoveItem(path.Join(directory, name), job)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, func(err error) {
+		iv.App.RefreshViewsShowing(directory)
+	})
+}
+
+func (iv *ItemView) deleteInFileSystem(title string, directory string, names []string) {
+	fsys := iv.FS.(WritableFileSystem)
+
+	iv.App.Jobs.Add(title, func(job *Job) error {
+		for _, name := range names {
+			err := job.Checkpoint()
+			if err != nil {
+				return err
+			}
+
+			err = fsys.RemoveAll(path.Join(directory, name))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, func(err error) {
+		iv.App.RefreshViewsIn(fsys, directory)
+	})
+}
+
+func (iv *ItemView) trashItems(names []string) {
+	if len(names) == 0 {
+		return
+	}
+
+	if iv.FS != nil {
+		NotifyError(iv.FS.Location() + " has no trash, use X to delete permanently")
+		return
+	}
+
+	directory := iv.CurrentPath
+
+	title := "Moving " + path.Join(directory, names[0]) + " to the trash"
+	if len(names) > 1 {
+		title = fmt.Sprintf("Moving %d items in %s to the trash", len(names), directory)
+	}
+
+	var ops []JournalOp
+	iv.App.Jobs.Add(title, func(job *Job) error {
+		var errs []error
+		for _, name := range names {
+			fullPath := path.Join(directory, name)
+
+			trashedPath, err := MoveToTrash(fullPath, job)
+			if errors.Is(err, ErrJobCancelled) {
+				return err
+			}
+
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			ops = append(ops, JournalOp{Type: JournalOpTrash, From: fullPath, To: trashedPath})
+		}
+
+		return joinErrors(errs)
+	}, func(err error) {
+		iv.App.Journal.Record("Delete "+names[0], ops...)
+		iv.App.RefreshViewsShowing(directory)
+	})
+}
+
+// Puts the cursor back to where it was before the items were reloaded, making sure it doesn't go past the last item
+func (iv *ItemView) restoreActive(lastActive int32) {
+	iv.ActiveItem = lastActive
+	if iv.ActiveItem >= int32(len(iv.Items)) && len(iv.Items) > 0 {
+		iv.ActiveItem = int32(len(iv.Items)) - 1
+	} else if len(iv.Items) == 0 {
+		iv.ActiveItem = -1
+	}
 
-	// @TODO (!important) not really efficient, better way would probably be to modify the existing items list instead of overriding it
-	iv.ShowFolder(iv.CurrentPath)
-	iv.App.RefreshOtherViews(iv.CurrentPath)
+	if iv.ActiveItem >= 0 {
+		iv.ActiveColumn = iv.ActiveItem / iv.MaxItemsPerColumn
+	}
 }
 
 func (iv *ItemView) CopyActive(showNotification bool) {
-	iv.App.Copy(iv.Items[iv.ActiveItem].Name, iv.CurrentPath, iv.Items[iv.ActiveItem].Type)
+	iv.App.Copy(iv.Items[iv.ActiveItem].Name, iv.CurrentPath, iv.Items[iv.ActiveItem].Type, iv.FS)
 
 	if showNotification {
-		NotifyInfo("Copied " + path.Join(iv.CurrentPath, iv.Items[iv.ActiveItem].Name))
+		fullPath := path.Join(iv.CurrentPath, iv.Items[iv.ActiveItem].Name)
+		if iv.FS != nil {
+			fullPath = GetFileSystemPath(iv.FS, fullPath)
+		}
+
+		NotifyInfo("Copied " + fullPath)
 	}
 }
 
 func (iv *ItemView) Paste() {
 	clipboard := iv.App.GetClipboard()
+	if clipboard.Name == "" || iv.isReadOnly() {
+		return
+	}
+
+	if clipboard.FS != nil || iv.FS != nil {
+		iv.receiveFromFileSystem(clipboard.FS, clipboard.Directory, clipboard.Name, false)
+		return
+	}
+
+	iv.receiveItem(clipboard.Directory, clipboard.Name, clipboard.Type, false)
+}
+
+// Moves an item from another folder into the current folder of this view. fsys is the file system the item is in,
+// nil for the local disk.
+func (iv *ItemView) MoveHere(fsys FileSystem, directory string, name string, itemType ItemType) {
+	if iv.isReadOnly() {
+		return
+	}
+
+	if fsys != nil || iv.FS != nil {
+		iv.receiveFromFileSystem(fsys, directory, name, true)
+		return
+	}
+
+	iv.receiveItem(directory, name, itemType, true)
+}
+
+// Copies or moves an item when the source, the destination or both are not on the local disk. fsys is the file
+// system of the source, nil for the local disk. Only the local items that are moved to the trash to make room and
+// the items extracted from archives are recorded in the journal, the rest couldn't be undone or redone without the
+// connection.
+func (iv *ItemView) receiveFromFileSystem(fsys FileSystem, directory string, name string, move bool) {
+	destination := iv.CurrentPath
+	source := path.Join(directory, name)
+	target, _ := iv.FS.(WritableFileSystem)
+
+	sourceLabel := source
+	if fsys != nil {
+		sourceLabel = GetFileSystemPath(fsys, source)
+	}
+
+	title := "Copying " + sourceLabel
+	if move {
+		title = "Moving " + sourceLabel
+	}
+
+	writableSource, _ := fsys.(WritableFileSystem)
+	if move && fsys != nil && writableSource == nil {
+		NotifyError(fsys.Location() + " is read-only")
+		return
+	}
 
-	if clipboard.Type == ItemTypeFolder {
-		// @TODO (!important) implement pasting a folder
-	} else if clipboard.Type == ItemTypeFile {
-		success, name := MakeFileCopy(clipboard.Directory, clipboard.Name, iv.CurrentPath)
-		if !success {
+	sameFileSystem := isSameFileSystem(fsys, iv.FS)
+
+	var targetPath string
+	var ops []JournalOp
+	iv.App.Jobs.Add(title, func(job *Job) (err error) {
+		resolver := iv.App.NewConflictResolver()
+		targetPath = path.Join(destination, name)
+
+		var resolveOps []JournalOp
+
+		if sameFileSystem && targetPath == source {
+			if move {
+				return nil
+			}
+
+			// Pasting into the same folder makes a duplicate
+			targetPath = path.Join(destination, GetAvailableNameInFileSystem(target, destination, name))
+		} else if target != nil {
+			targetPath, resolveOps, err = resolver.ResolveInFileSystem(fsys, source, target, targetPath)
+		} else {
+			targetPath, resolveOps, err = resolver.ResolveFromFileSystem(fsys, source, targetPath)
+		}
+		ops = append(ops, resolveOps...)
+
+		if err != nil || targetPath == "" {
+			return
+		}
+
+		if move && sameFileSystem {
+			return target.Rename(source, targetPath)
+		}
+
+		if fsys != nil {
+			job.SetTotal(MeasureInFileSystem(fsys, source))
+		} else {
+			job.SetTotal(MeasureItem(source))
+		}
+
+		if target != nil {
+			err = CopyToFileSystem(fsys, source, target, targetPath, job)
+		} else {
+			err = CopyFromFileSystem(fsys, source, targetPath, job)
+
+			if _, isArchive := fsys.(*ArchiveFileSystem); isArchive && DoesFileExist(targetPath) {
+				ops = append(ops, JournalOp{Type: JournalOpExtract, From: GetFileSystemPath(fsys, source), To: targetPath})
+			}
+		}
+
+		if err != nil || !move {
 			return
 		}
 
-		iv.ShowFolder(iv.CurrentPath)
-		iv.App.RefreshOtherViews(iv.CurrentPath)
-		iv.SetActiveByName(name)
+		if writableSource != nil {
+			return writableSource.RemoveAll(source)
+		}
+
+		return RemoveItem(source, nil)
+	}, func(err error) {
+		description := "Copy " + name
+		if move {
+			description = "Move " + name
+		} else if _, isArchive := fsys.(*ArchiveFileSystem); isArchive {
+			description = "Extract " + name
+		}
+
+		iv.App.Journal.Record(description, ops...)
+		if move {
+			iv.App.RefreshViewsIn(fsys, directory)
+		}
+
+		iv.App.RefreshViewsIn(iv.FS, destination)
+
+		if err == nil && targetPath != "" && iv.CurrentPath == destination {
+			iv.SetActiveByName(path.Base(targetPath))
+		}
+	})
+}
+
+func (iv *ItemView) receiveItem(directory string, name string, itemType ItemType, move bool) {
+	destination := iv.CurrentPath
+	source := path.Join(directory, name)
+
+	title := "Copying " + source
+	if move {
+		title = "Moving " + source
 	}
+
+	var target string
+	var ops []JournalOp
+	iv.App.Jobs.Add(title, func(job *Job) error {
+		target = path.Join(destination, name)
+
+		var err error
+		if target == source {
+			// Pasting into the same folder makes a duplicate
+			target = path.Join(destination, GetAvailableFileName(destination, name))
+		} else {
+			target, ops, err = iv.App.NewConflictResolver().Resolve(source, target)
+			if err != nil || target == "" {
+				return err
+			}
+		}
+
+		job.SetTotal(MeasureItem(source))
+
+		if move {
+			err = MovePath(source, target, job)
+		} else {
+			err = CopyPath(source, target, job)
+		}
+
+		if err == nil {
+			if move {
+				ops = append(ops, JournalOp{Type: JournalOpMove, From: source, To: target})
+			} else {
+				ops = append(ops, JournalOp{Type: JournalOpCopy, From: source, To: target})
+			}
+		}
+
+		return err
+	}, func(err error) {
+		if move {
+			iv.App.RefreshViewsShowing(directory)
+		}
+
+		iv.App.RefreshViewsShowing(destination)
+
+		if move {
+			iv.App.Journal.Record("Move "+name, ops...)
+		} else {
+			iv.App.Journal.Record("Copy "+name, ops...)
+		}
+
+		if err != nil || target == "" {
+			return
+		}
+
+		if iv.CurrentPath == destination {
+			iv.SetActiveByName(path.Base(target))
+		}
+
+		if move {
+			NotifyInfo("Moved " + source)
+		}
+	})
 }
 
 func (iv *ItemView) DuplicateActive() {
-	if iv.Items[iv.ActiveItem].Type == ItemTypeFolder {
-
-	} else if iv.Items[iv.ActiveItem].Type == ItemTypeFile {
-		iv.CopyActive(false)
-		iv.Paste()
+	if iv.ActiveItem < 0 || iv.ActiveItem >= int32(len(iv.Items)) {
+		return
 	}
+
+	iv.CopyActive(false)
+	iv.Paste()
 }
 
 func (iv *ItemView) RenameActive() {
+	if iv.isReadOnly() {
+		return
+	}
+
 	iv.Items[iv.ActiveItem].RenameInProgress = true
 	iv.ConsumingInput = true
 
@@ -582,19 +1860,115 @@ func (iv *ItemView) RenameActive() {
 		iv.Items[iv.ActiveItem].RenameInProgress = false
 		iv.ConsumingInput = false
 
-		err := os.Rename(path.Join(iv.CurrentPath, oldName), path.Join(iv.CurrentPath, value))
-		if err != nil {
-			NotifyError(err.Error())
+		if value == oldName || value == "" {
 			iv.Items[iv.ActiveItem].Name = oldName
+			return
+		}
+
+		directory := iv.CurrentPath
+		from := path.Join(directory, oldName)
+
+		if iv.FS != nil {
+			iv.renameInFileSystem(directory, oldName, value)
+			return
 		}
 
-		iv.App.RefreshOtherViews(iv.CurrentPath)
+		var to string
+		var ops []JournalOp
+		iv.App.Jobs.Add("Renaming "+from, func(job *Job) (err error) {
+			to, ops, err = iv.App.NewConflictResolver().Resolve(from, path.Join(directory, value))
+			if err != nil || to == "" {
+				return
+			}
+
+			err = os.Rename(from, to)
+			if err == nil {
+				ops = append(ops, JournalOp{Type: JournalOpRename, From: from, To: to})
+			}
+
+			return
+		}, func(err error) {
+			iv.App.Journal.Record("Rename "+oldName, ops...)
+			iv.App.RefreshViewsShowing(directory)
+
+			if iv.CurrentPath == directory {
+				if err == nil && to != "" {
+					iv.SetActiveByName(path.Base(to))
+				} else {
+					iv.SetActiveByName(oldName)
+				}
+			}
+		})
 	}, func() {
 		iv.Items[iv.ActiveItem].RenameInProgress = false
 		iv.ConsumingInput = false
 	})
 }
 
+// Renames aren't recorded in the journal outside of the local disk
+func (iv *ItemView) renameInFileSystem(directory string, oldName string, newName string) {
+	fsys := iv.FS.(WritableFileSystem)
+	from := path.Join(directory, oldName)
+
+	var to string
+	iv.App.Jobs.Add("Renaming "+GetFileSystemPath(fsys, from), func(job *Job) (err error) {
+		to, _, err = iv.App.NewConflictResolver().ResolveInFileSystem(fsys, from, fsys, path.Join(directory, newName))
+		if err != nil || to == "" {
+			return
+		}
+
+		return fsys.Rename(from, to)
+	}, func(err error) {
+		iv.App.RefreshViewsIn(fsys, directory)
+
+		if iv.CurrentPath == directory && iv.FS == fsys {
+			if err == nil && to != "" {
+				iv.SetActiveByName(path.Base(to))
+			} else {
+				iv.SetActiveByName(oldName)
+			}
+		}
+	})
+}
+
+// Opens the names of the selected items, or of all the items if nothing is selected, for renaming in a text buffer
+func (iv *ItemView) BulkRename() {
+	if iv.isNotLocal() {
+		return
+	}
+
+	names := iv.getSelectedItems()
+	if len(names) == 0 {
+		names = iv.itemsToNames()
+	}
+
+	if len(names) == 0 {
+		return
+	}
+
+	iv.SelectionMode = false
+	iv.App.BulkRenameView.Open(iv.CurrentPath, names)
+}
+
+// Opens the pattern rename dialog for the selected items, or for the active item if nothing is selected
+func (iv *ItemView) BatchRename() {
+	if iv.isNotLocal() {
+		return
+	}
+
+	names := iv.getSelectedItems()
+	if len(names) == 0 {
+		if iv.ActiveItem < 0 || iv.ActiveItem >= int32(len(iv.Items)) {
+			return
+		}
+
+		names = []string{iv.Items[iv.ActiveItem].Name}
+	}
+
+	iv.SelectionMode = false
+	iv.App.BatchRenameView.Open(iv.CurrentPath, names)
+}
+
 func (iv *ItemView) SelectActive() {
 	if iv.ActiveItem < 0 || iv.ActiveItem >= int32(len(iv.Items)) {
 		return
@@ -633,6 +2007,22 @@ func (iv *ItemView) updateSelection() {
 	}
 }
 
+// Selects the items that are only in this folder, newer or different than in the folder it's compared with, which are
+// the items that would be copied across to make the other folder the same
+func (iv *ItemView) SelectDifferences() {
+	if !iv.App.Comparison.Includes(iv) {
+		NotifyError("The view is not compared with another one")
+		return
+	}
+
+	for i := 0; i < len(iv.Items); i++ {
+		state, ok := iv.CompareStates[iv.Items[i].Name]
+		iv.Items[i].IsSelected = ok && (state == CompareOnlyHere || state == CompareNewer || state == CompareDifferent)
+	}
+
+	iv.SelectionMode = false
+}
+
 func (iv *ItemView) getSelectedItemsCount() (result int32) {
 	for i := 0; i < len(iv.Items); i++ {
 		if iv.Items[i].IsSelected {
@@ -663,19 +2053,19 @@ func (iv *ItemView) favoritesToPaths() []string {
 	return result
 }
 
-// func (iv *ItemView) getSelectedItems() (result []string) {
-// 	result = make([]string, iv.getSelectedItemsCount())
-// 	index := 0
+func (iv *ItemView) getSelectedItems() (result []string) {
+	result = make([]string, iv.getSelectedItemsCount())
+	index := 0
 
-// 	for i := 0; i < len(iv.Items); i++ {
-// 		if iv.Items[i].IsSelected {
-// 			result[index] = iv.Items[i].Name
-// 			index++
-// 		}
-// 	}
+	for i := 0; i < len(iv.Items); i++ {
+		if iv.Items[i].IsSelected {
+			result[index] = iv.Items[i].Name
+			index++
+		}
+	}
 
-// 	return
-// }
+	return
+}
 
 // func (iv *ItemView) getSelectedItemsPaths() (result []string) {
 // 	result = make([]string, iv.getSelectedItemsCount())
@@ -725,28 +2115,54 @@ func (iv *ItemView) RemoveFavorite(fullPath string) {
 }
 
 func (iv *ItemView) CreateNewFile() {
-	success, name := CreateNewFile(iv.CurrentPath)
+	if iv.isReadOnly() {
+		return
+	}
+
+	var success bool
+	var name string
+	if iv.FS != nil {
+		success, name = iv.createInFileSystem("New File", false)
+	} else {
+		success, name = CreateNewFile(iv.CurrentPath)
+	}
+
 	if !success {
 		return
 	}
 
-	// // @TODO (!important) not really efficient, better way would probably be to modify the existing items list instead of overriding it
-	iv.ShowFolder(iv.CurrentPath)
-	iv.App.RefreshOtherViews(iv.CurrentPath)
+	if iv.FS == nil {
+		iv.App.Journal.Record("Create "+name, JournalOp{Type: JournalOpCreateFile, To: path.Join(iv.CurrentPath, name)})
+	}
+
+	iv.App.RefreshViewsIn(iv.FS, iv.CurrentPath)
 	iv.SetActiveByName(name)
 	iv.RenameActive()
 }
 
 func (iv *ItemView) CreateNewFolder(updateView bool, rename bool) string {
-	success, name := CreateNewFolder(iv.CurrentPath, "New Folder")
+	if iv.isReadOnly() {
+		return ""
+	}
+
+	var success bool
+	var name string
+	if iv.FS != nil {
+		success, name = iv.createInFileSystem("New Folder", true)
+	} else {
+		success, name = CreateNewFolder(iv.CurrentPath, "New Folder")
+	}
+
 	if !success {
 		return ""
 	}
 
-	// @TODO (!important) not really efficient, better way would probably be to modify the existing items list instead of overriding it
+	if iv.FS == nil {
+		iv.App.Journal.Record("Create "+name, JournalOp{Type: JournalOpCreateFolder, To: path.Join(iv.CurrentPath, name)})
+	}
+
 	if updateView {
-		iv.ShowFolder(iv.CurrentPath)
-		iv.App.RefreshOtherViews(iv.CurrentPath)
+		iv.App.RefreshViewsIn(iv.FS, iv.CurrentPath)
 	}
 
 	iv.SetActiveByName(name)
@@ -757,57 +2173,343 @@ func (iv *ItemView) CreateNewFolder(updateView bool, rename bool) string {
 	return name
 }
 
+// Same as CreateNewFile and CreateNewFolder, but in the file system of the view
+func (iv *ItemView) createInFileSystem(defaultName string, folder bool) (bool, string) {
+	fsys := iv.FS.(WritableFileSystem)
+	name := GetAvailableNameInFileSystem(fsys, iv.CurrentPath, defaultName)
+	fullPath := path.Join(iv.CurrentPath, name)
+
+	var err error
+	if folder {
+		err = fsys.Mkdir(fullPath, 0755)
+	} else {
+		var file io.WriteCloser
+		file, err = fsys.Create(fullPath, 0644)
+		if err == nil {
+			err = file.Close()
+		}
+	}
+
+	if err != nil {
+		NotifyError(err.Error())
+		return false, ""
+	}
+
+	return true, name
+}
+
 func (iv *ItemView) GroupSelectedFiles() {
+	if iv.isNotLocal() {
+		return
+	}
+
 	if !iv.SelectionMode && iv.getSelectedItemsCount() == 0 {
 		return
 	}
 
-	newFolderName := iv.CreateNewFolder(false, false)
+	success, newFolderName := CreateNewFolder(iv.CurrentPath, "New Folder")
+	if !success {
+		return
+	}
 
-	for i := 0; i < len(iv.Items); i++ {
-		if !iv.Items[i].IsSelected {
-			continue
-		}
+	directory := iv.CurrentPath
+	names := iv.getSelectedItems()
+	ops := []JournalOp{{Type: JournalOpCreateFolder, To: path.Join(directory, newFolderName)}}
 
-		oldPath := path.Join(iv.CurrentPath, iv.Items[i].Name)
-		newPath := path.Join(iv.CurrentPath, newFolderName, iv.Items[i].Name)
-		err := os.Rename(oldPath, newPath)
-		if err != nil {
-			NotifyError(err.Error())
-			continue
+	iv.SelectionMode = false
+
+	iv.App.Jobs.Add("Grouping files in "+directory, func(job *Job) error {
+		resolver := iv.App.NewConflictResolver()
+
+		var errs []error
+		for _, name := range names {
+			oldPath := path.Join(directory, name)
+
+			newPath, resolveOps, err := resolver.Resolve(oldPath, path.Join(directory, newFolderName, name))
+			ops = append(ops, resolveOps...)
+			if errors.Is(err, ErrJobCancelled) {
+				return err
+			}
+
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			if newPath == "" {
+				continue
+			}
+
+			err = os.Rename(oldPath, newPath)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			ops = append(ops, JournalOp{Type: JournalOpRename, From: oldPath, To: newPath})
 		}
-	}
 
-	iv.SelectionMode = false
+		return joinErrors(errs)
+	}, func(err error) {
+		iv.App.Journal.Record("Group files", ops...)
+		iv.App.RefreshViewsShowing(directory)
 
-	iv.ShowFolder(iv.CurrentPath)
-	iv.App.RefreshOtherViews(iv.CurrentPath)
-	iv.SetActiveByName(newFolderName)
-	iv.RenameActive()
+		if iv.CurrentPath == directory {
+			iv.SetActiveByName(newFolderName)
+			iv.RenameActive()
+		}
+	})
 }
 
+// Moves everything from the active folder to the current folder and removes the active folder if it ends up empty.
+// Items that can't be moved are left in the folder.
 func (iv *ItemView) ExtractFilesFromFolder() {
+	if iv.isNotLocal() {
+		return
+	}
+
+	if iv.ActiveItem < 0 || iv.ActiveItem >= int32(len(iv.Items)) {
+		return
+	}
+
 	active := iv.Items[iv.ActiveItem]
 	if active.Type != ItemTypeFolder {
 		return
 	}
 
-	items, success := ReadDirectory(path.Join(iv.CurrentPath, active.Name))
+	directory := iv.CurrentPath
+	folderPath := path.Join(directory, active.Name)
+	items, success := ReadDirectory(folderPath)
 	if !success {
 		return
 	}
 
-	for _, file := range items {
-		err := os.Rename(path.Join(iv.CurrentPath, active.Name, file.Name()), path.Join(iv.CurrentPath, file.Name()))
-		if err != nil {
-			NotifyError(err.Error())
+	var ops []JournalOp
+	iv.App.Jobs.Add("Extracting "+folderPath, func(job *Job) error {
+		resolver := iv.App.NewConflictResolver()
+
+		var errs []error
+		for _, file := range items {
+			oldPath := path.Join(folderPath, file.Name())
+
+			newPath, resolveOps, err := resolver.Resolve(oldPath, path.Join(directory, file.Name()))
+			ops = append(ops, resolveOps...)
+			if errors.Is(err, ErrJobCancelled) {
+				return err
+			}
+
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			if newPath == "" {
+				continue
+			}
+
+			err = os.Rename(oldPath, newPath)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			ops = append(ops, JournalOp{Type: JournalOpRename, From: oldPath, To: newPath})
+		}
+
+		remaining, err := os.ReadDir(folderPath)
+		if err == nil && len(remaining) == 0 {
+			err = os.Remove(folderPath)
+			if err != nil {
+				errs = append(errs, err)
+			} else {
+				ops = append(ops, JournalOp{Type: JournalOpRemoveFolder, From: folderPath})
+			}
+		}
+
+		return joinErrors(errs)
+	}, func(err error) {
+		iv.App.Journal.Record("Extract "+active.Name, ops...)
+		iv.App.RefreshViewsShowing(directory)
+	})
+}
+
+// Packs the selected items, or the active item if nothing is selected, into a new archive in the current folder.
+// The name of the archive is typed in place of the active item, its extension decides the format.
+func (iv *ItemView) CompressSelected() {
+	if iv.isNotLocal() || iv.ActiveItem < 0 || iv.ActiveItem >= int32(len(iv.Items)) {
+		return
+	}
+
+	names := iv.getSelectedItems()
+	if len(names) == 0 {
+		names = []string{iv.Items[iv.ActiveItem].Name}
+	}
+
+	iv.SelectionMode = false
+
+	name := path.Base(iv.CurrentPath)
+	if len(names) == 1 {
+		name = strings.TrimSuffix(names[0], path.Ext(names[0]))
+	}
+
+	active := iv.ActiveItem
+	iv.Items[active].RenameInProgress = true
+	iv.ConsumingInput = true
+
+	iv.Input.Open(name+".zip", func(value string) {
+		iv.Items[active].RenameInProgress = false
+		iv.ConsumingInput = false
+
+		if value == "" {
+			return
+		}
+
+		iv.createArchive(value, names)
+	}, func() {
+		iv.Items[active].RenameInProgress = false
+		iv.ConsumingInput = false
+	})
+}
+
+func (iv *ItemView) createArchive(name string, names []string) {
+	directory := iv.CurrentPath
+	target := path.Join(directory, name)
+
+	if !IsArchive(name) {
+		NotifyError(name + " doesn't end with .zip, .tar, .tar.gz or .tar.zst")
+		return
+	}
+
+	if DoesFileExist(target) {
+		NotifyError(name + " already exists")
+		return
+	}
+
+	iv.App.Jobs.Add("Compressing "+target, func(job *Job) error {
+		var files, bytes int64
+		for _, name := range names {
+			f, b := MeasureItem(path.Join(directory, name))
+			files += f
+			bytes += b
+		}
+		job.SetTotal(files, bytes)
+
+		return CreateArchive(target, directory, names, job)
+	}, func(err error) {
+		iv.App.RefreshViewsShowing(directory)
+
+		if err == nil && iv.CurrentPath == directory && iv.FS == nil {
+			iv.SetActiveByName(name)
+		}
+	})
+}
+
+// Extracts the active archive into a new folder next to it, or, if toOtherView is set, into the folder shown in
+// the other view. Nothing is written outside of the destination, whatever the paths in the archive say.
+func (iv *ItemView) ExtractActive(toOtherView bool) {
+	if iv.ActiveItem < 0 || iv.ActiveItem >= int32(len(iv.Items)) {
+		return
+	}
+
+	if iv.FS != nil {
+		NotifyError("Copy the archive to this computer to extract it")
+		return
+	}
+
+	name := iv.Items[iv.ActiveItem].Name
+	if !IsArchive(name) {
+		NotifyError(name + " is not a supported archive")
+		return
+	}
+
+	archivePath := path.Join(iv.CurrentPath, name)
+
+	var destination string
+	if toOtherView {
+		other := iv.App.GetOtherView()
+		if other == nil {
+			NotifyError("Open another view to extract into")
+			return
+		}
+
+		if other.isNotLocal() {
 			return
 		}
+
+		destination = other.CurrentPath
+	} else {
+		destination = iv.CurrentPath
 	}
 
-	iv.DeleteActive()
-	iv.ShowFolder(iv.CurrentPath)
-	iv.App.RefreshOtherViews(iv.CurrentPath)
+	var ops []JournalOp
+	iv.App.Jobs.Add("Extracting "+archivePath, func(job *Job) error {
+		archive, err := OpenArchive(archivePath)
+		if err != nil {
+			return err
+		}
+
+		job.SetTotal(MeasureInFileSystem(archive, "/"))
+		resolver := iv.App.NewConflictResolver()
+
+		if !toOtherView {
+			target, resolveOps, err := resolver.Resolve(archivePath, path.Join(destination, getArchiveBaseName(name)))
+			ops = append(ops, resolveOps...)
+			if err != nil || target == "" {
+				return err
+			}
+
+			err = CopyFromFileSystem(archive, "/", target, job)
+			if err == nil || DoesFileExist(target) {
+				// Whatever was extracted before an error can be undone too
+				ops = append(ops, JournalOp{Type: JournalOpExtract, From: GetFileSystemPath(archive, "/"), To: target})
+			}
+
+			return err
+		}
+
+		items, err := archive.ReadDir("/")
+		if err != nil {
+			return err
+		}
+
+		var errs []error
+		for _, item := range items {
+			source := path.Join("/", item.Name())
+
+			target, resolveOps, err := resolver.ResolveFromFileSystem(archive, source, path.Join(destination, item.Name()))
+			ops = append(ops, resolveOps...)
+			if errors.Is(err, ErrJobCancelled) {
+				return err
+			}
+
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			if target == "" {
+				continue
+			}
+
+			err = CopyFromFileSystem(archive, source, target, job)
+			if err == nil || DoesFileExist(target) {
+				ops = append(ops, JournalOp{Type: JournalOpExtract, From: GetFileSystemPath(archive, source), To: target})
+			}
+
+			if errors.Is(err, ErrJobCancelled) {
+				return err
+			}
+
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		return joinErrors(errs)
+	}, func(err error) {
+		iv.App.Journal.Record("Extract "+name, ops...)
+		iv.App.RefreshViewsShowing(destination)
+	})
 }
 
 func (iv *ItemView) Resize(rect sdl.Rect) {
@@ -919,11 +2621,17 @@ func (iv *ItemView) Render(renderer *sdl.Renderer, app *App, active bool) {
 			if item.RenameInProgress {
 				iv.Input.Render(renderer, rect, &font, ifTheme)
 			} else {
+				// The verification mark takes some of the space of the name
+				available := iv.ItemWidth
+				if _, ok := iv.VerifyStates[item.Name]; ok {
+					available -= font.GetStringWidth("PASS") + itemPadding
+				}
+
 				name := item.Name
 				width := font.GetStringWidth(name)
-				if width > iv.ItemWidth {
-					name = font.ClipString(name, iv.ItemWidth-itemPadding*2)
-					width = iv.ItemWidth - itemPadding*2
+				if width > available {
+					name = font.ClipString(name, available-itemPadding*2)
+					width = available - itemPadding*2
 				}
 
 				stringRect := sdl.Rect{
@@ -937,7 +2645,11 @@ func (iv *ItemView) Render(renderer *sdl.Renderer, app *App, active bool) {
 				if item.IsHidden {
 					color = GetColor(ivTheme, "hidden_color")
 				} else {
-					if item.Type == ItemTypeFolder {
+					if item.Type == ItemTypeLink && item.IsBrokenLink && HasColor(ivTheme, "broken_link_color") {
+						color = GetColor(ivTheme, "broken_link_color")
+					} else if item.Type == ItemTypeLink && HasColor(ivTheme, "symlink_color") {
+						color = GetColor(ivTheme, "symlink_color")
+					} else if item.Type == ItemTypeFolder {
 						color = GetColor(ivTheme, "folder_color")
 					} else if item.FileType == FileTypeExe {
 						color = GetColor(ivTheme, "exe_color")
@@ -946,11 +2658,15 @@ func (iv *ItemView) Render(renderer *sdl.Renderer, app *App, active bool) {
 					}
 				}
 
+				if state, ok := iv.CompareStates[item.Name]; ok && HasColor(ivTheme, state.colorKey()) {
+					color = GetColor(ivTheme, state.colorKey())
+				}
+
 				if item.IsSelected && active {
 					DrawRect(renderer, &rect, GetColor(ivTheme, "selected_background_color"))
 
 					color = GetColor(ivTheme, "selected_file_color")
-					if item.Type == ItemTypeFolder {
+					if item.IsFolder() {
 						color = GetColor(ivTheme, "selected_folder_color")
 					}
 				}
@@ -969,13 +2685,17 @@ func (iv *ItemView) Render(renderer *sdl.Renderer, app *App, active bool) {
 					}
 
 					color = GetColor(ivTheme, "active_file_color")
-					if item.Type == ItemTypeFolder {
+					if item.IsFolder() {
 						color = GetColor(ivTheme, "active_folder_color")
 					}
 				}
 
 				DrawText(renderer, &font, name, &stringRect, color)
 
+				if state, ok := iv.VerifyStates[item.Name]; ok {
+					iv.renderVerifyMark(renderer, &font, rect, state, item.IsFavorite, itemPadding)
+				}
+
 				if item.IsFavorite {
 					iconRect := sdl.Rect{
 						X: rect.X + rect.W - (itemPadding + iv.App.FavoriteIcon.Width),
@@ -996,3 +2716,34 @@ func (iv *ItemView) Render(renderer *sdl.Renderer, app *App, active bool) {
 		iv.Scrollbar.Render(renderer, iv.ActiveColumn, iv.Columns, iv.App)
 	}
 }
+
+// Draws whether the item passed the verification at the end of the item, before the favorite icon
+func (iv *ItemView) renderVerifyMark(renderer *sdl.Renderer, font *Font, rect sdl.Rect, state VerifyState, isFavorite bool, itemPadding int32) {
+	ivTheme := iv.App.Theme.ItemViewTheme
+
+	mark := "PASS"
+	colorKey := "verify_passed_color"
+	if state != VerifyPassed {
+		mark = "FAIL"
+		colorKey = "verify_failed_color"
+	}
+
+	if !HasColor(ivTheme, colorKey) {
+		return
+	}
+
+	right := rect.X + rect.W - itemPadding
+	if isFavorite {
+		right -= iv.App.FavoriteIcon.Width + itemPadding
+	}
+
+	width := font.GetStringWidth(mark)
+	markRect := sdl.Rect{
+		X: right - width,
+		Y: rect.Y + (iv.ItemHeight-font.Size)/2,
+		W: width,
+		H: font.Size,
+	}
+
+	DrawText(renderer, font, mark, &markRect, GetColor(ivTheme, colorKey))
+}
@@ -2,14 +2,15 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"path"
+	"path/filepath"
 	"strings"
 	"unicode"
 
 	"github.com/veandco/go-sdl2/sdl"
 )
 
-// @TODO (!important) copy files and folders
 // @TODO (!important) lazy initialize compnents that are not needed right away
 
 type Mode int32
@@ -18,12 +19,14 @@ const (
 	Mode_Normal Mode = iota
 	Mode_Drive_Selection
 	Mode_Goto
+	Mode_Location
 )
 
 type Clipboard struct {
 	Directory string
 	Name      string
 	Type      ItemType
+	FS        FileSystem // Set if the item was copied from inside of an archive
 }
 
 type PlatformLayer struct {
@@ -35,7 +38,7 @@ type App struct {
 	FavoriteIcon Image
 	Theme
 	AvailableThemes []string
-	AvailabelDrives []string
+	Roots           []Root
 
 	ActiveView  int32
 	ViewCount   int32
@@ -56,6 +59,32 @@ type App struct {
 
 	NormalKeyMap map[byte]Shortcut
 	Clipboard
+	Jobs           *JobManager
+	JobsPanel      JobsPanel
+	Journal        *Journal
+	TrashView      TrashView
+	DiskUsageView  DiskUsageView
+	DuplicatesView DuplicatesView
+	SyncView       SyncView
+	FindView       FindView
+	AttributesView AttributesView
+	Comparison     *Comparison // nil if no views are compared
+
+	BulkRenameView  BulkRenameView
+	BatchRenameView BatchRenameView
+
+	ConflictPrompt ConflictPrompt
+	LocationInput  *InlineInputField
+
+	Watcher *FolderWatcher // nil if folders can't be watched
+
+	fileInfoRows chan fileInfoRow // Rows calculated in other threads, set in the info view by Tick
+}
+
+type fileInfoRow struct {
+	Name  string
+	Label string
+	Value string
 }
 
 func NewApp(renderer *sdl.Renderer, windowWidth int32, windowHeight int32, platformLayer PlatformLayer) (result *App) {
@@ -63,21 +92,36 @@ func NewApp(renderer *sdl.Renderer, windowWidth int32, windowHeight int32, platf
 
 	result.Font = LoadFont("assets/fonts/consolab.ttf", 12)
 	result.AvailableThemes = GetAvailableThemes()
-	result.AvailabelDrives = GetAvailableDrives()
+	result.Roots = GetRoots()
 
 	result.ActiveView = 0
 	result.ViewCount = 1
 	result.WindowRects = []sdl.Rect{{X: 0, Y: 0, W: windowWidth, H: windowHeight}}
 
-	result.Breadcrumbs = []Breadcrumbs{*NewBreadcrumbs(sdl.Rect{X: 0, Y: 0, W: windowWidth, H: 28}, result.AvailabelDrives)}
+	result.Breadcrumbs = []Breadcrumbs{*NewBreadcrumbs(sdl.Rect{X: 0, Y: 0, W: windowWidth, H: 28}, getRootLabels(result.Roots))}
 	result.ItemViews = []*ItemView{NewItemView(sdl.Rect{X: 0, Y: 28, W: windowWidth, H: windowHeight - 28}, result)}
 	// Only the width matters here, because the position is relative to parent component and height is dynamic
 	result.QuickOpen = *NewQuickOpen(sdl.Rect{X: 0, Y: 0, W: 394, H: 0})
 	result.Notification = *NewNotification()
 	result.InfoViews = []InfoView{*NewInfoView()}
 	result.Previews = []Preview{*NewPreview()}
-
-	result.GoToDrive('D')
+	result.Jobs = NewJobManager(2)
+	result.JobsPanel = *NewJobsPanel()
+	result.Journal = NewJournal()
+	result.TrashView = *NewTrashView()
+	result.DiskUsageView = *NewDiskUsageView()
+	result.DuplicatesView = *NewDuplicatesView()
+	result.SyncView = *NewSyncView()
+	result.FindView = *NewFindView()
+	result.AttributesView = *NewAttributesView()
+	result.BulkRenameView = *NewBulkRenameView()
+	result.BatchRenameView = *NewBatchRenameView()
+	result.ConflictPrompt = *NewConflictPrompt()
+	result.LocationInput = NewInlineInputField()
+	result.Watcher = NewFolderWatcher()
+	result.fileInfoRows = make(chan fileInfoRow, 16)
+
+	result.GoToPath(result.getStartPath())
 	result.Mode = Mode_Normal
 	result.Settings = NewSettings()
 	result.Renderer = renderer
@@ -129,6 +173,9 @@ func NewApp(renderer *sdl.Renderer, windowWidth int32, windowHeight int32, platf
 	result.NormalKeyMap['r'] = Shortcut{Ctrl: true, Alt: false, Callback: func() {
 		result.ItemViews[result.ActiveView].Refresh()
 	}}
+	result.NormalKeyMap['J'] = Shortcut{Ctrl: false, Alt: false, Callback: func() {
+		result.JobsPanel.Open()
+	}}
 
 	return
 }
@@ -136,6 +183,11 @@ func NewApp(renderer *sdl.Renderer, windowWidth int32, windowHeight int32, platf
 func (app *App) Close() {
 	// @TODO (!important) What if the program is closed in such a way that Save function is not called?
 	app.Settings.Save(false)
+	CloseSFTPConnections()
+	RemoveExtractedFiles()
+	if app.Watcher != nil {
+		app.Watcher.Close()
+	}
 	app.Font.Unload()
 	app.FavoriteIcon.Unload()
 }
@@ -161,6 +213,18 @@ func (app *App) Resize(windowWidth int32, windowHeight int32) {
 }
 
 func (app *App) Tick(input *Input) {
+	app.Jobs.Tick()
+	app.ConflictPrompt.Poll()
+	app.FindView.Poll()
+	app.pollFileInfoRows()
+	app.refreshWatchedViews()
+
+	// A conflict blocks the job that ran into it, so it takes priority over everything else
+	if app.ConflictPrompt.IsOpen {
+		app.ConflictPrompt.Tick(input)
+		return
+	}
+
 	if app.Notification.IsOpen {
 		app.Notification.Tick()
 	}
@@ -178,16 +242,65 @@ func (app *App) Tick(input *Input) {
 		return
 	}
 
+	if app.JobsPanel.IsOpen {
+		app.JobsPanel.Tick(input, app.Jobs)
+		return
+	}
+
+	if app.TrashView.IsOpen {
+		app.TrashView.Tick(input, app)
+		return
+	}
+
+	if app.DiskUsageView.IsOpen {
+		app.DiskUsageView.Tick(input, app)
+		return
+	}
+
+	if app.DuplicatesView.IsOpen {
+		app.DuplicatesView.Tick(input, app)
+		return
+	}
+
+	if app.SyncView.IsOpen {
+		app.SyncView.Tick(input, app)
+		return
+	}
+
+	if app.FindView.IsOpen {
+		app.FindView.Tick(input, app)
+		return
+	}
+
+	if app.AttributesView.IsOpen {
+		app.AttributesView.Tick(input, app)
+		return
+	}
+
+	if app.BulkRenameView.IsOpen {
+		app.BulkRenameView.Tick(input, app)
+		return
+	}
+
+	if app.BatchRenameView.IsOpen {
+		app.BatchRenameView.Tick(input, app)
+		return
+	}
+
 	if app.Mode == Mode_Drive_Selection {
 		app.handleInputDriveSelection(input)
 		return
 	}
 
+	if app.Mode == Mode_Location {
+		app.LocationInput.Tick(input)
+		return
+	}
+
 	app.handleInputNormal(input)
 }
 
 func (app *App) handleInputNormal(input *Input) {
-	// @TODO (!important) p to paste a folder
 	// @TODO (!important) P to paste an item contents (files if copying folder), shouldn't do anything for copied files
 
 	app.ItemViews[app.ActiveView].Tick(input)
@@ -215,7 +328,7 @@ func (app *App) handleInputDriveSelection(input *Input) {
 		return
 	}
 
-	if !unicode.IsLetter(rune(input.TypedCharacter)) || input.Escape {
+	if !unicode.IsLetter(rune(input.TypedCharacter)) && !unicode.IsDigit(rune(input.TypedCharacter)) {
 		app.Mode = Mode_Normal
 		return
 	}
@@ -225,24 +338,123 @@ func (app *App) handleInputDriveSelection(input *Input) {
 	app.Breadcrumbs[app.ActiveView].ShowAvailableDrives(false)
 }
 
-func (app *App) GoToDrive(drive byte) {
-	var sb strings.Builder
-	sb.WriteString(strings.ToUpper(string(drive)))
-	sb.WriteString(":")
-
-	withoutSlash := sb.String()
+func (app *App) GoToDrive(key byte) {
+	for _, root := range app.Roots {
+		if unicode.ToLower(rune(root.Key)) == unicode.ToLower(rune(key)) {
+			app.GoToPath(root.Path)
+			return
+		}
+	}
+}
 
-	sb.WriteString("/")
-	withSlash := sb.String()
+func (app *App) GoToPath(fullPath string) bool {
+	view := app.ItemViews[app.ActiveView]
 
-	// When we are opening the drive where the cwd is, go for some reason reads the cwd, not the drive.
-	// Adding a slash after the colon seems to fix this for whatever reason.
-	success := app.ItemViews[app.ActiveView].ShowFolder(withSlash)
+	fsys := view.FS
+	view.FS = nil
 
+	success := view.ShowFolder(fullPath)
 	if success {
-		app.Breadcrumbs[app.ActiveView].Clear()
-		app.Breadcrumbs[app.ActiveView].Push(withoutSlash)
+		app.Breadcrumbs[app.ActiveView].Set(fullPath)
+	} else {
+		view.FS = fsys
 	}
+
+	return success
+}
+
+// Asks for a path or a remote location to go to in the breadcrumbs of the active view
+func (app *App) OpenLocationPrompt() {
+	view := app.ItemViews[app.ActiveView]
+
+	location := view.CurrentPath
+	if view.FS != nil {
+		location = GetFileSystemPath(view.FS, view.CurrentPath)
+	}
+
+	app.Mode = Mode_Location
+	app.LocationInput.Open(location, func(value string) {
+		app.Mode = Mode_Normal
+		app.GoToLocation(value)
+	}, func() {
+		app.Mode = Mode_Normal
+	})
+}
+
+// Goes to a folder on the local disk or to a remote location, e.g. sftp://user@host/path or s3://bucket/prefix. If the location is a
+// file, its folder is shown instead and the file is made active.
+func (app *App) GoToLocation(location string) {
+	location = strings.TrimSpace(location)
+	if location == "" {
+		return
+	}
+
+	if !IsRemoteLocation(location) {
+		fullPath := filepath.ToSlash(location)
+		if GetItemType(fullPath) == ItemTypeFile {
+			if app.GoToPath(getParentPath(fullPath)) {
+				app.ItemViews[app.ActiveView].SetActiveByName(path.Base(fullPath))
+			}
+
+			return
+		}
+
+		app.GoToPath(fullPath)
+		return
+	}
+
+	view := app.ItemViews[app.ActiveView]
+
+	var fsys FileSystem
+	var fullPath string
+	var isFolder bool
+	app.Jobs.Add("Connecting to "+location, func(job *Job) (err error) {
+		fsys, fullPath, err = ConnectToLocation(location)
+		if err != nil {
+			return err
+		}
+
+		stats, err := fsys.Stat(fullPath)
+		if err != nil {
+			return err
+		}
+
+		isFolder = stats.IsDir()
+		return nil
+	}, func(err error) {
+		if err != nil {
+			return
+		}
+
+		if isFolder {
+			view.ShowFileSystem(fsys, fullPath)
+		} else if view.ShowFileSystem(fsys, path.Dir(fullPath)) {
+			view.SetActiveByName(path.Base(fullPath))
+		}
+	})
+}
+
+// The home folder, or the first root if there's no home folder
+func (app *App) getStartPath() string {
+	home, err := os.UserHomeDir()
+	if err == nil {
+		return filepath.ToSlash(home)
+	}
+
+	if len(app.Roots) > 0 {
+		return app.Roots[0].Path
+	}
+
+	return "/"
+}
+
+func getRootLabels(roots []Root) []string {
+	result := make([]string, len(roots))
+	for index, root := range roots {
+		result[index] = root.Label
+	}
+
+	return result
 }
 
 func (app *App) SelectFavorite(favorites []string) {
@@ -286,24 +498,63 @@ func (app *App) ShowFileInfo(info Info) {
 	app.InfoViews[app.ActiveView].Show(info)
 }
 
-func (app *App) ShowPreview(directory string, name string) {
+// fsys is the archive the file is in, or nil for files on the local disk
+func (app *App) ShowPreview(fsys FileSystem, directory string, name string) {
 	fileType := GetFileType(name)
 
+	var data []byte
+	if fsys != nil && (fileType == FileTypeImage || fileType == FileTypeText) {
+		var err error
+		data, err = ReadFromFileSystem(fsys, path.Join(directory, name), maxPreviewSize)
+		if err != nil {
+			NotifyError(err.Error())
+			return
+		}
+	}
+
 	switch fileType {
 	case FileTypeImage:
-		image := LoadImage(path.Join(directory, name), app.Renderer)
+		var image Image
+		if fsys != nil {
+			image = LoadImageFromMemory(data, app.Renderer)
+		} else {
+			image = LoadImage(path.Join(directory, name), app.Renderer)
+		}
+
 		app.Previews[app.ActiveView].ShowImage(name, &image)
 	case FileTypeText:
-		text := ReadFile(path.Join(directory, name))
+		var text string
+		if fsys != nil {
+			text = string(data)
+		} else {
+			text = ReadFile(path.Join(directory, name))
+		}
+
 		app.Previews[app.ActiveView].ShowText(name, text)
 	default:
 		app.Previews[app.ActiveView].ShowPreviewUnsupported(name)
 	}
 }
 
-// Used when the size is calculated in another thread
-func (app *App) SetFileInfoSize(size string) {
-	app.InfoViews[app.ActiveView].Info.Size = size
+// Used when a row, such as the size of a folder, is calculated in another thread. The info view is only changed on
+// the main thread, so the row is set in the next frame.
+func (app *App) SetFileInfoRow(name string, label string, value string) {
+	app.fileInfoRows <- fileInfoRow{Name: name, Label: label, Value: value}
+}
+
+func (app *App) pollFileInfoRows() {
+	for {
+		select {
+		case row := <-app.fileInfoRows:
+			app.InfoViews[app.ActiveView].SetRow(row.Name, row.Label, row.Value)
+		default:
+			return
+		}
+	}
+}
+
+func (app *App) SetFileInfoChecksums(name string, checksums []FileChecksums) {
+	app.InfoViews[app.ActiveView].SetChecksums(name, checksums)
 }
 
 func (app *App) AddView() {
@@ -328,7 +579,7 @@ func (app *App) AddView() {
 		app.Breadcrumbs[i].Resize(sdl.Rect{X: singleWidth * i, Y: 0, W: singleWidth, H: 28})
 		app.ItemViews[i].Resize(sdl.Rect{X: singleWidth * i, Y: 28, W: singleWidth, H: app.WindowRects[0].H - 28})
 	}
-	app.Breadcrumbs = append(app.Breadcrumbs, *NewBreadcrumbs(sdl.Rect{X: singleWidth * app.ViewCount, Y: 0, W: singleWidth, H: 28}, app.AvailabelDrives))
+	app.Breadcrumbs = append(app.Breadcrumbs, *NewBreadcrumbs(sdl.Rect{X: singleWidth * app.ViewCount, Y: 0, W: singleWidth, H: 28}, getRootLabels(app.Roots)))
 	app.ItemViews = append(app.ItemViews, NewItemView(sdl.Rect{X: singleWidth * app.ViewCount, Y: 28, W: singleWidth, H: app.WindowRects[0].H - 28}, app))
 	app.InfoViews = append(app.InfoViews, *NewInfoView())
 	app.Previews = append(app.Previews, *NewPreview())
@@ -337,7 +588,7 @@ func (app *App) AddView() {
 	app.ViewCount = newCount
 
 	app.ItemViews[app.ActiveView].SetFavorites(app.Settings.Favorites)
-	app.GoToDrive('D')
+	app.GoToPath(app.getStartPath())
 }
 
 func (app *App) RemoveView() {
@@ -362,6 +613,10 @@ func (app *App) RemoveView() {
 		app.Breadcrumbs[i].Resize(sdl.Rect{X: singleWidth * i, Y: 0, W: singleWidth, H: 28})
 		app.ItemViews[i].Resize(sdl.Rect{X: singleWidth * i, Y: 28, W: singleWidth, H: app.WindowRects[0].H - 28})
 	}
+	if app.Comparison.Includes(app.ItemViews[newCount]) {
+		app.EndComparison()
+	}
+
 	app.Breadcrumbs = app.Breadcrumbs[:newCount]
 	app.ItemViews = app.ItemViews[:newCount]
 	app.InfoViews = app.InfoViews[:newCount]
@@ -385,13 +640,27 @@ func (app *App) GoToPrevView() {
 	}
 }
 
-func (app *App) Copy(name string, directory string, itemType ItemType) {
+// Returns the view after the active one, or the one before it if the active view is the last one
+func (app *App) GetOtherView() *ItemView {
+	if app.ViewCount < 2 {
+		return nil
+	}
+
+	if app.ActiveView < app.ViewCount-1 {
+		return app.ItemViews[app.ActiveView+1]
+	}
+
+	return app.ItemViews[app.ActiveView-1]
+}
+
+func (app *App) Copy(name string, directory string, itemType ItemType, fsys FileSystem) {
 	app.Clipboard.Name = name
 	app.Clipboard.Directory = directory
 	app.Type = itemType
+	app.Clipboard.FS = fsys
 }
 
-func (app *App) MoveItemToNextView(name string, directory string, itemType ItemType) {
+func (app *App) MoveItemToNextView(name string, directory string, itemType ItemType, fsys FileSystem) {
 	nextView := app.ActiveView + 1
 	if nextView >= app.ViewCount {
 		return
@@ -399,11 +668,10 @@ func (app *App) MoveItemToNextView(name string, directory string, itemType ItemT
 
 	app.ActiveView = nextView
 
-	app.Copy(name, directory, itemType)
-	app.ItemViews[app.ActiveView].Paste()
+	app.ItemViews[app.ActiveView].MoveHere(fsys, directory, name, itemType)
 }
 
-func (app *App) MoveItemToPrevView(name string, directory string, itemType ItemType) {
+func (app *App) MoveItemToPrevView(name string, directory string, itemType ItemType, fsys FileSystem) {
 	prevView := app.ActiveView - 1
 	if prevView < 0 {
 		return
@@ -411,18 +679,187 @@ func (app *App) MoveItemToPrevView(name string, directory string, itemType ItemT
 
 	app.ActiveView = prevView
 
-	app.Copy(name, directory, itemType)
-	app.ItemViews[app.ActiveView].Paste()
+	app.ItemViews[app.ActiveView].MoveHere(fsys, directory, name, itemType)
 }
 
-func (app *App) RefreshOtherViews(fullPath string) {
+// Refreshes every view that shows the folder, including the active one. Used when an operation running in the
+// background finishes and the user might have switched views in the meantime.
+func (app *App) RefreshViewsShowing(fullPath string) {
+	app.RefreshViewsIn(nil, fullPath)
+}
+
+// Same as RefreshViewsShowing, for the views that show the folder inside of fsys. A nil fsys stands for the local
+// disk.
+func (app *App) RefreshViewsIn(fsys FileSystem, fullPath string) {
 	for i := int32(0); i < app.ViewCount; i++ {
-		if i != app.ActiveView && app.ItemViews[i].CurrentPath == fullPath {
-			app.ItemViews[i].Refresh()
+		view := app.ItemViews[i]
+		if view.CurrentPath == fullPath && isSameFileSystem(view.FS, fsys) {
+			view.Refresh()
 		}
 	}
 }
 
+// Compares the folder of the active view with the folder of the other view. Doing it again stops comparing them.
+func (app *App) ToggleComparison(byContents bool) {
+	view := app.ItemViews[app.ActiveView]
+	if app.Comparison.Includes(view) && app.Comparison.ByContents == byContents {
+		app.EndComparison()
+		return
+	}
+
+	other := app.GetOtherView()
+	if other == nil {
+		NotifyError("Open another view to compare with")
+		return
+	}
+
+	app.EndComparison()
+	app.Comparison = &Comparison{Views: [2]*ItemView{view, other}, ByContents: byContents}
+	app.runComparison()
+}
+
+// Plans syncing the folder of the other view with the folder of the active view, see SyncView
+func (app *App) SyncWithOtherView(twoWay bool) {
+	view := app.ItemViews[app.ActiveView]
+	other := app.GetOtherView()
+	if other == nil {
+		NotifyError("Open another view to sync with")
+		return
+	}
+
+	if view.isNotLocal() || other.isNotLocal() {
+		return
+	}
+
+	app.SyncView.Open(view.CurrentPath, other.CurrentPath, twoWay, app)
+}
+
+func (app *App) EndComparison() {
+	comparison := app.Comparison
+	if comparison == nil {
+		return
+	}
+
+	if comparison.job != nil {
+		comparison.job.Cancel()
+	}
+
+	for _, view := range comparison.Views {
+		view.CompareStates = nil
+	}
+
+	app.Comparison = nil
+}
+
+// Compares the views again, called whenever one of them is refreshed, so that items copied across show up as the
+// same
+func (app *App) runComparison() {
+	comparison := app.Comparison
+	if comparison.job != nil {
+		comparison.job.Cancel()
+	}
+
+	left, right := comparison.Views[0], comparison.Views[1]
+	leftFS, leftPath := left.FS, left.CurrentPath
+	rightFS, rightPath := right.FS, right.CurrentPath
+
+	leftLabel, rightLabel := leftPath, rightPath
+	if leftFS != nil {
+		leftLabel = GetFileSystemPath(leftFS, leftPath)
+	}
+	if rightFS != nil {
+		rightLabel = GetFileSystemPath(rightFS, rightPath)
+	}
+
+	var leftStates, rightStates map[string]CompareState
+	var job *Job
+	job = app.Jobs.Add("Comparing "+leftLabel+" with "+rightLabel, func(job *Job) (err error) {
+		leftStates, rightStates, err = CompareFolders(leftFS, leftPath, rightFS, rightPath, comparison.ByContents, job)
+		return
+	}, func(err error) {
+		// The views were compared again or aren't compared anymore
+		if app.Comparison != comparison || comparison.job != job {
+			return
+		}
+
+		comparison.job = nil
+		if err != nil {
+			app.EndComparison()
+			return
+		}
+
+		left.CompareStates = leftStates
+		right.CompareStates = rightStates
+
+		if !comparison.notified {
+			comparison.notified = true
+
+			// The items that are in both folders are only counted in the left one
+			differences := countDifferences(leftStates, false) + countDifferences(rightStates, true)
+			if differences == 0 {
+				NotifyInfo("The folders are the same")
+			} else {
+				NotifyInfo(fmt.Sprintf("Found %d differences", differences))
+			}
+		}
+	})
+
+	comparison.job = job
+}
+
+func countDifferences(states map[string]CompareState, onlyHere bool) (result int) {
+	for _, state := range states {
+		if state == CompareOnlyHere || !onlyHere && state != CompareSame {
+			result++
+		}
+	}
+
+	return
+}
+
+// Watches the local folders the views show and refreshes the views once another program changed their folder
+func (app *App) refreshWatchedViews() {
+	if app.Watcher == nil {
+		return
+	}
+
+	var folders []string
+	for i := int32(0); i < app.ViewCount; i++ {
+		if app.ItemViews[i].FS == nil && app.ItemViews[i].CurrentPath != "" {
+			folders = append(folders, app.ItemViews[i].CurrentPath)
+		}
+	}
+	app.Watcher.Watch(folders)
+
+	for _, folder := range app.Watcher.TakeChanged() {
+		for i := int32(0); i < app.ViewCount; i++ {
+			view := app.ItemViews[i]
+			if view.FS == nil && getWatchKey(view.CurrentPath) == folder {
+				view.Refresh()
+			}
+		}
+	}
+}
+
+func (app *App) Undo() {
+	app.Journal.Undo(app.Jobs, app.refreshChangedFolders)
+}
+
+func (app *App) Redo() {
+	app.Journal.Redo(app.Jobs, app.refreshChangedFolders)
+}
+
+func (app *App) refreshChangedFolders(folders []string) {
+	for _, folder := range folders {
+		app.RefreshViewsShowing(folder)
+	}
+}
+
+// Creates a resolver for a single operation, which asks the user what to do through the conflict prompt
+func (app *App) NewConflictResolver() *ConflictResolver {
+	return NewConflictResolver(app.ConflictPrompt.Ask)
+}
+
 func (app *App) GetClipboard() *Clipboard {
 	return &app.Clipboard
 }
@@ -441,11 +878,21 @@ func (app *App) Render() {
 		DrawRectTransparent(app.Renderer, &rect, sdl.Color{R: 0, G: 0, B: 0, A: 150})
 	}
 
+	if app.Mode == Mode_Location {
+		app.LocationInput.Render(app.Renderer, app.Breadcrumbs[app.ActiveView].Rect, &app.Font, app.Theme.InputFieldTheme)
+	}
+
+	fullRect := sdl.Rect{X: 0, Y: 0, W: app.WindowRects[0].W * app.ViewCount, H: app.WindowRects[0].H}
 	if app.Notification.IsOpen {
-		fullRect := sdl.Rect{X: 0, Y: 0, W: app.WindowRects[0].W * app.ViewCount, H: app.WindowRects[0].H}
 		app.Notification.Render(app.Renderer, &fullRect, app)
 	}
 
+	if app.JobsPanel.IsOpen {
+		app.JobsPanel.Render(app.Renderer, &fullRect, app)
+	} else {
+		app.JobsPanel.RenderSummary(app.Renderer, &fullRect, app)
+	}
+
 	for i := int32(0); i < app.ViewCount; i++ {
 		if app.InfoViews[i].IsOpen {
 			app.InfoViews[i].Render(app.Renderer, &app.WindowRects[i], app)
@@ -456,10 +903,55 @@ func (app *App) Render() {
 		}
 	}
 
+	if app.TrashView.IsOpen {
+		DrawRectTransparent(app.Renderer, &fullRect, sdl.Color{R: 0, G: 0, B: 0, A: 150})
+		app.TrashView.Render(app.Renderer, &fullRect, app)
+	}
+
+	if app.DiskUsageView.IsOpen {
+		DrawRectTransparent(app.Renderer, &fullRect, sdl.Color{R: 0, G: 0, B: 0, A: 150})
+		app.DiskUsageView.Render(app.Renderer, &fullRect, app)
+	}
+
+	if app.DuplicatesView.IsOpen {
+		DrawRectTransparent(app.Renderer, &fullRect, sdl.Color{R: 0, G: 0, B: 0, A: 150})
+		app.DuplicatesView.Render(app.Renderer, &fullRect, app)
+	}
+
+	if app.SyncView.IsOpen {
+		DrawRectTransparent(app.Renderer, &fullRect, sdl.Color{R: 0, G: 0, B: 0, A: 150})
+		app.SyncView.Render(app.Renderer, &fullRect, app)
+	}
+
+	if app.FindView.IsOpen {
+		DrawRectTransparent(app.Renderer, &fullRect, sdl.Color{R: 0, G: 0, B: 0, A: 150})
+		app.FindView.Render(app.Renderer, &fullRect, app)
+	}
+
+	if app.AttributesView.IsOpen {
+		DrawRectTransparent(app.Renderer, &fullRect, sdl.Color{R: 0, G: 0, B: 0, A: 150})
+		app.AttributesView.Render(app.Renderer, &fullRect, app)
+	}
+
+	if app.BulkRenameView.IsOpen {
+		DrawRectTransparent(app.Renderer, &fullRect, sdl.Color{R: 0, G: 0, B: 0, A: 150})
+		app.BulkRenameView.Render(app.Renderer, &fullRect, app)
+	}
+
+	if app.BatchRenameView.IsOpen {
+		DrawRectTransparent(app.Renderer, &fullRect, sdl.Color{R: 0, G: 0, B: 0, A: 150})
+		app.BatchRenameView.Render(app.Renderer, &fullRect, app)
+	}
+
 	if app.QuickOpen.IsOpen {
 		DrawRectTransparent(app.Renderer, &app.WindowRects[app.ActiveView], sdl.Color{R: 0, G: 0, B: 0, A: 150})
 		app.QuickOpen.Render(app.Renderer, &app.ItemViews[app.ActiveView].Rect, &app.Font, app.Theme.QuickOpenTheme, app.Theme.InputFieldTheme)
 	}
 
+	if app.ConflictPrompt.IsOpen {
+		DrawRectTransparent(app.Renderer, &fullRect, sdl.Color{R: 0, G: 0, B: 0, A: 150})
+		app.ConflictPrompt.Render(app.Renderer, &fullRect, app)
+	}
+
 	app.Renderer.Present()
 }
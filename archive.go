@@ -0,0 +1,447 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Archives are browsed as read-only file systems. When an archive is opened, only the list of its entries is read.
+// The contents of an entry are streamed from the archive when they are needed, nothing is extracted to the disk.
+// Zip entries can be read directly, because zip has an index with the offset of every entry. Tar archives have to
+// be read from the start until the entry is found.
+
+type ArchiveFormat int32
+
+const (
+	ArchiveZip ArchiveFormat = iota
+	ArchiveTar
+	ArchiveTarGz
+	ArchiveTarZst
+)
+
+const zipMethodZstd = 93
+
+var archiveExtensions = []struct {
+	Extension string
+	Format    ArchiveFormat
+}{
+	{".zip", ArchiveZip},
+	{".tar", ArchiveTar},
+	{".tar.gz", ArchiveTarGz},
+	{".tgz", ArchiveTarGz},
+	{".tar.zst", ArchiveTarZst},
+	{".tzst", ArchiveTarZst},
+}
+
+func GetArchiveFormat(name string) (ArchiveFormat, bool) {
+	lowercase := strings.ToLower(name)
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(lowercase, ext.Extension) {
+			return ext.Format, true
+		}
+	}
+
+	return 0, false
+}
+
+func IsArchive(name string) bool {
+	_, ok := GetArchiveFormat(name)
+	return ok
+}
+
+// Returns the name of the archive without its extension, e.g. "photos" for "photos.tar.gz"
+func getArchiveBaseName(name string) string {
+	lowercase := strings.ToLower(name)
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(lowercase, ext.Extension) && len(name) > len(ext.Extension) {
+			return name[:len(name)-len(ext.Extension)]
+		}
+	}
+
+	return name
+}
+
+type archiveEntry struct {
+	Info     *virtualFileInfo
+	Children []string // Full paths of the entries inside of a folder
+
+	// Zip only: where the compressed data starts and how it is compressed
+	Offset         int64
+	CompressedSize int64
+	Method         uint16
+
+	LinkTarget string // Tar only: full path of the entry a hard link points to, resolved to a file that isn't a link
+	BrokenLink bool   // Tar only: the hard link points to itself, to a missing entry or around in circles
+	Symlink    string // Tar only: target of a symbolic link, as stored in the archive
+}
+
+type ArchiveFileSystem struct {
+	ArchivePath string
+	Format      ArchiveFormat
+
+	entries map[string]*archiveEntry
+}
+
+// Reads the list of entries in the archive. Might take a while for big compressed tar archives, because they
+// have to be decompressed to get to all the headers.
+func OpenArchive(archivePath string) (*ArchiveFileSystem, error) {
+	format, ok := GetArchiveFormat(archivePath)
+	if !ok {
+		return nil, errors.New(archivePath + " is not a supported archive")
+	}
+
+	stats, err := os.Stat(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ArchiveFileSystem{
+		ArchivePath: archivePath,
+		Format:      format,
+		entries:     make(map[string]*archiveEntry),
+	}
+	result.entries["/"] = &archiveEntry{Info: &virtualFileInfo{name: path.Base(archivePath), mode: fs.ModeDir | 0755, modTime: stats.ModTime()}}
+
+	if format == ArchiveZip {
+		err = result.readZipIndex()
+	} else {
+		err = result.readTarIndex()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (a *ArchiveFileSystem) Location() string {
+	return a.ArchivePath
+}
+
+func (a *ArchiveFileSystem) readZipIndex() error {
+	reader, err := zip.OpenReader(a.ArchivePath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		info := &virtualFileInfo{
+			name:    path.Base(cleanArchivePath(file.Name)),
+			size:    int64(file.UncompressedSize64),
+			mode:    file.Mode(),
+			modTime: file.Modified,
+		}
+
+		entry := &archiveEntry{Info: info, Method: file.Method, CompressedSize: int64(file.CompressedSize64)}
+		if !info.IsDir() {
+			entry.Offset, err = file.DataOffset()
+			if err != nil {
+				return err
+			}
+		}
+
+		// Encrypted entries can be listed, but not read
+		if file.Flags&0x1 != 0 {
+			entry.Method = 0xFFFF
+		}
+
+		a.addEntry(file.Name, entry)
+	}
+
+	return nil
+}
+
+func (a *ArchiveFileSystem) readTarIndex() error {
+	reader, closer, err := a.openTarStream()
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			a.resolveTarLinks()
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		info := &virtualFileInfo{
+			name:    path.Base(cleanArchivePath(header.Name)),
+			size:    header.Size,
+			mode:    header.FileInfo().Mode(),
+			modTime: header.ModTime,
+		}
+
+		entry := &archiveEntry{Info: info}
+		if header.Typeflag == tar.TypeLink {
+			entry.LinkTarget = cleanArchivePath(header.Linkname)
+		} else if header.Typeflag == tar.TypeSymlink {
+			entry.Symlink = header.Linkname
+		}
+
+		a.addEntry(header.Name, entry)
+	}
+}
+
+// Points every hard link at the file at the end of its chain, so that opening a link never has to follow another
+// one. Crafted archives can have links to themselves or to each other, which are marked as broken instead.
+func (a *ArchiveFileSystem) resolveTarLinks() {
+	for fullPath, entry := range a.entries {
+		if entry.LinkTarget == "" {
+			continue
+		}
+
+		visited := map[string]bool{fullPath: true}
+		target := entry.LinkTarget
+		for {
+			targetEntry, ok := a.entries[target]
+			if !ok || visited[target] || targetEntry.Info.IsDir() {
+				entry.BrokenLink = true
+				break
+			}
+
+			if targetEntry.LinkTarget == "" {
+				entry.LinkTarget = target
+				break
+			}
+
+			visited[target] = true
+			target = targetEntry.LinkTarget
+		}
+	}
+}
+
+// Adds the entry and any of its parent folders that the archive doesn't list on their own
+func (a *ArchiveFileSystem) addEntry(name string, entry *archiveEntry) {
+	fullPath := cleanArchivePath(name)
+	if fullPath == "/" {
+		return
+	}
+
+	existing, ok := a.entries[fullPath]
+	if ok {
+		// A folder might have been added before because one of its children came first
+		entry.Children = existing.Children
+	} else {
+		a.addToParent(fullPath)
+	}
+
+	a.entries[fullPath] = entry
+}
+
+func (a *ArchiveFileSystem) addToParent(fullPath string) {
+	parentPath := path.Dir(fullPath)
+
+	parent, ok := a.entries[parentPath]
+	if !ok {
+		parent = &archiveEntry{Info: &virtualFileInfo{name: path.Base(parentPath), mode: fs.ModeDir | 0755, modTime: a.entries["/"].Info.modTime}}
+		a.entries[parentPath] = parent
+		a.addToParent(parentPath)
+	}
+
+	parent.Children = append(parent.Children, fullPath)
+}
+
+func cleanArchivePath(name string) string {
+	return path.Clean("/" + strings.ReplaceAll(name, "\\", "/"))
+}
+
+func (a *ArchiveFileSystem) getEntry(fullPath string) (*archiveEntry, error) {
+	entry, ok := a.entries[path.Clean("/"+fullPath)]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: GetFileSystemPath(a, fullPath), Err: fs.ErrNotExist}
+	}
+
+	return entry, nil
+}
+
+func (a *ArchiveFileSystem) ReadDir(dirPath string) ([]fs.FileInfo, error) {
+	entry, err := a.getEntry(dirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if !entry.Info.IsDir() {
+		return nil, errors.New(GetFileSystemPath(a, dirPath) + " is not a folder")
+	}
+
+	result := make([]fs.FileInfo, len(entry.Children))
+	for index, child := range entry.Children {
+		result[index] = a.entries[child].Info
+	}
+
+	return result, nil
+}
+
+func (a *ArchiveFileSystem) Stat(fullPath string) (fs.FileInfo, error) {
+	entry, err := a.getEntry(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry.Info, nil
+}
+
+func (a *ArchiveFileSystem) Open(fullPath string) (io.ReadCloser, error) {
+	entry, err := a.getEntry(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.Info.IsDir() {
+		return nil, errors.New(GetFileSystemPath(a, fullPath) + " is a folder")
+	}
+
+	if a.Format == ArchiveZip {
+		return a.openZipEntry(fullPath, entry)
+	}
+
+	if entry.BrokenLink {
+		return nil, errors.New(GetFileSystemPath(a, fullPath) + " is a broken link")
+	}
+
+	if entry.LinkTarget != "" {
+		return a.openTarEntry(entry.LinkTarget)
+	}
+
+	return a.openTarEntry(path.Clean("/" + fullPath))
+}
+
+// Returns the target of a symbolic link stored in the archive. Zip keeps the target as the contents of the entry.
+func (a *ArchiveFileSystem) ReadLink(fullPath string) (string, error) {
+	entry, err := a.getEntry(fullPath)
+	if err != nil {
+		return "", err
+	}
+
+	if entry.Info.Mode()&fs.ModeSymlink == 0 {
+		return "", errors.New(GetFileSystemPath(a, fullPath) + " is not a link")
+	}
+
+	if a.Format != ArchiveZip {
+		return entry.Symlink, nil
+	}
+
+	reader, err := a.openZipEntry(fullPath, entry)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	target, err := io.ReadAll(io.LimitReader(reader, 4096))
+	return string(target), err
+}
+
+func (a *ArchiveFileSystem) openZipEntry(fullPath string, entry *archiveEntry) (io.ReadCloser, error) {
+	file, err := os.Open(a.ArchivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	data := io.NewSectionReader(file, entry.Offset, entry.CompressedSize)
+
+	switch entry.Method {
+	case zip.Store:
+		return &streamReader{Reader: data, closers: []io.Closer{file}}, nil
+	case zip.Deflate:
+		decompressor := flate.NewReader(data)
+		return &streamReader{Reader: decompressor, closers: []io.Closer{decompressor, file}}, nil
+	case zipMethodZstd:
+		decoder, err := zstd.NewReader(data)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		return &streamReader{Reader: decoder, closers: []io.Closer{decoder.IOReadCloser(), file}}, nil
+	}
+
+	file.Close()
+	return nil, errors.New(GetFileSystemPath(a, fullPath) + " is encrypted or uses an unsupported compression method")
+}
+
+// Reads the tar archive from the start until the entry is found and returns a reader for its contents
+func (a *ArchiveFileSystem) openTarEntry(fullPath string) (io.ReadCloser, error) {
+	reader, closer, err := a.openTarStream()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		header, err := reader.Next()
+		if err != nil {
+			closer.Close()
+
+			if err == io.EOF {
+				return nil, &fs.PathError{Op: "open", Path: GetFileSystemPath(a, fullPath), Err: fs.ErrNotExist}
+			}
+
+			return nil, err
+		}
+
+		if cleanArchivePath(header.Name) == fullPath {
+			return &streamReader{Reader: reader, closers: []io.Closer{closer}}, nil
+		}
+	}
+}
+
+func (a *ArchiveFileSystem) openTarStream() (*tar.Reader, io.Closer, error) {
+	file, err := os.Open(a.ArchivePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch a.Format {
+	case ArchiveTarGz:
+		decompressor, err := gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+
+		return tar.NewReader(decompressor), &streamReader{closers: []io.Closer{decompressor, file}}, nil
+	case ArchiveTarZst:
+		decoder, err := zstd.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+
+		return tar.NewReader(decoder), &streamReader{closers: []io.Closer{decoder.IOReadCloser(), file}}, nil
+	}
+
+	return tar.NewReader(file), file, nil
+}
+
+// A reader that closes everything it was made from, innermost first
+type streamReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (s *streamReader) Close() (err error) {
+	for _, closer := range s.closers {
+		closeErr := closer.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}
+
+	return
+}
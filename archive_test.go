@@ -0,0 +1,149 @@
+package main
+
+import (
+	"archive/tar"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestCleanArchivePath(t *testing.T) {
+	tests := map[string]string{
+		"a.txt":                "/a.txt",
+		"folder/":              "/folder",
+		"./folder/a.txt":       "/folder/a.txt",
+		"../a.txt":             "/a.txt",
+		"../../etc/passwd":     "/etc/passwd",
+		"folder/../../a.txt":   "/a.txt",
+		"/etc/passwd":          "/etc/passwd",
+		"//server/share/a.txt": "/server/share/a.txt",
+		`..\..\windows\a.txt`:  "/windows/a.txt",
+		`C:\a.txt`:             "/C:/a.txt",
+		"":                     "/",
+		"..":                   "/",
+	}
+
+	for name, expected := range tests {
+		result := cleanArchivePath(name)
+		if result != expected {
+			t.Errorf("%q: got %q, expected %q", name, result, expected)
+		}
+	}
+}
+
+func TestIsLinkInside(t *testing.T) {
+	tests := []struct {
+		linkPath string
+		target   string
+		inside   bool
+	}{
+		{linkPath: "/root/link", target: "a.txt", inside: true},
+		{linkPath: "/root/link", target: "folder/a.txt", inside: true},
+		{linkPath: "/root/folder/link", target: "../a.txt", inside: true},
+		{linkPath: "/root/folder/link", target: "../folder/../a.txt", inside: true},
+		{linkPath: "/root/link", target: ".", inside: true},
+		{linkPath: "/root/link", target: "..", inside: false},
+		{linkPath: "/root/link", target: "../a.txt", inside: false},
+		{linkPath: "/root/folder/link", target: "../../a.txt", inside: false},
+		{linkPath: "/root/folder/link", target: "a/../../../a.txt", inside: false},
+		{linkPath: "/root/folder/link", target: `..\..\a.txt`, inside: false},
+		{linkPath: "/root/link", target: "/etc/passwd", inside: false},
+		{linkPath: "/root/link", target: "/root/a.txt", inside: false},
+		{linkPath: "/root/link", target: `C:\Windows`, inside: false},
+		{linkPath: "/root/link", target: "", inside: false},
+	}
+
+	for _, test := range tests {
+		result := isLinkInside("/root", test.linkPath, test.target)
+		if result != test.inside {
+			t.Errorf("%s -> %s: got %v, expected %v", test.linkPath, test.target, result, test.inside)
+		}
+	}
+}
+
+func writeTestTar(t *testing.T, fullPath string, headers []*tar.Header) {
+	t.Helper()
+
+	file, err := os.Create(fullPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	writer := tar.NewWriter(file)
+	for _, header := range headers {
+		header.Mode = 0644
+		err = writer.WriteHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = writer.Write([]byte(header.Name)[:header.Size])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtractStaysInside(t *testing.T) {
+	directory := t.TempDir()
+	archivePath := path.Join(directory, "crafted.tar")
+
+	writeTestTar(t, archivePath, []*tar.Header{
+		{Name: "../../escaped.txt", Typeflag: tar.TypeReg, Size: 4},
+		{Name: "/absolute.txt", Typeflag: tar.TypeReg, Size: 4},
+		{Name: "folder/inside", Typeflag: tar.TypeSymlink, Linkname: "../escaped.txt"},
+		{Name: "folder/up", Typeflag: tar.TypeSymlink, Linkname: "../../outside.txt"},
+		{Name: "absolute", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"},
+	})
+
+	archive, err := OpenArchive(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	extracted := path.Join(directory, "extracted", "crafted")
+	err = os.Mkdir(path.Dir(extracted), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The links that lead out are skipped, the rest is still extracted
+	err = CopyFromFileSystem(archive, "/", extracted, &Job{})
+	if err == nil {
+		t.Fatal("extracting links that lead out should be reported")
+	}
+
+	for _, name := range []string{"escaped.txt", "absolute.txt"} {
+		_, err = os.Stat(path.Join(extracted, name))
+		if err != nil {
+			t.Errorf("%s wasn't extracted into the folder: %v", name, err)
+		}
+	}
+
+	for _, name := range []string{path.Join(directory, "escaped.txt"), "/absolute.txt"} {
+		_, err = os.Lstat(name)
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("%s was written outside of the folder", name)
+		}
+	}
+
+	target, err := os.Readlink(path.Join(extracted, "folder", "inside"))
+	if err != nil || target != "../escaped.txt" {
+		t.Errorf("the link inside of the folder points to %q: %v", target, err)
+	}
+
+	for _, name := range []string{"folder/up", "absolute"} {
+		_, err = os.Lstat(path.Join(extracted, name))
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("the link %s that leads out was extracted", name)
+		}
+	}
+}
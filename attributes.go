@@ -0,0 +1,236 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// The attributes of an item that can be edited: the permissions, the owner and the group, the times and the
+// extended attributes. Changes only list what was edited, so that editing several items at once leaves the rest of
+// their attributes as they were.
+
+const attributeTimeLayout = "2006-01-02 15:04:05"
+
+// The permission bits and the special bits, which are the bits chmod can change
+const attributeModeMask = fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky
+
+type Xattr struct {
+	Name  string
+	Value string
+}
+
+type Attributes struct {
+	Mode     fs.FileMode
+	Owner    string
+	Group    string
+	Modified time.Time
+	Accessed time.Time
+	Xattrs   []Xattr // Sorted by name
+	IsFolder bool
+
+	// What the platform and the file system support
+	HasOwners bool
+	HasXattrs bool
+}
+
+type AttributeChange struct {
+	SetMode   fs.FileMode
+	ClearMode fs.FileMode
+	Owner     string    // Empty to keep the owner
+	Group     string    // Empty to keep the group
+	Modified  time.Time // Zero to keep the time
+	Accessed  time.Time // Zero to keep the time
+
+	SetXattrs    []Xattr
+	RemoveXattrs []string
+}
+
+func (c *AttributeChange) IsEmpty() bool {
+	return c.SetMode == 0 && c.ClearMode == 0 && c.Owner == "" && c.Group == "" && c.Modified.IsZero() &&
+		c.Accessed.IsZero() && len(c.SetXattrs) == 0 && len(c.RemoveXattrs) == 0
+}
+
+// Links are followed, like chmod does
+func ReadAttributes(fullPath string) (result Attributes, err error) {
+	stats, err := os.Stat(fullPath)
+	if err != nil {
+		return
+	}
+
+	result.Mode = stats.Mode() & attributeModeMask
+	result.Modified = stats.ModTime()
+	result.IsFolder = stats.IsDir()
+	result.Owner, result.Group, result.HasOwners = getOwnership(stats)
+	result.Accessed = getAccessTime(stats)
+
+	xattrs, err := listXattrs(fullPath)
+	if err == nil {
+		result.Xattrs = xattrs
+		result.HasXattrs = true
+	}
+
+	sort.Slice(result.Xattrs, func(i, j int) bool {
+		return result.Xattrs[i].Name < result.Xattrs[j].Name
+	})
+
+	return result, nil
+}
+
+// Applies the change to every item, and to everything inside of the folders if recursive is set. Links inside of the
+// folders are skipped, so that nothing outside of them is changed. Items that can't be changed don't stop the rest,
+// their errors are returned together.
+func ApplyAttributes(paths []string, change AttributeChange, recursive bool, job *Job) error {
+	uid, gid, err := lookupOwnership(change.Owner, change.Group)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	apply := func(fullPath string) error {
+		err := applyAttributeChange(fullPath, &change, uid, gid)
+		if err != nil {
+			errs = append(errs, err)
+		}
+
+		return job.AddFile()
+	}
+
+	for _, fullPath := range paths {
+		stats, err := os.Stat(fullPath)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if !recursive || !stats.IsDir() {
+			err = apply(fullPath)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		err = filepath.WalkDir(fullPath, func(itemPath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				errs = append(errs, err)
+				return nil
+			}
+
+			if entry.Type()&fs.ModeSymlink != 0 && itemPath != fullPath {
+				return nil
+			}
+
+			return apply(filepath.ToSlash(itemPath))
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%d items couldn't be changed: %w", len(errs), joinErrors(errs))
+	}
+
+	return nil
+}
+
+func applyAttributeChange(fullPath string, change *AttributeChange, uid int, gid int) error {
+	stats, err := os.Stat(fullPath)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	// The owner goes first, because changing it clears the setuid and setgid bits
+	if uid >= 0 || gid >= 0 {
+		err = os.Chown(fullPath, uid, gid)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if change.SetMode != 0 || change.ClearMode != 0 {
+		mode := stats.Mode()&attributeModeMask&^change.ClearMode | change.SetMode
+		err = os.Chmod(fullPath, mode)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Chtimes leaves the zero times as they are
+	if !change.Modified.IsZero() || !change.Accessed.IsZero() {
+		err = os.Chtimes(fullPath, change.Accessed, change.Modified)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, xattr := range change.SetXattrs {
+		err = setXattr(fullPath, xattr.Name, xattr.Value)
+		if err != nil {
+			errs = append(errs, &fs.PathError{Op: "setxattr " + xattr.Name, Path: fullPath, Err: err})
+		}
+	}
+
+	for _, name := range change.RemoveXattrs {
+		err = removeXattr(fullPath, name)
+		if err != nil && !errors.Is(err, errXattrNotFound) {
+			errs = append(errs, &fs.PathError{Op: "removexattr " + name, Path: fullPath, Err: err})
+		}
+	}
+
+	return joinErrors(errs)
+}
+
+// Formats the mode the way chmod takes it, e.g. "755" or "4755" with the special bits
+func FormatOctalMode(mode fs.FileMode) string {
+	value := uint32(mode.Perm())
+	if mode&fs.ModeSetuid != 0 {
+		value |= 04000
+	}
+	if mode&fs.ModeSetgid != 0 {
+		value |= 02000
+	}
+	if mode&fs.ModeSticky != 0 {
+		value |= 01000
+	}
+
+	return fmt.Sprintf("%03o", value)
+}
+
+func ParseOctalMode(value string) (fs.FileMode, error) {
+	parsed, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || parsed > 07777 {
+		return 0, errors.New(value + " is not an octal mode, e.g. 755")
+	}
+
+	result := fs.FileMode(parsed) & fs.ModePerm
+	if parsed&04000 != 0 {
+		result |= fs.ModeSetuid
+	}
+	if parsed&02000 != 0 {
+		result |= fs.ModeSetgid
+	}
+	if parsed&01000 != 0 {
+		result |= fs.ModeSticky
+	}
+
+	return result, nil
+}
+
+func ParseAttributeTime(value string) (time.Time, error) {
+	result, err := time.ParseInLocation(attributeTimeLayout, value, time.Local)
+	if err != nil {
+		return result, errors.New(value + " is not a time, e.g. " + time.Now().Format(attributeTimeLayout))
+	}
+
+	return result, nil
+}
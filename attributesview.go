@@ -0,0 +1,523 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Edits the attributes of one or more items, see attributes.go. The first row is a grid of the mode bits, the rows
+// below it are fields. Fields that differ between the items start empty and are only applied if something is typed
+// into them, the same goes for the bits of the grid.
+
+type attributeFieldKind int32
+
+const (
+	attributeFieldOctal attributeFieldKind = iota
+	attributeFieldOwner
+	attributeFieldGroup
+	attributeFieldModified
+	attributeFieldAccessed
+	attributeFieldXattr
+	attributeFieldNewXattr
+)
+
+type attributeField struct {
+	Kind    attributeFieldKind
+	Label   string
+	Input   *InputField
+	Initial string
+	IsMixed bool   // The items have different values, so the field starts empty
+	Xattr   string // The name of the extended attribute
+}
+
+// The rows of the grid are the owner, the group, the others and the special bits, the columns are read, write and
+// execute, or setuid, setgid and sticky for the special bits
+var attributeGridBits = [4][3]fs.FileMode{
+	{0400, 0200, 0100},
+	{0040, 0020, 0010},
+	{0004, 0002, 0001},
+	{fs.ModeSetuid, fs.ModeSetgid, fs.ModeSticky},
+}
+
+var attributeGridRows = []string{"Owner", "Group", "Others", "Special"}
+var attributeGridColumns = []string{"Read", "Write", "Execute"}
+var attributeSpecialBits = []string{"setuid", "setgid", "sticky"}
+
+type AttributesView struct {
+	IsOpen     bool
+	Folder     string
+	Names      []string
+	HasFolders bool
+	Recursive  bool
+
+	Mode        fs.FileMode
+	InitialMode fs.FileMode // The mode of the first item
+	MixedMode   fs.FileMode // The bits that differ between the items
+	ChangedMode fs.FileMode // The bits that were edited
+
+	Fields     []attributeField
+	ActiveRow  int // 0 is the grid, then the fields, then the recursive toggle
+	GridRow    int
+	GridColumn int
+	Error      string
+
+	MaxWidth     int32
+	Padding      int32
+	HeaderHeight int32
+	FieldHeight  int32
+	LabelWidth   int32
+	CellWidth    int32
+	LineHeight   int32
+}
+
+func NewAttributesView() *AttributesView {
+	return &AttributesView{
+		MaxWidth:     640,
+		Padding:      8,
+		HeaderHeight: 28,
+		FieldHeight:  40,
+		LabelWidth:   150,
+		CellWidth:    110,
+		LineHeight:   24,
+	}
+}
+
+func (a *AttributesView) Open(folder string, names []string) {
+	attributes := make([]Attributes, len(names))
+	for index, name := range names {
+		var err error
+		attributes[index], err = ReadAttributes(path.Join(folder, name))
+		if err != nil {
+			NotifyError(err.Error())
+			return
+		}
+	}
+
+	a.IsOpen = true
+	a.Folder = folder
+	a.Names = names
+	a.Recursive = false
+	a.HasFolders = false
+	a.ActiveRow = 0
+	a.GridRow = 0
+	a.GridColumn = 0
+	a.Error = ""
+
+	first := attributes[0]
+	a.Mode = first.Mode
+	a.InitialMode = first.Mode
+	a.MixedMode = 0
+	a.ChangedMode = 0
+
+	hasOwners, hasXattrs := true, true
+	for _, item := range attributes {
+		a.MixedMode |= item.Mode ^ first.Mode
+		a.HasFolders = a.HasFolders || item.IsFolder
+		hasOwners = hasOwners && item.HasOwners
+		hasXattrs = hasXattrs && item.HasXattrs
+	}
+
+	a.Fields = nil
+	octal := ""
+	if a.MixedMode == 0 {
+		octal = FormatOctalMode(a.Mode)
+	}
+	a.addField(attributeFieldOctal, "Mode (octal)", octal, a.MixedMode != 0)
+	a.Fields[0].Input.OnInputCallback = a.onOctalInput
+
+	if hasOwners {
+		a.addCommonField(attributeFieldOwner, "Owner", attributes, func(item Attributes) string { return item.Owner })
+		a.addCommonField(attributeFieldGroup, "Group", attributes, func(item Attributes) string { return item.Group })
+	}
+
+	a.addCommonField(attributeFieldModified, "Modified", attributes, func(item Attributes) string {
+		return item.Modified.Format(attributeTimeLayout)
+	})
+	a.addCommonField(attributeFieldAccessed, "Accessed", attributes, func(item Attributes) string {
+		return item.Accessed.Format(attributeTimeLayout)
+	})
+
+	if hasXattrs {
+		for _, name := range getXattrNames(attributes) {
+			a.addCommonField(attributeFieldXattr, name, attributes, func(item Attributes) string {
+				for _, xattr := range item.Xattrs {
+					if xattr.Name == name {
+						return xattr.Value
+					}
+				}
+
+				return ""
+			})
+			a.Fields[len(a.Fields)-1].Xattr = name
+		}
+
+		a.addField(attributeFieldNewXattr, "New attribute", "", false)
+	}
+}
+
+func (a *AttributesView) Close() {
+	a.IsOpen = false
+}
+
+func (a *AttributesView) addField(kind attributeFieldKind, label string, value string, isMixed bool) {
+	input := NewInputField(sdl.Rect{H: a.FieldHeight}, nil)
+	input.Value.WriteString(value)
+
+	a.Fields = append(a.Fields, attributeField{Kind: kind, Label: label, Input: input, Initial: value, IsMixed: isMixed})
+}
+
+// Adds a field with the value of the items, or an empty one if their values differ
+func (a *AttributesView) addCommonField(kind attributeFieldKind, label string, attributes []Attributes, getValue func(item Attributes) string) {
+	value := getValue(attributes[0])
+	for _, item := range attributes[1:] {
+		if getValue(item) != value {
+			a.addField(kind, label, "", true)
+			return
+		}
+	}
+
+	a.addField(kind, label, value, false)
+}
+
+// Returns the names of the extended attributes of all of the items
+func getXattrNames(attributes []Attributes) (result []string) {
+	for _, item := range attributes {
+		for _, xattr := range item.Xattrs {
+			if IndexOf(result, xattr.Name) < 0 {
+				result = append(result, xattr.Name)
+			}
+		}
+	}
+
+	sort.Strings(result)
+	return
+}
+
+// Keeps the grid in sync with the mode that is being typed. Clearing the field leaves the mode as it was.
+func (a *AttributesView) onOctalInput(value string) {
+	if value == "" {
+		a.Mode = a.InitialMode
+		a.ChangedMode = 0
+		return
+	}
+
+	mode, err := ParseOctalMode(value)
+	if err == nil {
+		a.Mode = mode
+		a.ChangedMode = attributeModeMask
+	}
+}
+
+func (a *AttributesView) toggleActiveBit() {
+	bit := attributeGridBits[a.GridRow][a.GridColumn]
+
+	// A bit that differs between the items is set first, because that's usually what is wanted, e.g. making every
+	// script executable
+	if a.MixedMode&^a.ChangedMode&bit != 0 {
+		a.Mode |= bit
+	} else {
+		a.Mode ^= bit
+	}
+	a.ChangedMode |= bit
+
+	// The octal mode can only be shown once every bit is known
+	if a.MixedMode&^a.ChangedMode == 0 {
+		octal := a.Fields[0].Input
+		octal.Value.Reset()
+		octal.Value.WriteString(FormatOctalMode(a.Mode))
+	}
+}
+
+func (a *AttributesView) getRowCount() int {
+	result := len(a.Fields) + 1
+	if a.HasFolders {
+		result++
+	}
+
+	return result
+}
+
+func (a *AttributesView) Tick(input *Input, app *App) {
+	if input.Escape {
+		a.Close()
+		return
+	}
+
+	rowCount := a.getRowCount()
+	isGrid := a.ActiveRow == 0
+	isRecursive := a.HasFolders && a.ActiveRow == rowCount-1
+
+	switch {
+	case input.TypedCharacter == '\n':
+		a.apply(app)
+	case input.TypedCharacter == '\t':
+		a.ActiveRow = (a.ActiveRow + 1) % rowCount
+	case input.Up:
+		if isGrid && a.GridRow > 0 {
+			a.GridRow--
+		} else if a.ActiveRow > 0 {
+			a.ActiveRow--
+		}
+	case input.Down:
+		if isGrid && a.GridRow < len(attributeGridBits)-1 {
+			a.GridRow++
+		} else if a.ActiveRow < rowCount-1 {
+			a.ActiveRow++
+		}
+	case isGrid && input.Left:
+		if a.GridColumn > 0 {
+			a.GridColumn--
+		}
+	case isGrid && input.Right:
+		if a.GridColumn < len(attributeGridColumns)-1 {
+			a.GridColumn++
+		}
+	case isGrid && input.TypedCharacter == ' ':
+		a.toggleActiveBit()
+	case isRecursive && input.TypedCharacter == ' ':
+		a.Recursive = !a.Recursive
+	case !isGrid && !isRecursive:
+		a.Fields[a.ActiveRow-1].Input.Tick(input)
+	}
+}
+
+// Collects what was edited. Returns false if a field has a value that can't be used.
+func (a *AttributesView) getChange() (result AttributeChange, ok bool) {
+	for _, field := range a.Fields {
+		value := strings.TrimSpace(field.Input.Value.String())
+		if value == field.Initial {
+			continue
+		}
+
+		var err error
+		switch field.Kind {
+		case attributeFieldOctal:
+			if value != "" {
+				a.Mode, err = ParseOctalMode(value)
+				a.ChangedMode = attributeModeMask
+			}
+		case attributeFieldOwner:
+			result.Owner = value
+		case attributeFieldGroup:
+			result.Group = value
+		case attributeFieldModified:
+			if value != "" {
+				result.Modified, err = ParseAttributeTime(value)
+			}
+		case attributeFieldAccessed:
+			if value != "" {
+				result.Accessed, err = ParseAttributeTime(value)
+			}
+		case attributeFieldXattr:
+			// Clearing the value removes the attribute
+			if value == "" {
+				result.RemoveXattrs = append(result.RemoveXattrs, field.Xattr)
+			} else {
+				result.SetXattrs = append(result.SetXattrs, Xattr{Name: field.Xattr, Value: value})
+			}
+		case attributeFieldNewXattr:
+			name, xattrValue, found := strings.Cut(value, "=")
+			name = strings.TrimSpace(name)
+			if !found || name == "" {
+				a.Error = "Type the new attribute as name=value"
+				return result, false
+			}
+
+			// Users can only set the attributes in the user namespace
+			if !strings.Contains(name, ".") {
+				name = "user." + name
+			}
+
+			result.SetXattrs = append(result.SetXattrs, Xattr{Name: name, Value: strings.TrimSpace(xattrValue)})
+		}
+
+		if err != nil {
+			a.Error = field.Label + ": " + err.Error()
+			return result, false
+		}
+	}
+
+	result.SetMode = a.Mode & a.ChangedMode
+	result.ClearMode = attributeModeMask &^ a.Mode & a.ChangedMode
+
+	return result, true
+}
+
+func (a *AttributesView) apply(app *App) {
+	change, ok := a.getChange()
+	if !ok {
+		return
+	}
+
+	a.Close()
+	if change.IsEmpty() {
+		return
+	}
+
+	paths := make([]string, len(a.Names))
+	for index, name := range a.Names {
+		paths[index] = path.Join(a.Folder, name)
+	}
+
+	folder := a.Folder
+	title := a.getTitle()
+	recursive := a.Recursive
+
+	app.Jobs.Add("Changing the attributes of "+title, func(job *Job) error {
+		return ApplyAttributes(paths, change, recursive, job)
+	}, func(err error) {
+		app.RefreshViewsShowing(folder)
+
+		if err == nil {
+			NotifyInfo("Changed the attributes of " + title)
+		}
+	})
+}
+
+func (a *AttributesView) getTitle() string {
+	if len(a.Names) == 1 {
+		return a.Names[0]
+	}
+
+	return fmt.Sprintf("%d items", len(a.Names))
+}
+
+func (a *AttributesView) Render(renderer *sdl.Renderer, parentRect *sdl.Rect, app *App) {
+	theme := app.Theme.PanelTheme
+
+	width := a.MaxWidth
+	if width > parentRect.W-20 {
+		width = parentRect.W - 20
+	}
+
+	gridHeight := int32(len(attributeGridBits)+1) * a.LineHeight
+	height := a.HeaderHeight + a.Padding*4 + gridHeight + int32(len(a.Fields))*a.FieldHeight + a.LineHeight
+	if a.HasFolders {
+		height += a.LineHeight
+	}
+	if height > parentRect.H-80 {
+		height = parentRect.H - 80
+	}
+
+	rect := sdl.Rect{
+		X: parentRect.X + (parentRect.W-width)/2,
+		Y: parentRect.Y + 40,
+		W: width,
+		H: height,
+	}
+
+	title := "Attributes of " + a.getTitle() + " (Tab or arrows to move, Space to toggle, Enter to apply, Esc to cancel)"
+	insetRect := DrawPanel(renderer, &rect, title, a.HeaderHeight, a.Padding, &app.Font, theme)
+	bottom := insetRect.Y + insetRect.H
+
+	y := insetRect.Y + a.Padding
+	x := insetRect.X + a.Padding
+	textColor := GetColor(theme, "text_color")
+	secondaryColor := GetColor(theme, "secondary_text_color")
+
+	for column, label := range attributeGridColumns {
+		labelRect := sdl.Rect{X: x + a.LabelWidth + int32(column)*a.CellWidth, Y: y, W: a.CellWidth, H: a.LineHeight}
+		DrawTextInRect(renderer, &app.Font, label, &labelRect, secondaryColor)
+	}
+	y += a.LineHeight
+
+	for row, bits := range attributeGridBits {
+		labelColor := secondaryColor
+		if a.ActiveRow == 0 && a.GridRow == row {
+			labelColor = GetColor(theme, "header_color")
+		}
+
+		labelRect := sdl.Rect{X: x, Y: y, W: a.LabelWidth, H: a.LineHeight}
+		DrawTextInRect(renderer, &app.Font, attributeGridRows[row], &labelRect, labelColor)
+
+		for column, bit := range bits {
+			cellRect := sdl.Rect{X: x + a.LabelWidth + int32(column)*a.CellWidth, Y: y, W: a.CellWidth - a.Padding, H: a.LineHeight}
+			if a.ActiveRow == 0 && a.GridRow == row && a.GridColumn == column {
+				DrawRect(renderer, &cellRect, GetColor(theme, "active_item_background_color"))
+			}
+
+			text := "[ ]"
+			if a.MixedMode&^a.ChangedMode&bit != 0 {
+				text = "[?]"
+			} else if a.Mode&bit != 0 {
+				text = "[x]"
+			}
+
+			if row == len(attributeGridBits)-1 {
+				text += " " + attributeSpecialBits[column]
+			}
+
+			textRect := cellRect
+			textRect.X += a.Padding
+			DrawTextInRect(renderer, &app.Font, text, &textRect, textColor)
+		}
+
+		y += a.LineHeight
+	}
+	y += a.Padding
+
+	for index, field := range a.Fields {
+		if y+a.FieldHeight > bottom {
+			break
+		}
+
+		labelColor := secondaryColor
+		if a.ActiveRow == index+1 {
+			labelColor = GetColor(theme, "header_color")
+		}
+
+		labelRect := sdl.Rect{X: x, Y: y, W: a.LabelWidth - a.Padding, H: a.FieldHeight}
+		DrawTextInRect(renderer, &app.Font, field.Label, &labelRect, labelColor)
+
+		field.Input.Rect.W = insetRect.W - a.LabelWidth - a.Padding
+		field.Input.Render(renderer, x+a.LabelWidth-a.Padding, y, &app.Font, app.Theme.InputFieldTheme)
+
+		placeholder := ""
+		if field.Input.Value.Len() == 0 {
+			if field.IsMixed {
+				placeholder = "differs between the items"
+			} else if field.Kind == attributeFieldNewXattr {
+				placeholder = "name=value"
+			}
+		}
+
+		placeholderRect := sdl.Rect{
+			X: x + a.LabelWidth - a.Padding + field.Input.BasePadding + field.Input.InputAreaPadding + 4,
+			Y: y,
+			W: field.Input.Rect.W / 2,
+			H: a.FieldHeight,
+		}
+		DrawTextInRect(renderer, &app.Font, placeholder, &placeholderRect, secondaryColor)
+
+		y += a.FieldHeight
+	}
+
+	if a.HasFolders && y+a.LineHeight <= bottom {
+		labelColor := secondaryColor
+		if a.ActiveRow == a.getRowCount()-1 {
+			labelColor = GetColor(theme, "header_color")
+		}
+
+		labelRect := sdl.Rect{X: x, Y: y, W: a.LabelWidth, H: a.LineHeight}
+		DrawTextInRect(renderer, &app.Font, "Folders", &labelRect, labelColor)
+
+		text := "[ ] Apply to everything inside"
+		if a.Recursive {
+			text = "[x] Apply to everything inside"
+		}
+
+		textRect := sdl.Rect{X: x + a.LabelWidth + a.Padding, Y: y, W: insetRect.W - a.LabelWidth - a.Padding*2, H: a.LineHeight}
+		DrawTextInRect(renderer, &app.Font, text, &textRect, textColor)
+		y += a.LineHeight
+	}
+
+	if a.Error != "" && y+a.LineHeight <= bottom {
+		errorRect := sdl.Rect{X: x, Y: y, W: insetRect.W - a.Padding*2, H: a.LineHeight}
+		DrawTextInRect(renderer, &app.Font, a.Error, &errorRect, GetColor(theme, "error_color"))
+	}
+}
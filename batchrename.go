@@ -0,0 +1,234 @@
+package main
+
+import (
+	"path"
+	"strconv"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Renames the selected items with a pattern (see renamepattern.go). The preview is updated on every key press and
+// the renames are only done once the user confirms them.
+
+type BatchRenameView struct {
+	IsOpen      bool
+	Directory   string
+	Names       []string
+	NewNames    []string
+	Fields      [2]*InputField // Find, Replace
+	ActiveField int
+	Preview     ListView
+	Error       string
+
+	metadata map[string]*RenameMetadata
+
+	MaxWidth     int32
+	Padding      int32
+	HeaderHeight int32
+	FieldHeight  int32
+	LabelWidth   int32
+	LineHeight   int32
+}
+
+func NewBatchRenameView() *BatchRenameView {
+	result := &BatchRenameView{
+		Preview:      *NewListView(),
+		MaxWidth:     700,
+		Padding:      8,
+		HeaderHeight: 28,
+		FieldHeight:  40,
+		LabelWidth:   90,
+		LineHeight:   24,
+	}
+
+	result.Preview.ItemHeight = 24
+
+	for i := range result.Fields {
+		result.Fields[i] = NewInputField(sdl.Rect{H: result.FieldHeight}, nil)
+	}
+
+	return result
+}
+
+func (b *BatchRenameView) Open(directory string, names []string) {
+	b.IsOpen = true
+	b.Directory = directory
+	b.Names = names
+	b.ActiveField = 1
+	b.Preview.ActiveItem = 0
+	b.metadata = make(map[string]*RenameMetadata)
+
+	for _, field := range b.Fields {
+		field.Clear()
+		field.OnInputCallback = func(string) {
+			b.updatePreview()
+		}
+	}
+
+	b.updatePreview()
+}
+
+func (b *BatchRenameView) Close() {
+	b.IsOpen = false
+	b.Names = nil
+	b.NewNames = nil
+	b.metadata = nil
+}
+
+func (b *BatchRenameView) Tick(input *Input, app *App) {
+	if input.Escape {
+		b.Close()
+		return
+	}
+
+	switch {
+	case input.TypedCharacter == '\t':
+		b.ActiveField = (b.ActiveField + 1) % len(b.Fields)
+	case input.TypedCharacter == '\n':
+		b.apply(app)
+	case input.Up:
+		if b.Preview.ActiveItem > 0 {
+			b.Preview.ActiveItem--
+		}
+	case input.Down:
+		if b.Preview.ActiveItem < int32(len(b.Preview.Items))-1 {
+			b.Preview.ActiveItem++
+		}
+	default:
+		b.Fields[b.ActiveField].Tick(input)
+	}
+}
+
+func (b *BatchRenameView) getMetadata(name string) func() *RenameMetadata {
+	return func() *RenameMetadata {
+		result, ok := b.metadata[name]
+		if !ok {
+			result = ReadRenameMetadata(path.Join(b.Directory, name))
+			b.metadata[name] = result
+		}
+
+		return result
+	}
+}
+
+func (b *BatchRenameView) updatePreview() {
+	b.Error = ""
+	b.NewNames = make([]string, len(b.Names))
+	copy(b.NewNames, b.Names)
+
+	items := make([]ListItem, len(b.Names))
+	for index, name := range b.Names {
+		items[index] = ListItem{Text: name, ColorKey: "secondary_text_color"}
+	}
+
+	find := b.Fields[0].Value.String()
+	template := b.Fields[1].Value.String()
+
+	if template == "" && find == "" {
+		b.Preview.SetItems(items)
+		return
+	}
+
+	pattern, err := ParseRenamePattern(find, template)
+	if err != nil {
+		b.Error = err.Error()
+		b.Preview.SetItems(items)
+		return
+	}
+
+	for index, name := range b.Names {
+		newName, err := pattern.Apply(b.Directory, name, index, b.getMetadata(name))
+		if err != nil {
+			items[index] = ListItem{Text: name + "  ->  " + err.Error(), ColorKey: "error_color"}
+			if b.Error == "" {
+				b.Error = err.Error()
+			}
+
+			continue
+		}
+
+		b.NewNames[index] = newName
+		if newName != name {
+			items[index] = ListItem{Text: name + "  ->  " + newName}
+		}
+	}
+
+	b.Preview.SetItems(items)
+
+	if b.Error == "" {
+		_, err = PlanRenames(b.Directory, b.Names, b.NewNames)
+		if err != nil {
+			b.Error = err.Error()
+		}
+	}
+}
+
+func (b *BatchRenameView) apply(app *App) {
+	if b.Error != "" {
+		return
+	}
+
+	// The files might have changed since the preview was made
+	ops, err := PlanRenames(b.Directory, b.Names, b.NewNames)
+	if err != nil {
+		b.Error = err.Error()
+		return
+	}
+
+	directory := b.Directory
+	b.Close()
+
+	if len(ops) > 0 {
+		RunRenames(app, directory, ops)
+	}
+}
+
+func (b *BatchRenameView) Render(renderer *sdl.Renderer, parentRect *sdl.Rect, app *App) {
+	theme := app.Theme.PanelTheme
+
+	width := b.MaxWidth
+	if width > parentRect.W-20 {
+		width = parentRect.W - 20
+	}
+
+	rect := sdl.Rect{
+		X: parentRect.X + (parentRect.W-width)/2,
+		Y: parentRect.Y + 40,
+		W: width,
+		H: parentRect.H - 80,
+	}
+
+	title := "Rename " + strconv.Itoa(len(b.Names)) + " items (Tab to switch fields, Enter to apply, Esc to cancel)"
+	insetRect := DrawPanel(renderer, &rect, title, b.HeaderHeight, b.Padding, &app.Font, theme)
+
+	y := insetRect.Y
+	labels := []string{"Find", "Replace"}
+	for index, field := range b.Fields {
+		color := GetColor(theme, "secondary_text_color")
+		if index == b.ActiveField {
+			color = GetColor(theme, "header_color")
+		}
+
+		labelRect := sdl.Rect{X: insetRect.X + b.Padding, Y: y, W: b.LabelWidth, H: b.FieldHeight}
+		DrawTextInRect(renderer, &app.Font, labels[index], &labelRect, color)
+
+		field.Rect.W = insetRect.W - b.LabelWidth - b.Padding
+		field.Render(renderer, insetRect.X+b.LabelWidth, y, &app.Font, app.Theme.InputFieldTheme)
+
+		y += b.FieldHeight
+	}
+
+	help := "{name} {ext} {n:03} {1} {parent} {date:YYYYMMDD} {exif}  :upper :lower :title"
+	helpColor := GetColor(theme, "secondary_text_color")
+	if b.Error != "" {
+		help = b.Error
+		helpColor = GetColor(theme, "error_color")
+	}
+
+	helpRect := sdl.Rect{X: insetRect.X + b.Padding, Y: y, W: insetRect.W - b.Padding*2, H: b.LineHeight}
+	DrawTextInRect(renderer, &app.Font, help, &helpRect, helpColor)
+	y += b.LineHeight
+
+	previewRect := sdl.Rect{X: insetRect.X, Y: y, W: insetRect.W, H: insetRect.Y + insetRect.H - y}
+	b.Preview.Render(renderer, &previewRect, &app.Font, theme, "Nothing selected")
+}
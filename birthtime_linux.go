@@ -0,0 +1,19 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// The birth time isn't part of stat on Linux, it has to be requested with statx. Not every file system stores it.
+func GetBirthTime(fullPath string, stats os.FileInfo) (time.Time, bool) {
+	var statx unix.Statx_t
+	err := unix.Statx(unix.AT_FDCWD, fullPath, 0, unix.STATX_BTIME, &statx)
+	if err != nil || statx.Mask&unix.STATX_BTIME == 0 || statx.Btime.Sec == 0 {
+		return time.Time{}, false
+	}
+
+	return time.Unix(statx.Btime.Sec, int64(statx.Btime.Nsec)), true
+}
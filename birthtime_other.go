@@ -0,0 +1,13 @@
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package main
+
+import (
+	"os"
+	"time"
+)
+
+func GetBirthTime(fullPath string, stats os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
@@ -41,7 +41,13 @@ func (b *Breadcrumbs) Pop() string {
 }
 
 func (b *Breadcrumbs) Set(fullPath string) {
-	b.Path = strings.Split(fullPath, "/")
+	b.Path = splitPath(fullPath)
+}
+
+// The first crumb is the location of the file system, e.g. sftp://user@host:22, instead of the root
+func (b *Breadcrumbs) SetInFileSystem(location string, fullPath string) {
+	b.Path = splitPath(fullPath)
+	b.Path[0] = location
 }
 
 func (b *Breadcrumbs) ShowAvailableDrives(show bool) {
@@ -56,7 +62,7 @@ func (b *Breadcrumbs) Render(renderer *sdl.Renderer, font *Font, theme Subtheme)
 	DrawRect3D(renderer, &b.Rect, GetColor(theme, "background_color"))
 
 	if b.ShowDrives {
-		staticText := "Available drives: "
+		staticText := "Go to: "
 		drives := strings.Join(b.AvailableDrives, ", ")
 
 		staticTextWidth := font.GetStringWidth(staticText)
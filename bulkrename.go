@@ -0,0 +1,239 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Bulk rename opens the names of the items in a text buffer, one per line. Once the buffer is applied, every line
+// is the new name of the item that was originally on that line. The names are checked before anything on the disk
+// is touched and the renames are ordered so that items can swap names with each other.
+
+type BulkRenameView struct {
+	IsOpen    bool
+	Directory string
+	Names     []string
+	Editor    TextEditor
+	Error     string
+
+	MaxWidth     int32
+	Padding      int32
+	HeaderHeight int32
+	FooterHeight int32
+}
+
+func NewBulkRenameView() *BulkRenameView {
+	return &BulkRenameView{
+		Editor:       *NewTextEditor(),
+		MaxWidth:     700,
+		Padding:      8,
+		HeaderHeight: 28,
+		FooterHeight: 24,
+	}
+}
+
+func (b *BulkRenameView) Open(directory string, names []string) {
+	b.IsOpen = true
+	b.Directory = directory
+	b.Names = names
+	b.Error = ""
+	b.Editor.SetText(strings.Join(names, "\n"))
+}
+
+func (b *BulkRenameView) Close() {
+	b.IsOpen = false
+	b.Names = nil
+}
+
+func (b *BulkRenameView) Tick(input *Input, app *App) {
+	if input.Escape {
+		b.Close()
+		return
+	}
+
+	if input.Ctrl && input.TypedCharacter == 's' {
+		b.apply(app)
+		return
+	}
+
+	b.Editor.Tick(input)
+}
+
+func (b *BulkRenameView) apply(app *App) {
+	newNames := b.Editor.Lines
+	// A trailing new line is easy to type by accident
+	for len(newNames) > len(b.Names) && newNames[len(newNames)-1] == "" {
+		newNames = newNames[:len(newNames)-1]
+	}
+
+	ops, err := PlanRenames(b.Directory, b.Names, newNames)
+	if err != nil {
+		b.Error = err.Error()
+		return
+	}
+
+	b.Close()
+
+	if len(ops) == 0 {
+		return
+	}
+
+	RunRenames(app, b.Directory, ops)
+}
+
+// Applies the renames planned by PlanRenames as a job
+func RunRenames(app *App, directory string, ops []JournalOp) {
+	var done []JournalOp
+	app.Jobs.Add("Renaming items in "+directory, func(job *Job) error {
+		for _, op := range ops {
+			err := job.Checkpoint()
+			if err != nil {
+				return err
+			}
+
+			err = renameNoReplace(op.From, op.To)
+			if err != nil {
+				return err
+			}
+
+			done = append(done, op)
+		}
+
+		return nil
+	}, func(err error) {
+		// Whatever got renamed before a failure is still recorded, so it can be undone
+		app.Journal.Record("Rename items in "+directory, done...)
+		app.RefreshViewsShowing(directory)
+	})
+}
+
+// Returns the renames that turn every old name into the new name on the same index. Names that swap places or form
+// longer cycles go through a temporary name first.
+func PlanRenames(directory string, oldNames []string, newNames []string) (result []JournalOp, err error) {
+	if len(oldNames) != len(newNames) {
+		return nil, fmt.Errorf("expected %d names, got %d", len(oldNames), len(newNames))
+	}
+
+	isOld := make(map[string]bool, len(oldNames))
+	for _, name := range oldNames {
+		isOld[name] = true
+	}
+
+	seen := make(map[string]bool, len(newNames))
+	pending := make(map[string]string) // new name -> current name of the item that wants it
+	var order []string
+
+	for index, name := range newNames {
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\") {
+			return nil, errors.New("line " + strconv.Itoa(index+1) + ": \"" + name + "\" is not a valid name")
+		}
+
+		if seen[name] {
+			return nil, errors.New(name + " appears more than once")
+		}
+		seen[name] = true
+
+		oldName := oldNames[index]
+		if name == oldName {
+			continue
+		}
+
+		if !isOld[name] && DoesFileExist(path.Join(directory, name)) && !isSameItem(path.Join(directory, oldName), path.Join(directory, name)) {
+			return nil, errors.New(name + " already exists")
+		}
+
+		pending[name] = oldName
+		order = append(order, name)
+	}
+
+	// The names that are still taken by items which haven't been renamed yet
+	taken := make(map[string]bool, len(pending))
+	for _, name := range order {
+		taken[pending[name]] = true
+	}
+
+	for len(order) > 0 {
+		progress := false
+
+		var remaining []string
+		for _, name := range order {
+			if taken[name] {
+				remaining = append(remaining, name)
+				continue
+			}
+
+			from := pending[name]
+			result = append(result, JournalOp{Type: JournalOpRename, From: path.Join(directory, from), To: path.Join(directory, name)})
+			delete(taken, from)
+			progress = true
+		}
+
+		order = remaining
+		if progress || len(order) == 0 {
+			continue
+		}
+
+		// Everything left is part of a cycle, so one of the items gets out of the way
+		name := order[0]
+		from := pending[name]
+		temp := getTemporaryName(directory, from, seen)
+		result = append(result, JournalOp{Type: JournalOpRename, From: path.Join(directory, from), To: path.Join(directory, temp)})
+		delete(taken, from)
+		pending[name] = temp
+	}
+
+	return
+}
+
+func getTemporaryName(directory string, name string, reserved map[string]bool) string {
+	for count := 0; ; count++ {
+		result := "." + name + ".bonfire-rename"
+		if count > 0 {
+			result += strconv.Itoa(count)
+		}
+
+		if !reserved[result] && !DoesFileExist(path.Join(directory, result)) {
+			reserved[result] = true
+			return result
+		}
+	}
+}
+
+func (b *BulkRenameView) Render(renderer *sdl.Renderer, parentRect *sdl.Rect, app *App) {
+	theme := app.Theme.PanelTheme
+
+	width := b.MaxWidth
+	if width > parentRect.W-20 {
+		width = parentRect.W - 20
+	}
+
+	rect := sdl.Rect{
+		X: parentRect.X + (parentRect.W-width)/2,
+		Y: parentRect.Y + 40,
+		W: width,
+		H: parentRect.H - 80,
+	}
+
+	title := "Rename " + strconv.Itoa(len(b.Names)) + " items (Ctrl+s to apply, Esc to cancel)"
+	insetRect := DrawPanel(renderer, &rect, title, b.HeaderHeight, b.Padding, &app.Font, theme)
+
+	editorRect := insetRect
+	if b.Error != "" {
+		editorRect.H -= b.FooterHeight
+
+		errorRect := sdl.Rect{
+			X: insetRect.X + b.Editor.Padding,
+			Y: editorRect.Y + editorRect.H,
+			W: insetRect.W - b.Editor.Padding*2,
+			H: b.FooterHeight,
+		}
+		DrawTextInRect(renderer, &app.Font, b.Error, &errorRect, GetColor(theme, "error_color"))
+	}
+
+	b.Editor.Render(renderer, &editorRect, &app.Font, app.Theme.InputFieldTheme)
+}
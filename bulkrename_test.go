@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestPlanRenames(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []string // Every file holds its own name
+		oldNames []string
+		newNames []string
+		fails    bool
+		ops      int
+		expected map[string]string // New name -> the name of the file that should have it
+	}{
+		{
+			name:     "swap",
+			files:    []string{"a", "b"},
+			oldNames: []string{"a", "b"},
+			newNames: []string{"b", "a"},
+			ops:      3,
+			expected: map[string]string{"a": "b", "b": "a"},
+		},
+		{
+			name:     "rotation",
+			files:    []string{"a", "b", "c"},
+			oldNames: []string{"a", "b", "c"},
+			newNames: []string{"b", "c", "a"},
+			ops:      4,
+			expected: map[string]string{"b": "a", "c": "b", "a": "c"},
+		},
+		{
+			name:     "chain",
+			files:    []string{"a", "b"},
+			oldNames: []string{"a", "b"},
+			newNames: []string{"b", "c"},
+			ops:      2,
+			expected: map[string]string{"b": "a", "c": "b"},
+		},
+		{
+			name:     "clash with an item that isn't renamed",
+			files:    []string{"a", "b"},
+			oldNames: []string{"a"},
+			newNames: []string{"b"},
+			fails:    true,
+		},
+		{
+			name:     "case only",
+			files:    []string{"photo.jpg"},
+			oldNames: []string{"photo.jpg"},
+			newNames: []string{"Photo.JPG"},
+			ops:      1,
+			expected: map[string]string{"Photo.JPG": "photo.jpg"},
+		},
+		{
+			name:     "unchanged",
+			files:    []string{"a"},
+			oldNames: []string{"a"},
+			newNames: []string{"a"},
+			expected: map[string]string{"a": "a"},
+		},
+		{
+			name:     "duplicate names",
+			files:    []string{"a", "b"},
+			oldNames: []string{"a", "b"},
+			newNames: []string{"c", "c"},
+			fails:    true,
+		},
+		{
+			name:     "invalid name",
+			files:    []string{"a"},
+			oldNames: []string{"a"},
+			newNames: []string{"x/y"},
+			fails:    true,
+		},
+		{
+			name:     "missing line",
+			files:    []string{"a", "b"},
+			oldNames: []string{"a", "b"},
+			newNames: []string{"c"},
+			fails:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			directory := t.TempDir()
+			for _, name := range test.files {
+				err := os.WriteFile(path.Join(directory, name), []byte(name), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ops, err := PlanRenames(directory, test.oldNames, test.newNames)
+			if test.fails {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", ops)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(ops) != test.ops {
+				t.Fatalf("got %d renames, expected %d: %v", len(ops), test.ops, ops)
+			}
+
+			for _, op := range ops {
+				err = renameNoReplace(op.From, op.To)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for name, original := range test.expected {
+				data, err := os.ReadFile(path.Join(directory, name))
+				if err != nil || string(data) != original {
+					t.Errorf("%s holds %q, expected %q (%v)", name, data, original, err)
+				}
+			}
+		})
+	}
+}
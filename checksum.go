@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+	"lukechampine.com/blake3"
+)
+
+// Checksums are computed in a single pass over the file, all of the algorithms are fed the same chunks. Checksum files,
+// such as SHA256SUMS or files ending with .sha256, list a digest and a path relative to the checksum file on every
+// line, either in the format written by sha256sum ("<digest>  <path>") or in the BSD format
+// ("SHA256 (<path>) = <digest>").
+
+type ChecksumAlgorithm struct {
+	Name    string
+	BSDName string // The name used by the BSD format
+	New     func() hash.Hash
+}
+
+var checksumAlgorithms = []ChecksumAlgorithm{
+	{Name: "MD5", BSDName: "MD5", New: md5.New},
+	{Name: "SHA-1", BSDName: "SHA1", New: sha1.New},
+	{Name: "SHA-256", BSDName: "SHA256", New: sha256.New},
+	// The digests b2sum and b3sum write by default
+	{Name: "BLAKE2b", BSDName: "BLAKE2b", New: func() hash.Hash {
+		// Only fails for keys that are too long
+		result, _ := blake2b.New512(nil)
+		return result
+	}},
+	{Name: "BLAKE3", BSDName: "BLAKE3", New: func() hash.Hash {
+		return blake3.New(32, nil)
+	}},
+}
+
+type FileChecksums struct {
+	Name    string
+	Digests []string // In the order of checksumAlgorithms, nil while they are computed
+	Err     string
+}
+
+// Computes the digests of the file with every algorithm. fsys is nil for files on the local disk.
+func ComputeChecksums(fsys FileSystem, fullPath string, job *Job) ([]string, error) {
+	if fsys == nil {
+		fsys = diskFileSystem{}
+	}
+
+	file, err := fsys.Open(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hashes := make([]hash.Hash, len(checksumAlgorithms))
+	writers := make([]io.Writer, len(checksumAlgorithms))
+	for index, algorithm := range checksumAlgorithms {
+		hashes[index] = algorithm.New()
+		writers[index] = hashes[index]
+	}
+
+	_, err = copyStream(io.MultiWriter(writers...), file, job)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, len(hashes))
+	for index, h := range hashes {
+		result[index] = hex.EncodeToString(h.Sum(nil))
+	}
+
+	return result, nil
+}
+
+// Formats the digests the way sha256sum does, so that the result can be saved as a checksum file
+func FormatChecksums(checksums []FileChecksums, algorithm int) string {
+	if len(checksums) == 1 {
+		return checksums[0].Digests[algorithm]
+	}
+
+	var sb strings.Builder
+	for _, file := range checksums {
+		if file.Digests != nil {
+			sb.WriteString(file.Digests[algorithm] + "  " + file.Name + "\n")
+		}
+	}
+
+	return sb.String()
+}
+
+// Returns true for the names checksum files are usually saved under
+func IsChecksumFile(name string) bool {
+	lower := strings.ToLower(name)
+	for _, suffix := range []string{"sums", ".md5", ".sha1", ".sha256", ".b2", ".b3"} {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type ChecksumEntry struct {
+	Path      string // Relative to the folder of the checksum file
+	Digest    string
+	Algorithm int
+}
+
+func ParseChecksumFile(fullPath string) ([]ChecksumEntry, error) {
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// sha256sum and b3sum digests have the same length, so the name of the file tells them apart
+	name := strings.ToLower(path.Base(fullPath))
+	isBlake3 := name == "b3sums" || strings.HasSuffix(name, ".b3")
+
+	var result []ChecksumEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		entry, ok := parseChecksumLine(line, isBlake3)
+		if !ok {
+			return nil, errors.New(path.Base(fullPath) + " is not a checksum file, can't read the line: " + line)
+		}
+
+		result = append(result, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// The GNU tools escape backslashes and line breaks in the paths, and start the line with a backslash if they did
+var checksumPathEscapes = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\r`, "\r")
+
+func parseChecksumLine(line string, isBlake3 bool) (result ChecksumEntry, ok bool) {
+	escaped := strings.HasPrefix(line, `\`)
+	if escaped {
+		line = line[1:]
+	}
+
+	result, ok = parseChecksumFields(line, isBlake3)
+	if escaped {
+		result.Path = checksumPathEscapes.Replace(result.Path)
+	}
+
+	return
+}
+
+func parseChecksumFields(line string, isBlake3 bool) (result ChecksumEntry, ok bool) {
+	// BSD format
+	for index, algorithm := range checksumAlgorithms {
+		prefix := algorithm.BSDName + " ("
+		separator := strings.LastIndex(line, ") = ")
+		if strings.HasPrefix(line, prefix) && separator > len(prefix) {
+			result = ChecksumEntry{Path: line[len(prefix):separator], Digest: line[separator+4:], Algorithm: index}
+			return result, isHexDigest(result.Digest)
+		}
+	}
+
+	digest, name, found := strings.Cut(line, " ")
+	if !found || !isHexDigest(digest) {
+		return result, false
+	}
+
+	// A star marks files that were read in binary mode, which makes no difference
+	name = strings.TrimPrefix(strings.TrimPrefix(name, " "), "*")
+
+	result = ChecksumEntry{Path: name, Digest: strings.ToLower(digest)}
+	switch {
+	case len(digest) == 32:
+		result.Algorithm = 0
+	case len(digest) == 40:
+		result.Algorithm = 1
+	case len(digest) == 64 && !isBlake3:
+		result.Algorithm = 2
+	case len(digest) == 128:
+		result.Algorithm = 3
+	case len(digest) == 64:
+		result.Algorithm = 4
+	default:
+		return result, false
+	}
+
+	return result, true
+}
+
+func isHexDigest(value string) bool {
+	_, err := hex.DecodeString(value)
+	return err == nil && value != ""
+}
+
+type VerifyState int32
+
+const (
+	VerifyPassed VerifyState = iota
+	VerifyFailed
+	VerifyMissing
+)
+
+// Checks the files listed in the checksum file. Returns the state of every listed path.
+func VerifyChecksums(directory string, entries []ChecksumEntry, job *Job) (map[string]VerifyState, error) {
+	var bytes int64
+	for _, entry := range entries {
+		stats, err := os.Stat(path.Join(directory, entry.Path))
+		if err == nil {
+			bytes += stats.Size()
+		}
+	}
+	job.SetTotal(int64(len(entries)), bytes)
+
+	result := make(map[string]VerifyState, len(entries))
+	for _, entry := range entries {
+		fullPath := path.Join(directory, entry.Path)
+
+		file, err := os.Open(fullPath)
+		if err != nil {
+			result[entry.Path] = VerifyMissing
+			continue
+		}
+
+		h := checksumAlgorithms[entry.Algorithm].New()
+		_, err = copyStream(h, file, job)
+		file.Close()
+
+		if errors.Is(err, ErrJobCancelled) {
+			return nil, err
+		}
+
+		if err != nil || hex.EncodeToString(h.Sum(nil)) != strings.ToLower(entry.Digest) {
+			result[entry.Path] = VerifyFailed
+		} else {
+			result[entry.Path] = VerifyPassed
+		}
+
+		err = job.AddFile()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestParseChecksumLine(t *testing.T) {
+	md5 := "d41d8cd98f00b204e9800998ecf8427e"
+	sha1 := "da39a3ee5e6b4b0d3255bfef95601890afd80709"
+	sha256 := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	blake2b := strings.Repeat("ab", 64)
+
+	tests := []struct {
+		line      string
+		isBlake3  bool
+		ok        bool
+		path      string
+		algorithm int
+	}{
+		{line: md5 + "  a.txt", ok: true, path: "a.txt", algorithm: 0},
+		{line: sha1 + " *a.txt", ok: true, path: "a.txt", algorithm: 1},
+		{line: sha256 + "  folder/a b.txt", ok: true, path: "folder/a b.txt", algorithm: 2},
+		{line: sha256 + "  a.txt", isBlake3: true, ok: true, path: "a.txt", algorithm: 4},
+		{line: blake2b + "  a.txt", ok: true, path: "a.txt", algorithm: 3},
+		{line: "SHA256 (a.txt) = " + sha256, ok: true, path: "a.txt", algorithm: 2},
+		{line: "BLAKE2b (a (1).txt) = " + blake2b, ok: true, path: "a (1).txt", algorithm: 3},
+		{line: `\` + sha256 + `  a\\b.txt`, ok: true, path: `a\b.txt`, algorithm: 2},
+		{line: `\` + sha256 + `  a\nb.txt`, ok: true, path: "a\nb.txt", algorithm: 2},
+		{line: `\SHA256 (a\\b.txt) = ` + sha256, ok: true, path: `a\b.txt`, algorithm: 2},
+		{line: "abc  a.txt"},
+		{line: strings.Repeat("a", 48) + "  a.txt"},
+		{line: "SHA256 (a.txt) = not a digest"},
+		{line: sha256},
+	}
+
+	for _, test := range tests {
+		result, ok := parseChecksumLine(test.line, test.isBlake3)
+		if ok != test.ok {
+			t.Errorf("%q: got ok %v, expected %v", test.line, ok, test.ok)
+			continue
+		}
+
+		if ok && (result.Path != test.path || result.Algorithm != test.algorithm) {
+			t.Errorf("%q: got %q with %d, expected %q with %d", test.line, result.Path, result.Algorithm, test.path, test.algorithm)
+		}
+	}
+}
+
+func TestParseChecksumFileNames(t *testing.T) {
+	sha256 := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	directory := t.TempDir()
+
+	// Only b3sum files have 64 digit BLAKE3 digests
+	tests := map[string]int{
+		"SHA256SUMS":        2,
+		"web3.sha256":       2,
+		"db3-backup.sha256": 2,
+		"B3SUMS":            4,
+		"release.b3":        4,
+	}
+
+	for name, algorithm := range tests {
+		fullPath := path.Join(directory, name)
+		err := os.WriteFile(fullPath, []byte(sha256+"  a.txt\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		entries, err := ParseChecksumFile(fullPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(entries) != 1 || entries[0].Algorithm != algorithm {
+			t.Errorf("%s: got %v, expected the algorithm %d", name, entries, algorithm)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"time"
+)
+
+// Two views can be compared to see how their folders differ, e.g. a folder and its backup. Only the items directly in
+// the folders are compared, folders with the same name count as the same. Files are compared by their size and
+// modification time, or by their contents if asked to, which takes longer but doesn't depend on the times being kept
+// when the files were copied.
+
+type CompareState int32
+
+const (
+	CompareSame CompareState = iota
+	CompareOnlyHere
+	CompareNewer
+	CompareOlder
+	CompareDifferent
+)
+
+// Some file systems, e.g. FAT, only keep the modification time with a precision of two seconds
+const compareTimeTolerance = 2 * time.Second
+
+type Comparison struct {
+	Views      [2]*ItemView
+	ByContents bool
+
+	job      *Job // The comparison that is running, nil if there's none
+	notified bool
+}
+
+func (c *Comparison) Includes(view *ItemView) bool {
+	return c != nil && (c.Views[0] == view || c.Views[1] == view)
+}
+
+// Returns the color of an item in the item view theme, if the item differs from the item with the same name in the
+// other folder
+func (s CompareState) colorKey() string {
+	switch s {
+	case CompareOnlyHere:
+		return "compare_only_color"
+	case CompareNewer:
+		return "compare_newer_color"
+	case CompareOlder:
+		return "compare_older_color"
+	case CompareDifferent:
+		return "compare_different_color"
+	}
+
+	return ""
+}
+
+// Compares the items of two folders. fsysA and fsysB are nil for the local disk. Returns the state of every item in
+// each of the folders.
+func CompareFolders(fsysA FileSystem, a string, fsysB FileSystem, b string, byContents bool, job *Job) (statesA map[string]CompareState, statesB map[string]CompareState, err error) {
+	itemsA, err := readFolderForComparison(fsysA, a)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	itemsB, err := readFolderForComparison(fsysB, b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	statesA = make(map[string]CompareState, len(itemsA))
+	statesB = make(map[string]CompareState, len(itemsB))
+
+	var toHash []string
+	var hashBytes int64
+
+	for name, statsA := range itemsA {
+		statsB, ok := itemsB[name]
+		if !ok {
+			statesA[name] = CompareOnlyHere
+			continue
+		}
+
+		state := compareStats(statsA, statsB)
+		if byContents && statsA.Mode().IsRegular() && statsB.Mode().IsRegular() && statsA.Size() == statsB.Size() {
+			toHash = append(toHash, name)
+			hashBytes += statsA.Size() * 2
+		}
+
+		statesA[name] = state
+		statesB[name] = invertCompareState(state)
+	}
+
+	for name := range itemsB {
+		if _, ok := itemsA[name]; !ok {
+			statesB[name] = CompareOnlyHere
+		}
+	}
+
+	// Only files with the same size can have the same contents. The files that can't be read keep the state based on
+	// their size and modification time.
+	job.SetTotal(0, hashBytes)
+	for _, name := range toHash {
+		hashA, err := hashInFileSystem(fsysA, path.Join(a, name), job)
+		if errors.Is(err, ErrJobCancelled) {
+			return nil, nil, err
+		}
+
+		hashB, errB := hashInFileSystem(fsysB, path.Join(b, name), job)
+		if errors.Is(errB, ErrJobCancelled) {
+			return nil, nil, errB
+		}
+
+		if err != nil || errB != nil {
+			continue
+		}
+
+		if bytes.Equal(hashA, hashB) {
+			statesA[name] = CompareSame
+			statesB[name] = CompareSame
+		} else if statesA[name] == CompareSame {
+			statesA[name] = CompareDifferent
+			statesB[name] = CompareDifferent
+		}
+	}
+
+	return statesA, statesB, nil
+}
+
+func readFolderForComparison(fsys FileSystem, fullPath string) (map[string]fs.FileInfo, error) {
+	result := make(map[string]fs.FileInfo)
+
+	if fsys != nil {
+		items, err := fsys.ReadDir(fullPath)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range items {
+			result[item.Name()] = item
+		}
+
+		return result, nil
+	}
+
+	entries, err := os.ReadDir(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		// Links are compared by what they point to, broken links by themselves
+		stats, err := os.Stat(path.Join(fullPath, entry.Name()))
+		if err != nil {
+			stats, err = entry.Info()
+			if err != nil {
+				continue
+			}
+		}
+
+		result[entry.Name()] = stats
+	}
+
+	return result, nil
+}
+
+// Returns the state of a as seen from its folder
+func compareStats(a fs.FileInfo, b fs.FileInfo) CompareState {
+	if a.IsDir() || b.IsDir() {
+		if a.IsDir() && b.IsDir() {
+			return CompareSame
+		}
+
+		return CompareDifferent
+	}
+
+	difference := a.ModTime().Sub(b.ModTime())
+	if difference > compareTimeTolerance {
+		return CompareNewer
+	}
+
+	if difference < -compareTimeTolerance {
+		return CompareOlder
+	}
+
+	if a.Size() != b.Size() {
+		return CompareDifferent
+	}
+
+	return CompareSame
+}
+
+func invertCompareState(state CompareState) CompareState {
+	switch state {
+	case CompareNewer:
+		return CompareOlder
+	case CompareOlder:
+		return CompareNewer
+	}
+
+	return state
+}
+
+func hashInFileSystem(fsys FileSystem, fullPath string, job *Job) ([]byte, error) {
+	if fsys == nil {
+		fsys = diskFileSystem{}
+	}
+
+	file, err := fsys.Open(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = copyStream(hash, file, job)
+	if err != nil {
+		return nil, err
+	}
+
+	return hash.Sum(nil), nil
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Packs items into new archives. Extracting is done by opening the archive as a file system (see archive.go) and
+// copying out of it with CopyFromFileSystem.
+
+// Writes the items in directory into a new archive. The format is picked from the extension of archivePath. If
+// anything fails, the unfinished archive is removed.
+func CreateArchive(archivePath string, directory string, names []string, progress ProgressReporter) (err error) {
+	format, ok := GetArchiveFormat(archivePath)
+	if !ok {
+		return errors.New(path.Base(archivePath) + " doesn't end with .zip, .tar, .tar.gz or .tar.zst")
+	}
+
+	file, err := os.OpenFile(archivePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		closeErr := file.Close()
+		if err == nil {
+			err = closeErr
+		}
+
+		if err != nil {
+			os.Remove(archivePath)
+		}
+	}()
+
+	var writer archiveWriter
+	switch format {
+	case ArchiveZip:
+		writer = &zipArchiveWriter{Writer: zip.NewWriter(file)}
+	case ArchiveTar:
+		writer = newTarArchiveWriter(file, nil)
+	case ArchiveTarGz:
+		compressor := gzip.NewWriter(file)
+		writer = newTarArchiveWriter(compressor, compressor)
+	case ArchiveTarZst:
+		encoder, err := zstd.NewWriter(file)
+		if err != nil {
+			return err
+		}
+
+		writer = newTarArchiveWriter(encoder, encoder)
+	}
+
+	for _, name := range names {
+		err = addToArchive(writer, path.Join(directory, name), name, progress)
+		if err != nil {
+			writer.Close()
+			return err
+		}
+	}
+
+	return writer.Close()
+}
+
+func addToArchive(writer archiveWriter, fullPath string, name string, progress ProgressReporter) error {
+	if progress != nil {
+		err := progress.AddBytes(0)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Links are stored as links, the items they point to are not packed
+	stats, err := os.Lstat(fullPath)
+	if err != nil {
+		return err
+	}
+
+	if stats.Mode()&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(fullPath)
+		if err != nil {
+			return err
+		}
+
+		return writer.AddLink(name, stats, filepath.ToSlash(target))
+	}
+
+	if !stats.IsDir() {
+		if !stats.Mode().IsRegular() {
+			return nil
+		}
+
+		err = writer.AddFile(name, stats, fullPath, progress)
+		if err != nil {
+			return err
+		}
+
+		if progress != nil {
+			return progress.AddFile()
+		}
+
+		return nil
+	}
+
+	err = writer.AddFolder(name, stats)
+	if err != nil {
+		return err
+	}
+
+	items, err := os.ReadDir(fullPath)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		err = addToArchive(writer, path.Join(fullPath, item.Name()), path.Join(name, item.Name()), progress)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Names passed to an archiveWriter are relative to the root of the archive and use forward slashes
+type archiveWriter interface {
+	AddFolder(name string, stats fs.FileInfo) error
+	AddFile(name string, stats fs.FileInfo, fullPath string, progress ProgressReporter) error
+	AddLink(name string, stats fs.FileInfo, target string) error
+	Close() error
+}
+
+type zipArchiveWriter struct {
+	Writer *zip.Writer
+}
+
+func (z *zipArchiveWriter) AddFolder(name string, stats fs.FileInfo) error {
+	header, err := zip.FileInfoHeader(stats)
+	if err != nil {
+		return err
+	}
+	header.Name = name + "/"
+
+	_, err = z.Writer.CreateHeader(header)
+	return err
+}
+
+func (z *zipArchiveWriter) AddFile(name string, stats fs.FileInfo, fullPath string, progress ProgressReporter) error {
+	header, err := zip.FileInfoHeader(stats)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+
+	out, err := z.Writer.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	in, err := os.Open(fullPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	_, err = copyStream(out, in, progress)
+	return err
+}
+
+func (z *zipArchiveWriter) AddLink(name string, stats fs.FileInfo, target string) error {
+	header, err := zip.FileInfoHeader(stats)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+
+	out, err := z.Writer.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(out, target)
+	return err
+}
+
+func (z *zipArchiveWriter) Close() error {
+	return z.Writer.Close()
+}
+
+type tarArchiveWriter struct {
+	Writer     *tar.Writer
+	compressor io.Closer // nil for uncompressed archives
+}
+
+func newTarArchiveWriter(out io.Writer, compressor io.Closer) *tarArchiveWriter {
+	return &tarArchiveWriter{Writer: tar.NewWriter(out), compressor: compressor}
+}
+
+func (t *tarArchiveWriter) writeHeader(name string, stats fs.FileInfo, target string) error {
+	header, err := tar.FileInfoHeader(stats, target)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+
+	return t.Writer.WriteHeader(header)
+}
+
+func (t *tarArchiveWriter) AddFolder(name string, stats fs.FileInfo) error {
+	return t.writeHeader(name+"/", stats, "")
+}
+
+func (t *tarArchiveWriter) AddFile(name string, stats fs.FileInfo, fullPath string, progress ProgressReporter) error {
+	err := t.writeHeader(name, stats, "")
+	if err != nil {
+		return err
+	}
+
+	in, err := os.Open(fullPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// The header promised exactly this many bytes, so a file that grew in the meantime is cut
+	_, err = copyStream(t.Writer, io.LimitReader(in, stats.Size()), progress)
+	return err
+}
+
+func (t *tarArchiveWriter) AddLink(name string, stats fs.FileInfo, target string) error {
+	return t.writeHeader(name, stats, target)
+}
+
+func (t *tarArchiveWriter) Close() error {
+	err := t.Writer.Close()
+
+	if t.compressor != nil {
+		closeErr := t.compressor.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
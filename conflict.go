@@ -0,0 +1,159 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path"
+)
+
+// When an operation wants to put an item where another item with the same name already exists, the user is asked
+// what to do. Operations run as jobs, so the question is asked from a worker goroutine, which blocks until the
+// ConflictPrompt on the main thread gets an answer. A ConflictResolver is created for every operation and remembers
+// the answer if the user chose to apply it to all the remaining conflicts of that operation.
+
+type ConflictResolution int32
+
+const (
+	ConflictOverwrite ConflictResolution = iota
+	ConflictSkip
+	ConflictKeepBoth
+	ConflictOverwriteIfNewer
+	ConflictCancel
+)
+
+type Conflict struct {
+	Source      string
+	Destination string
+
+	SourceInfo      fs.FileInfo // nil if the source couldn't be read
+	DestinationInfo fs.FileInfo
+
+	// The destination is a folder the source is in, e.g. when extracting foo/foo out of foo. Trashing it would take
+	// the source along, so it can only be skipped or kept next to it.
+	ContainsSource bool
+}
+
+type ConflictAnswer struct {
+	Resolution ConflictResolution
+	ApplyToAll bool
+}
+
+type ConflictResolver struct {
+	Ask func(conflict Conflict) ConflictAnswer
+
+	applyToAll bool
+	answer     ConflictAnswer
+}
+
+func NewConflictResolver(ask func(conflict Conflict) ConflictAnswer) *ConflictResolver {
+	return &ConflictResolver{Ask: ask}
+}
+
+// Returns the path the source should be moved or copied to. The path is empty if the item should be skipped.
+// If the user chose to overwrite the destination, the existing item is moved to the trash first, so it can still be
+// brought back, and the returned journal operations record that.
+func (r *ConflictResolver) Resolve(source string, destination string) (string, []JournalOp, error) {
+	destStats, err := os.Lstat(destination)
+	if err != nil {
+		return destination, nil, nil
+	}
+
+	// Renaming a file to a different case of the same name on a case insensitive file system
+	sourceStats, err := os.Lstat(source)
+	if err == nil && os.SameFile(sourceStats, destStats) {
+		return destination, nil, nil
+	}
+
+	return r.resolve(source, sourceStats, nil, destination, destStats, IsSubPath(destination, source))
+}
+
+// Same as Resolve, but the source is an item inside of a file system, e.g. an archive
+func (r *ConflictResolver) ResolveFromFileSystem(fsys FileSystem, source string, destination string) (string, []JournalOp, error) {
+	destStats, err := os.Lstat(destination)
+	if err != nil {
+		return destination, nil, nil
+	}
+
+	sourceStats, _ := fsys.Stat(source)
+	return r.resolve(GetFileSystemPath(fsys, source), sourceStats, nil, destination, destStats, false)
+}
+
+// Same as Resolve, but the destination is inside of a writable file system, such as a remote folder, and the source
+// is either inside of a file system or on the local disk if fsys is nil. File systems don't have a trash, so the
+// existing item is removed permanently if the user chose to overwrite it.
+func (r *ConflictResolver) ResolveInFileSystem(fsys FileSystem, source string, target WritableFileSystem, destination string) (string, []JournalOp, error) {
+	destStats, err := target.Stat(destination)
+	if err != nil {
+		return destination, nil, nil
+	}
+
+	sourceLabel := source
+	var sourceStats fs.FileInfo
+	if fsys != nil {
+		sourceLabel = GetFileSystemPath(fsys, source)
+		sourceStats, _ = fsys.Stat(source)
+	} else {
+		sourceStats, _ = os.Stat(source)
+	}
+
+	return r.resolve(sourceLabel, sourceStats, target, destination, destStats, false)
+}
+
+func (r *ConflictResolver) resolve(source string, sourceStats fs.FileInfo, target WritableFileSystem, destination string, destStats fs.FileInfo, containsSource bool) (string, []JournalOp, error) {
+	destinationLabel := destination
+	if target != nil {
+		destinationLabel = GetFileSystemPath(target, destination)
+	}
+
+	// An answer to overwrite everything doesn't apply to a destination that can't be overwritten, so that is asked
+	answer := r.answer
+	if !r.applyToAll || (containsSource && isOverwrite(answer.Resolution)) {
+		answer = r.Ask(Conflict{Source: source, Destination: destinationLabel, SourceInfo: sourceStats, DestinationInfo: destStats, ContainsSource: containsSource})
+		if answer.ApplyToAll {
+			r.applyToAll = true
+			r.answer = answer
+		}
+	}
+
+	if containsSource && isOverwrite(answer.Resolution) {
+		return "", nil, nil
+	}
+
+	switch answer.Resolution {
+	case ConflictCancel:
+		return "", nil, ErrJobCancelled
+	case ConflictSkip:
+		return "", nil, nil
+	case ConflictKeepBoth:
+		dir := getParentPath(destination)
+		if target != nil {
+			return path.Join(dir, GetAvailableNameInFileSystem(target, dir, path.Base(destination))), nil, nil
+		}
+
+		return path.Join(dir, GetAvailableFileName(dir, path.Base(destination))), nil, nil
+	case ConflictOverwriteIfNewer:
+		if sourceStats == nil || !sourceStats.ModTime().After(destStats.ModTime()) {
+			return "", nil, nil
+		}
+	}
+
+	if target != nil {
+		err := target.RemoveAll(destination)
+		if err != nil {
+			return "", nil, err
+		}
+
+		return destination, nil, nil
+	}
+
+	trashedPath, err := MoveToTrash(destination, nil)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return destination, []JournalOp{{Type: JournalOpTrash, From: destination, To: trashedPath}}, nil
+}
+
+func isOverwrite(resolution ConflictResolution) bool {
+	return resolution == ConflictOverwrite || resolution == ConflictOverwriteIfNewer
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+type conflictRequest struct {
+	Conflict Conflict
+	Reply    chan ConflictAnswer
+}
+
+type ConflictPrompt struct {
+	IsOpen  bool
+	Current conflictRequest
+
+	Requests chan conflictRequest
+
+	MaxWidth     int32
+	Padding      int32
+	ItemPadding  int32
+	HeaderHeight int32
+	ItemHeight   int32
+}
+
+func NewConflictPrompt() *ConflictPrompt {
+	return &ConflictPrompt{
+		Requests:     make(chan conflictRequest),
+		MaxWidth:     500,
+		Padding:      8,
+		ItemPadding:  5,
+		HeaderHeight: 28,
+		ItemHeight:   24,
+	}
+}
+
+// Asks the user what to do with the conflict and waits for the answer. Must not be called from the main thread.
+func (c *ConflictPrompt) Ask(conflict Conflict) ConflictAnswer {
+	reply := make(chan ConflictAnswer)
+	c.Requests <- conflictRequest{Conflict: conflict, Reply: reply}
+
+	return <-reply
+}
+
+// Opens the prompt if there's a conflict waiting for an answer
+func (c *ConflictPrompt) Poll() {
+	if c.IsOpen {
+		return
+	}
+
+	select {
+	case request := <-c.Requests:
+		c.Current = request
+		c.IsOpen = true
+	default:
+	}
+}
+
+func (c *ConflictPrompt) answer(resolution ConflictResolution, applyToAll bool) {
+	c.IsOpen = false
+	c.Current.Reply <- ConflictAnswer{Resolution: resolution, ApplyToAll: applyToAll}
+}
+
+func (c *ConflictPrompt) Tick(input *Input) {
+	if input.Escape {
+		c.answer(ConflictCancel, true)
+		return
+	}
+
+	// The options to overwrite aren't offered if the destination holds the source
+	if c.Current.Conflict.ContainsSource && strings.IndexByte("oOnN", input.TypedCharacter) >= 0 {
+		return
+	}
+
+	switch input.TypedCharacter {
+	case 'o':
+		c.answer(ConflictOverwrite, false)
+	case 'O':
+		c.answer(ConflictOverwrite, true)
+	case 's':
+		c.answer(ConflictSkip, false)
+	case 'S':
+		c.answer(ConflictSkip, true)
+	case 'k':
+		c.answer(ConflictKeepBoth, false)
+	case 'K':
+		c.answer(ConflictKeepBoth, true)
+	case 'n':
+		c.answer(ConflictOverwriteIfNewer, false)
+	case 'N':
+		c.answer(ConflictOverwriteIfNewer, true)
+	}
+}
+
+func (c *ConflictPrompt) Render(renderer *sdl.Renderer, parentRect *sdl.Rect, app *App) {
+	theme := app.Theme.PanelTheme
+	conflict := c.Current.Conflict
+
+	lines := []ListItem{
+		{Text: "Existing: " + describeConflictItem(conflict.Destination, conflict.DestinationInfo), ColorKey: "secondary_text_color"},
+		{Text: "New:      " + describeConflictItem(conflict.Source, conflict.SourceInfo), ColorKey: "secondary_text_color"},
+		{},
+		{Text: "o  overwrite"},
+		{Text: "s  skip"},
+		{Text: "k  keep both"},
+		{Text: "n  overwrite if newer"},
+		{},
+		{Text: "Shift + key applies to all conflicts, Esc cancels", ColorKey: "secondary_text_color"},
+	}
+
+	if conflict.ContainsSource {
+		lines = []ListItem{
+			lines[0],
+			lines[1],
+			{Text: "The existing folder holds the new item, so it can't be overwritten", ColorKey: "error_color"},
+			{},
+			{Text: "s  skip"},
+			{Text: "k  keep both"},
+			{},
+			lines[8],
+		}
+	}
+
+	width := c.MaxWidth
+	if width > parentRect.W-20 {
+		width = parentRect.W - 20
+	}
+
+	height := c.HeaderHeight + c.ItemHeight*int32(len(lines)) + c.Padding*2
+	rect := sdl.Rect{
+		X: parentRect.X + (parentRect.W-width)/2,
+		Y: parentRect.Y + (parentRect.H-height)/2,
+		W: width,
+		H: height,
+	}
+
+	insetRect := DrawPanel(renderer, &rect, path.Base(conflict.Destination)+" already exists", c.HeaderHeight, c.Padding, &app.Font, theme)
+
+	for index, line := range lines {
+		color := GetColor(theme, "text_color")
+		if line.ColorKey != "" {
+			color = GetColor(theme, line.ColorKey)
+		}
+
+		lineRect := sdl.Rect{
+			X: insetRect.X + c.ItemPadding,
+			Y: insetRect.Y + int32(index)*c.ItemHeight,
+			W: insetRect.W - c.ItemPadding*2,
+			H: c.ItemHeight,
+		}
+		DrawTextInRect(renderer, &app.Font, line.Text, &lineRect, color)
+	}
+}
+
+func describeConflictItem(fullPath string, stats fs.FileInfo) string {
+	if stats == nil {
+		return fullPath
+	}
+
+	size := "folder"
+	if !stats.IsDir() {
+		size = bytesToString(stats.Size())
+	}
+
+	return size + ", modified " + stats.ModTime().Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Tries to clone the file first (reflink), which is instant on file systems that support it (btrfs, xfs) and doesn't
+// take any additional space. If that fails, copy_file_range is used, which at least keeps the data inside the kernel.
+// Returns false if neither of them can be used for these files.
+func copyFileContentsFast(out *os.File, in *os.File, size int64, progress ProgressReporter) (int64, bool, error) {
+	// Some special files (e.g. in /proc) report a size of zero even though they have contents
+	if size == 0 {
+		return 0, false, nil
+	}
+
+	err := unix.IoctlFileClone(int(out.Fd()), int(in.Fd()))
+	if err == nil {
+		if progress != nil {
+			return size, true, progress.AddBytes(size)
+		}
+
+		return size, true, nil
+	}
+
+	var copied int64
+	for copied < size {
+		n, err := unix.CopyFileRange(int(in.Fd()), nil, int(out.Fd()), nil, copyChunkSize, 0)
+		if err != nil {
+			if copied == 0 && isCopyFileRangeUnsupported(err) {
+				return 0, false, nil
+			}
+
+			return copied, true, err
+		}
+
+		if n == 0 {
+			break
+		}
+
+		copied += int64(n)
+		if progress != nil {
+			err = progress.AddBytes(int64(n))
+			if err != nil {
+				return copied, true, err
+			}
+		}
+	}
+
+	return copied, true, nil
+}
+
+func isCopyFileRangeUnsupported(err error) bool {
+	switch err {
+	case unix.ENOSYS, unix.EXDEV, unix.EINVAL, unix.EOPNOTSUPP, unix.EPERM:
+		return true
+	}
+
+	return false
+}
+
+func copyOwner(destination string, stats fs.FileInfo) {
+	sys, ok := stats.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+
+	os.Lchown(destination, int(sys.Uid), int(sys.Gid))
+}
+
+func getAccessTime(stats fs.FileInfo) time.Time {
+	sys, ok := stats.Sys().(*syscall.Stat_t)
+	if !ok {
+		return stats.ModTime()
+	}
+
+	return time.Unix(int64(sys.Atim.Sec), int64(sys.Atim.Nsec))
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+	"time"
+)
+
+// There is no fast path on Windows yet, the files are always copied in chunks
+func copyFileContentsFast(out *os.File, in *os.File, size int64, progress ProgressReporter) (int64, bool, error) {
+	return 0, false, nil
+}
+
+// File ownership on Windows is controlled by ACLs, which are inherited from the destination folder
+func copyOwner(destination string, stats fs.FileInfo) {
+}
+
+func getAccessTime(stats fs.FileInfo) time.Time {
+	sys, ok := stats.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return stats.ModTime()
+	}
+
+	return time.Unix(0, sys.LastAccessTime.Nanoseconds())
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"os"
+	"path"
+	"sort"
+)
+
+// The disk usage analyzer scans a folder tree once and keeps the size of every folder in memory, so that the tree
+// can be browsed without waiting. Sizes are what the items take on the disk. Links are not followed, hard links are
+// counted once and the scan stays on the file system it started on, so that scanning "/" doesn't walk into /proc or
+// into network shares.
+
+// Identifies an item on the disk, see getFileIdentity
+type FileIdentity struct {
+	Device uint64
+	Inode  uint64
+	Links  uint64
+}
+
+type DiskUsageNode struct {
+	Name       string // The full path for the root of the scan
+	Size       int64  // Including everything inside of a folder
+	Files      int64
+	IsFolder   bool
+	Unreadable bool // Set if the folder, or any folder inside of it, couldn't be read
+
+	Parent   *DiskUsageNode
+	Children []*DiskUsageNode // Biggest first
+}
+
+func (n *DiskUsageNode) FullPath() string {
+	if n.Parent == nil {
+		return n.Name
+	}
+
+	return path.Join(n.Parent.FullPath(), n.Name)
+}
+
+func (n *DiskUsageNode) sortChildren() {
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		if n.Children[i].Size == n.Children[j].Size {
+			return n.Children[i].Name < n.Children[j].Name
+		}
+
+		return n.Children[i].Size > n.Children[j].Size
+	})
+}
+
+// Adds the difference to the node and all of the folders above it
+func (n *DiskUsageNode) addToAncestors(size int64, files int64) {
+	for node := n; node != nil; node = node.Parent {
+		node.Size += size
+		node.Files += files
+
+		if node.Parent != nil {
+			node.Parent.sortChildren()
+		}
+	}
+}
+
+// Takes the node out of the tree and subtracts its size from the folders above it
+func (n *DiskUsageNode) Remove() {
+	parent := n.Parent
+	if parent == nil {
+		return
+	}
+
+	for index, child := range parent.Children {
+		if child == n {
+			parent.Children = append(parent.Children[:index], parent.Children[index+1:]...)
+			parent.addToAncestors(-n.Size, -n.Files)
+			break
+		}
+	}
+
+	n.Parent = nil
+}
+
+// Puts a node from a new scan of the same folder in place of this one, used to rescan a part of the tree
+func (n *DiskUsageNode) Replace(scanned *DiskUsageNode) {
+	parent := n.Parent
+	if parent == nil {
+		return
+	}
+
+	for index, child := range parent.Children {
+		if child == n {
+			parent.Children[index] = scanned
+			break
+		}
+	}
+
+	scanned.Name = n.Name
+	scanned.Parent = parent
+	parent.addToAncestors(scanned.Size-n.Size, scanned.Files-n.Files)
+}
+
+type diskUsageScanner struct {
+	device    uint64
+	hasDevice bool
+	seen      map[FileIdentity]bool
+	job       *Job
+}
+
+// Scans the folder and everything inside of it. Progress is reported to the job, which can also cancel the scan.
+func ScanDiskUsage(fullPath string, job *Job) (*DiskUsageNode, error) {
+	stats, err := os.Lstat(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := &diskUsageScanner{seen: make(map[FileIdentity]bool), job: job}
+
+	identity, ok := getFileIdentity(stats)
+	if ok {
+		scanner.device = identity.Device
+		scanner.hasDevice = true
+	}
+
+	root := &DiskUsageNode{Name: fullPath, Size: getAllocatedSize(stats), IsFolder: true}
+	err = scanner.scanFolder(root, fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return root, nil
+}
+
+func (s *diskUsageScanner) scanFolder(node *DiskUsageNode, fullPath string) error {
+	entries, err := os.ReadDir(fullPath)
+	if err != nil {
+		node.Unreadable = true
+	}
+
+	for _, entry := range entries {
+		err = s.job.Checkpoint()
+		if err != nil {
+			return err
+		}
+
+		stats, err := entry.Info()
+		if err != nil {
+			// Removed while the folder was being scanned
+			continue
+		}
+
+		child := &DiskUsageNode{Name: entry.Name(), Parent: node}
+		identity, hasIdentity := getFileIdentity(stats)
+
+		if stats.IsDir() {
+			child.IsFolder = true
+			child.Size = getAllocatedSize(stats)
+
+			// Other file systems mounted inside of the folder are shown, but not scanned
+			if !s.hasDevice || !hasIdentity || identity.Device == s.device {
+				err = s.scanFolder(child, path.Join(fullPath, entry.Name()))
+				if err != nil {
+					return err
+				}
+			}
+		} else {
+			// The number of links is the same for every name of the file, so it's fine as a part of the key
+			counted := hasIdentity && identity.Links > 1 && s.seen[identity]
+			if hasIdentity && identity.Links > 1 {
+				s.seen[identity] = true
+			}
+
+			if !counted {
+				child.Size = getAllocatedSize(stats)
+			}
+			child.Files = 1
+
+			err = s.job.AddFile()
+			if err != nil {
+				return err
+			}
+
+			err = s.job.AddBytes(child.Size)
+			if err != nil {
+				return err
+			}
+		}
+
+		node.Size += child.Size
+		node.Files += child.Files
+		node.Unreadable = node.Unreadable || child.Unreadable
+		node.Children = append(node.Children, child)
+	}
+
+	node.sortChildren()
+	return nil
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+type DiskUsageView struct {
+	IsOpen  bool
+	Root    *DiskUsageNode
+	Current *DiskUsageNode
+	List    ListView
+
+	scanJob  *Job // The scan that is running, nil if there's none
+	scanPath string
+
+	MaxWidth     int32
+	Padding      int32
+	HeaderHeight int32
+	BarWidth     int32
+}
+
+func NewDiskUsageView() *DiskUsageView {
+	return &DiskUsageView{
+		List:         *NewListView(),
+		MaxWidth:     800,
+		Padding:      8,
+		HeaderHeight: 28,
+		BarWidth:     150,
+	}
+}
+
+// Shows the folder. The last scan is kept, so opening the same folder again is instant, r scans it again.
+func (d *DiskUsageView) Open(fullPath string, app *App) {
+	d.IsOpen = true
+
+	if d.Root != nil && d.Root.Name == fullPath {
+		d.updateList()
+		return
+	}
+
+	if d.scanJob != nil {
+		if d.scanPath == fullPath {
+			return
+		}
+
+		d.scanJob.Cancel()
+		d.scanJob = nil
+	}
+
+	d.Root = nil
+	d.Current = nil
+	d.List.ActiveItem = 0
+	d.updateList()
+	d.scan(nil, fullPath, app)
+}
+
+func (d *DiskUsageView) Close() {
+	d.IsOpen = false
+}
+
+// Scans the folder at fullPath. If node is set, the result replaces that node, otherwise it becomes the new root.
+func (d *DiskUsageView) scan(node *DiskUsageNode, fullPath string, app *App) {
+	if d.scanJob != nil {
+		NotifyError("The disk usage of " + d.scanPath + " is still being analyzed")
+		return
+	}
+
+	root := d.Root
+
+	var scanned *DiskUsageNode
+	var scanJob *Job
+	scanJob = app.Jobs.Add("Analyzing disk usage of "+fullPath, func(job *Job) (err error) {
+		scanned, err = ScanDiskUsage(fullPath, job)
+		return
+	}, func(err error) {
+		// Another folder was opened in the meantime
+		if d.scanJob != scanJob || d.Root != root {
+			return
+		}
+
+		d.scanJob = nil
+		if err != nil {
+			return
+		}
+
+		if node == nil {
+			d.Root = scanned
+			d.Current = scanned
+			d.List.ActiveItem = 0
+		} else if node.Parent == nil {
+			// Rescanning the root
+			if d.Current == node {
+				d.Current = scanned
+			} else {
+				d.Current = d.findNode(scanned, d.Current.FullPath())
+			}
+
+			d.Root = scanned
+		} else {
+			currentPath := d.Current.FullPath()
+			node.Replace(scanned)
+			d.Current = d.findNode(d.Root, currentPath)
+		}
+
+		d.updateList()
+	})
+
+	d.scanJob = scanJob
+	d.scanPath = fullPath
+}
+
+// Finds the node at fullPath, or the closest folder above it that is still in the tree
+func (d *DiskUsageView) findNode(root *DiskUsageNode, fullPath string) *DiskUsageNode {
+	result := root
+	for _, name := range splitRelativePath(root.Name, fullPath) {
+		found := false
+		for _, child := range result.Children {
+			if child.Name == name {
+				result = child
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			break
+		}
+	}
+
+	return result
+}
+
+func splitRelativePath(parent string, fullPath string) (result []string) {
+	for fullPath != parent && getParentPath(fullPath) != fullPath {
+		result = append([]string{path.Base(fullPath)}, result...)
+		fullPath = getParentPath(fullPath)
+	}
+
+	return
+}
+
+func (d *DiskUsageView) updateList() {
+	if d.Current == nil {
+		d.List.SetItems(nil)
+		return
+	}
+
+	items := make([]ListItem, len(d.Current.Children))
+	for index, child := range d.Current.Children {
+		name := child.Name
+		if child.IsFolder {
+			name += "/"
+		}
+
+		detail := fmt.Sprintf("%s  %5.1f%%", bytesToString(child.Size), d.getShare(child)*100)
+		if child.IsFolder {
+			detail += fmt.Sprintf("  %d files", child.Files)
+		}
+
+		colorKey := ""
+		if child.Unreadable {
+			detail += "  (some folders can't be read)"
+			colorKey = "error_color"
+		}
+
+		items[index] = ListItem{Text: name, Detail: detail, ColorKey: colorKey}
+	}
+
+	d.List.SetItems(items)
+}
+
+func (d *DiskUsageView) getShare(node *DiskUsageNode) float32 {
+	if node.Parent == nil || node.Parent.Size <= 0 {
+		return 0
+	}
+
+	return float32(node.Size) / float32(node.Parent.Size)
+}
+
+func (d *DiskUsageView) getActive() *DiskUsageNode {
+	if d.Current == nil || !d.List.HasActive() {
+		return nil
+	}
+
+	return d.Current.Children[d.List.ActiveItem]
+}
+
+func (d *DiskUsageView) Tick(input *Input, app *App) {
+	if input.Escape {
+		d.Close()
+		return
+	}
+
+	if d.Current == nil {
+		return
+	}
+
+	if input.Backspace {
+		d.goUp()
+		return
+	}
+
+	if d.List.Tick(input) {
+		return
+	}
+
+	active := d.getActive()
+
+	switch input.TypedCharacter {
+	case 'h':
+		d.goUp()
+	case 'l':
+		if active != nil && active.IsFolder {
+			d.Current = active
+			d.List.ActiveItem = 0
+			d.List.FirstVisible = 0
+			d.updateList()
+		}
+	case '\n':
+		// Shows the item in the active view, so it can be worked with like any other item
+		if active != nil {
+			d.Close()
+			if app.GoToPath(d.Current.FullPath()) {
+				app.ItemViews[app.ActiveView].SetActiveByName(active.Name)
+			}
+		}
+	case 'r':
+		d.scan(d.Current, d.Current.FullPath(), app)
+	case 'x':
+		if active != nil {
+			d.delete(active, false, app)
+		}
+	case 'X':
+		if active != nil {
+			d.delete(active, true, app)
+		}
+	}
+}
+
+func (d *DiskUsageView) goUp() {
+	if d.Current.Parent == nil {
+		return
+	}
+
+	previous := d.Current
+	d.Current = d.Current.Parent
+	d.updateList()
+
+	for index, child := range d.Current.Children {
+		if child == previous {
+			d.List.ActiveItem = int32(index)
+			break
+		}
+	}
+}
+
+// Moves the item to the trash, or deletes it permanently, and takes it out of the tree without scanning again
+func (d *DiskUsageView) delete(node *DiskUsageNode, permanently bool, app *App) {
+	fullPath := node.FullPath()
+	directory := getParentPath(fullPath)
+
+	title := "Moving " + fullPath + " to the trash"
+	if permanently {
+		title = "Deleting " + fullPath + " permanently"
+	}
+
+	var ops []JournalOp
+	app.Jobs.Add(title, func(job *Job) error {
+		if permanently {
+			job.SetTotal(MeasureItem(fullPath))
+			return RemoveItem(fullPath, job)
+		}
+
+		trashedPath, err := MoveToTrash(fullPath, job)
+		if err == nil {
+			ops = append(ops, JournalOp{Type: JournalOpTrash, From: fullPath, To: trashedPath})
+		}
+
+		return err
+	}, func(err error) {
+		app.Journal.Record("Delete "+node.Name, ops...)
+		app.RefreshViewsShowing(directory)
+
+		if err == nil && node.Parent != nil {
+			node.Remove()
+			d.updateList()
+		}
+	})
+}
+
+func (d *DiskUsageView) Render(renderer *sdl.Renderer, parentRect *sdl.Rect, app *App) {
+	theme := app.Theme.PanelTheme
+
+	width := d.MaxWidth
+	if width > parentRect.W-20 {
+		width = parentRect.W - 20
+	}
+
+	rect := sdl.Rect{
+		X: parentRect.X + (parentRect.W-width)/2,
+		Y: parentRect.Y + 40,
+		W: width,
+		H: parentRect.H - 80,
+	}
+
+	title := "Disk usage (l/h to browse, Enter to show, r to rescan, x/X to delete)"
+	if d.Current != nil {
+		title = fmt.Sprintf("%s  %s (l/h to browse, Enter to show, r to rescan, x/X to delete)", d.Current.FullPath(), bytesToString(d.Current.Size))
+	}
+
+	if d.scanJob != nil {
+		files, _ := d.scanJob.Files()
+		bytes, _ := d.scanJob.Bytes()
+		title = fmt.Sprintf("Analyzing %s: %d files, %s so far", d.scanPath, files, bytesToString(bytes))
+	}
+
+	insetRect := DrawPanel(renderer, &rect, title, d.HeaderHeight, d.Padding, &app.Font, theme)
+
+	listRect := insetRect
+	listRect.W -= d.BarWidth + d.Padding
+	emptyText := "The folder is empty"
+	if d.Current == nil {
+		emptyText = "Analyzing..."
+	}
+	d.List.Render(renderer, &listRect, &app.Font, theme, emptyText)
+
+	if d.Current == nil {
+		return
+	}
+
+	// The bars show the share of the folder each item takes
+	visible := listRect.H / d.List.ItemHeight
+	for i := int32(0); i < visible; i++ {
+		index := d.List.FirstVisible + i
+		if index >= int32(len(d.Current.Children)) {
+			break
+		}
+
+		barRect := sdl.Rect{
+			X: listRect.X + listRect.W + d.Padding,
+			Y: listRect.Y + i*d.List.ItemHeight + d.List.ItemHeight/2 - 6,
+			W: d.BarWidth - d.Padding,
+			H: 12,
+		}
+		DrawProgressBar(renderer, &barRect, d.getShare(d.Current.Children[index]), theme)
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The duplicate finder groups the files by size first, which costs nothing, then by a hash of their first bytes and
+// only then by a hash of their whole contents, so that only the files that are very likely to be duplicates are read
+// completely. Empty files are left out, there's nothing to gain by removing them. Hard links to the same file are
+// counted once, they don't take any extra space.
+
+const duplicatePartialSize = 64 * 1024
+
+type DuplicateFile struct {
+	FullPath   string
+	IsSelected bool // Marked for deleting, linking or moving
+}
+
+type DuplicateGroup struct {
+	Size  int64
+	Files []DuplicateFile // Sorted by path
+}
+
+// Returns the space that would be freed by keeping a single copy
+func (g *DuplicateGroup) Wasted() int64 {
+	return g.Size * int64(len(g.Files)-1)
+}
+
+type duplicateCandidate struct {
+	fullPath string
+	size     int64
+	hash     []byte
+}
+
+// Finds the files with the same contents in the folder, and in the folders inside of it if recursive is set. The
+// groups that waste the most space come first.
+func FindDuplicates(fullPath string, recursive bool, job *Job) ([]DuplicateGroup, error) {
+	var candidates []duplicateCandidate
+	seen := make(map[FileIdentity]bool)
+
+	err := collectDuplicateCandidates(fullPath, recursive, seen, &candidates, job)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := groupCandidates(candidates, func(c duplicateCandidate) string {
+		return strconv.FormatInt(c.size, 10)
+	})
+
+	// Only the files that share their size with another file are read
+	var partialBytes int64
+	for _, group := range groups {
+		for _, c := range group {
+			partialBytes += min(c.size, duplicatePartialSize)
+		}
+	}
+	job.SetTotal(0, partialBytes)
+
+	groups, err = hashCandidates(groups, duplicatePartialSize, job)
+	if err != nil {
+		return nil, err
+	}
+
+	// The files that are smaller than the partial hash have been read completely already
+	var complete, partial [][]duplicateCandidate
+	var fullBytes int64
+	for _, group := range groups {
+		if group[0].size <= duplicatePartialSize {
+			complete = append(complete, group)
+			continue
+		}
+
+		partial = append(partial, group)
+		fullBytes += group[0].size * int64(len(group))
+	}
+
+	done, _ := job.Bytes()
+	job.SetTotal(0, done+fullBytes)
+
+	partial, err = hashCandidates(partial, -1, job)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []DuplicateGroup
+	for _, group := range append(complete, partial...) {
+		files := make([]DuplicateFile, len(group))
+		for index, c := range group {
+			files[index] = DuplicateFile{FullPath: c.fullPath}
+		}
+
+		sort.Slice(files, func(i, j int) bool { return files[i].FullPath < files[j].FullPath })
+		result = append(result, DuplicateGroup{Size: group[0].size, Files: files})
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Wasted() == result[j].Wasted() {
+			return result[i].Files[0].FullPath < result[j].Files[0].FullPath
+		}
+
+		return result[i].Wasted() > result[j].Wasted()
+	})
+
+	return result, nil
+}
+
+func collectDuplicateCandidates(fullPath string, recursive bool, seen map[FileIdentity]bool, candidates *[]duplicateCandidate, job *Job) error {
+	// Folders that can't be read are skipped, the rest of the files are still compared
+	entries, _ := os.ReadDir(fullPath)
+
+	for _, entry := range entries {
+		err := job.Checkpoint()
+		if err != nil {
+			return err
+		}
+
+		entryPath := path.Join(fullPath, entry.Name())
+
+		// Links are not followed, so a folder can't be reached twice
+		if entry.IsDir() {
+			if recursive {
+				err = collectDuplicateCandidates(entryPath, recursive, seen, candidates, job)
+				if err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		stats, err := entry.Info()
+		if err != nil || stats.Size() == 0 {
+			continue
+		}
+
+		identity, ok := getFileIdentity(stats)
+		if ok && identity.Links > 1 {
+			if seen[identity] {
+				continue
+			}
+			seen[identity] = true
+		}
+
+		*candidates = append(*candidates, duplicateCandidate{fullPath: entryPath, size: stats.Size()})
+
+		err = job.AddFile()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Splits the candidates into groups with the same key, leaving out the groups with a single file
+func groupCandidates(candidates []duplicateCandidate, key func(c duplicateCandidate) string) (result [][]duplicateCandidate) {
+	groups := make(map[string][]duplicateCandidate)
+	var keys []string
+
+	for _, c := range candidates {
+		k := key(c)
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], c)
+	}
+
+	for _, k := range keys {
+		if len(groups[k]) > 1 {
+			result = append(result, groups[k])
+		}
+	}
+
+	return
+}
+
+// Hashes the first limit bytes of every file, or the whole file if limit is negative, and splits the groups by the
+// hash. The files that can't be read are left out.
+func hashCandidates(groups [][]duplicateCandidate, limit int64, job *Job) (result [][]duplicateCandidate, err error) {
+	for _, group := range groups {
+		var hashed []duplicateCandidate
+		for _, c := range group {
+			c.hash, err = hashFilePart(c.fullPath, limit, job)
+			if errors.Is(err, ErrJobCancelled) {
+				return nil, err
+			}
+
+			if err == nil {
+				hashed = append(hashed, c)
+			}
+		}
+
+		result = append(result, groupCandidates(hashed, func(c duplicateCandidate) string {
+			return string(c.hash)
+		})...)
+	}
+
+	return result, nil
+}
+
+func hashFilePart(fullPath string, limit int64, job *Job) ([]byte, error) {
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if limit >= 0 {
+		reader = io.LimitReader(file, limit)
+	}
+
+	hash := sha256.New()
+	_, err = copyStream(hash, reader, job)
+	if err != nil {
+		return nil, err
+	}
+
+	return hash.Sum(nil), nil
+}
+
+// Returns the path relative to the folder the duplicates were searched in, which is shorter to show
+func getRelativePath(parent string, fullPath string) string {
+	if !strings.HasSuffix(parent, "/") {
+		parent += "/"
+	}
+
+	return strings.TrimPrefix(fullPath, parent)
+}
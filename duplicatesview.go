@@ -0,0 +1,485 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Every group is shown as a header row followed by a row for each of its files
+type duplicateRow struct {
+	Group int
+	File  int // -1 for the header of the group
+}
+
+type DuplicatesView struct {
+	IsOpen    bool
+	Path      string
+	Recursive bool
+	Groups    []DuplicateGroup
+	List      ListView
+
+	rows    []duplicateRow
+	scanJob *Job // The search that is running, nil if there's none
+
+	MaxWidth     int32
+	Padding      int32
+	HeaderHeight int32
+}
+
+func NewDuplicatesView() *DuplicatesView {
+	return &DuplicatesView{
+		List:         *NewListView(),
+		MaxWidth:     900,
+		Padding:      8,
+		HeaderHeight: 28,
+	}
+}
+
+func (d *DuplicatesView) Open(fullPath string, app *App) {
+	d.IsOpen = true
+
+	if d.Path == fullPath && (d.Groups != nil || d.scanJob != nil) {
+		return
+	}
+
+	d.Path = fullPath
+	d.scan(app)
+}
+
+func (d *DuplicatesView) Close() {
+	d.IsOpen = false
+}
+
+func (d *DuplicatesView) scan(app *App) {
+	if d.scanJob != nil {
+		d.scanJob.Cancel()
+	}
+
+	d.Groups = nil
+	d.List.ActiveItem = 0
+	d.updateList()
+
+	fullPath := d.Path
+	recursive := d.Recursive
+	var groups []DuplicateGroup
+	var scanJob *Job
+	scanJob = app.Jobs.Add("Finding duplicates in "+fullPath, func(job *Job) (err error) {
+		groups, err = FindDuplicates(fullPath, recursive, job)
+		return
+	}, func(err error) {
+		// Another search was started in the meantime
+		if d.scanJob != scanJob {
+			return
+		}
+
+		d.scanJob = nil
+		if err == nil {
+			d.Groups = groups
+			d.updateList()
+		}
+	})
+
+	d.scanJob = scanJob
+}
+
+func (d *DuplicatesView) updateList() {
+	d.rows = nil
+	var items []ListItem
+
+	for groupIndex, group := range d.Groups {
+		d.rows = append(d.rows, duplicateRow{Group: groupIndex, File: -1})
+		items = append(items, ListItem{
+			Text:     fmt.Sprintf("%d copies of %s", len(group.Files), bytesToString(group.Size)),
+			Detail:   bytesToString(group.Wasted()) + " can be freed",
+			ColorKey: "header_color",
+		})
+
+		for fileIndex, file := range group.Files {
+			d.rows = append(d.rows, duplicateRow{Group: groupIndex, File: fileIndex})
+			items = append(items, ListItem{Text: "    " + getRelativePath(d.Path, file.FullPath), IsSelected: file.IsSelected})
+		}
+	}
+
+	d.List.SetItems(items)
+}
+
+func (d *DuplicatesView) getActiveRow() (duplicateRow, bool) {
+	if !d.List.HasActive() {
+		return duplicateRow{}, false
+	}
+
+	return d.rows[d.List.ActiveItem], true
+}
+
+// Marks the active file, or every file but the first one if the header of a group is active
+func (d *DuplicatesView) toggleActive() {
+	row, ok := d.getActiveRow()
+	if !ok {
+		return
+	}
+
+	group := &d.Groups[row.Group]
+	if row.File >= 0 {
+		group.Files[row.File].IsSelected = !group.Files[row.File].IsSelected
+	} else {
+		d.markCopies(group, !group.Files[1].IsSelected)
+	}
+
+	d.updateList()
+}
+
+func (d *DuplicatesView) markCopies(group *DuplicateGroup, mark bool) {
+	for index := range group.Files {
+		group.Files[index].IsSelected = mark && index > 0
+	}
+}
+
+func (d *DuplicatesView) markAll(mark bool) {
+	for index := range d.Groups {
+		d.markCopies(&d.Groups[index], mark)
+	}
+
+	d.updateList()
+}
+
+func (d *DuplicatesView) getMarked() (result []string) {
+	for _, group := range d.Groups {
+		for _, file := range group.Files {
+			if file.IsSelected {
+				result = append(result, file.FullPath)
+			}
+		}
+	}
+
+	return
+}
+
+// Returns the first file of every group that isn't marked, keyed by the marked files of the group. Returns false if
+// every file of a group is marked, because then no copy would be left.
+func (d *DuplicatesView) getKeptFiles() (map[string]string, bool) {
+	result := make(map[string]string)
+
+	for _, group := range d.Groups {
+		kept := ""
+		for _, file := range group.Files {
+			if !file.IsSelected {
+				kept = file.FullPath
+				break
+			}
+		}
+
+		for _, file := range group.Files {
+			if !file.IsSelected {
+				continue
+			}
+
+			if kept == "" {
+				NotifyError("Every copy of " + path.Base(file.FullPath) + " is marked, keep at least one of them")
+				return nil, false
+			}
+
+			result[file.FullPath] = kept
+		}
+	}
+
+	return result, true
+}
+
+// Takes the files out of the groups once they have been dealt with
+func (d *DuplicatesView) removeFiles(done []string) {
+	var groups []DuplicateGroup
+	for _, group := range d.Groups {
+		var files []DuplicateFile
+		for _, file := range group.Files {
+			if IndexOf(done, file.FullPath) < 0 {
+				files = append(files, file)
+			}
+		}
+
+		if len(files) > 1 {
+			group.Files = files
+			groups = append(groups, group)
+		}
+	}
+
+	d.Groups = groups
+	d.updateList()
+}
+
+func (d *DuplicatesView) Tick(input *Input, app *App) {
+	if input.Escape {
+		d.Close()
+		return
+	}
+
+	if input.TypedCharacter == 'a' && input.Ctrl {
+		d.markAll(true)
+		return
+	}
+
+	if d.List.Tick(input) {
+		return
+	}
+
+	switch input.TypedCharacter {
+	case 'v':
+		d.toggleActive()
+	case 'V':
+		d.markAll(false)
+	case '\n':
+		// Shows the file in the active view, so it can be worked with like any other item
+		row, ok := d.getActiveRow()
+		if ok && row.File >= 0 {
+			fullPath := d.Groups[row.Group].Files[row.File].FullPath
+			d.Close()
+			if app.GoToPath(getParentPath(fullPath)) {
+				app.ItemViews[app.ActiveView].SetActiveByName(path.Base(fullPath))
+			}
+		}
+	case 'r':
+		d.scan(app)
+	case 'R':
+		d.Recursive = !d.Recursive
+		d.scan(app)
+	case 'x':
+		d.deleteMarked(false, app)
+	case 'X':
+		d.deleteMarked(true, app)
+	case 'L':
+		d.linkMarked(app)
+	case 'm':
+		d.moveMarked(app)
+	}
+}
+
+func (d *DuplicatesView) deleteMarked(permanently bool, app *App) {
+	marked := d.getMarked()
+	if len(marked) == 0 {
+		return
+	}
+
+	if _, ok := d.getKeptFiles(); !ok {
+		return
+	}
+
+	title := fmt.Sprintf("Moving %d duplicates to the trash", len(marked))
+	if permanently {
+		title = fmt.Sprintf("Deleting %d duplicates permanently", len(marked))
+	}
+
+	var done []string
+	var ops []JournalOp
+	app.Jobs.Add(title, func(job *Job) error {
+		for _, fullPath := range marked {
+			var err error
+			if permanently {
+				err = RemoveItem(fullPath, job)
+			} else {
+				var trashedPath string
+				trashedPath, err = MoveToTrash(fullPath, job)
+				if err == nil {
+					ops = append(ops, JournalOp{Type: JournalOpTrash, From: fullPath, To: trashedPath})
+				}
+			}
+
+			if err != nil {
+				return err
+			}
+
+			done = append(done, fullPath)
+		}
+
+		return nil
+	}, func(err error) {
+		app.Journal.Record("Delete duplicates", ops...)
+		d.finish(done, app)
+	})
+}
+
+// Replaces the marked files by hard links to the copy that is kept, so they take no extra space. The files are
+// moved to the trash, so replacing them can be undone.
+func (d *DuplicatesView) linkMarked(app *App) {
+	kept, ok := d.getKeptFiles()
+	if !ok || len(kept) == 0 {
+		return
+	}
+
+	marked := d.getMarked()
+
+	var done []string
+	var ops []JournalOp
+	app.Jobs.Add(fmt.Sprintf("Replacing %d duplicates with links", len(marked)), func(job *Job) error {
+		var errs []error
+		for _, fullPath := range marked {
+			err := job.Checkpoint()
+			if err != nil {
+				return err
+			}
+
+			source := kept[fullPath]
+
+			// The file might have changed since the search
+			sourceStats, sourceErr := os.Stat(source)
+			stats, err := os.Stat(fullPath)
+			if sourceErr != nil || err != nil || sourceStats.Size() != stats.Size() {
+				errs = append(errs, errors.New(fullPath+" has changed, search again before replacing it"))
+				continue
+			}
+
+			// Hard links can't point to another drive
+			sourceIdentity, sourceOk := getFileIdentity(sourceStats)
+			identity, ok := getFileIdentity(stats)
+			if sourceOk && ok && sourceIdentity.Device != identity.Device {
+				errs = append(errs, errors.New(fullPath+" is on another drive than "+source+", so it can't be linked to it"))
+				continue
+			}
+
+			// The link is made next to the duplicate first, so the duplicate is only replaced once the link exists
+			linkPath := path.Join(getParentPath(fullPath), "."+path.Base(fullPath)+".bonfire-link")
+			err = os.Link(source, linkPath)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			trashedPath, err := MoveToTrash(fullPath, job)
+			if err != nil {
+				os.Remove(linkPath)
+				errs = append(errs, err)
+				continue
+			}
+			ops = append(ops, JournalOp{Type: JournalOpTrash, From: fullPath, To: trashedPath})
+
+			err = os.Rename(linkPath, fullPath)
+			if err != nil {
+				os.Remove(linkPath)
+				errs = append(errs, err)
+				continue
+			}
+			ops = append(ops, JournalOp{Type: JournalOpHardLink, From: source, To: fullPath})
+
+			done = append(done, fullPath)
+		}
+
+		return joinErrors(errs)
+	}, func(err error) {
+		app.Journal.Record("Link duplicates", ops...)
+		d.finish(done, app)
+	})
+}
+
+func (d *DuplicatesView) moveMarked(app *App) {
+	other := app.GetOtherView()
+	if other == nil {
+		NotifyError("Open another view to move the duplicates to")
+		return
+	}
+
+	if other.isNotLocal() {
+		return
+	}
+
+	marked := d.getMarked()
+	if len(marked) == 0 {
+		return
+	}
+
+	destination := other.CurrentPath
+
+	var done []string
+	var ops []JournalOp
+	app.Jobs.Add(fmt.Sprintf("Moving %d duplicates to %s", len(marked), destination), func(job *Job) error {
+		resolver := app.NewConflictResolver()
+
+		var errs []error
+		for _, fullPath := range marked {
+			target, resolveOps, err := resolver.Resolve(fullPath, path.Join(destination, path.Base(fullPath)))
+			ops = append(ops, resolveOps...)
+			if errors.Is(err, ErrJobCancelled) {
+				return err
+			}
+
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			if target == "" {
+				continue
+			}
+
+			err = MovePath(fullPath, target, job)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			ops = append(ops, JournalOp{Type: JournalOpMove, From: fullPath, To: target})
+			done = append(done, fullPath)
+		}
+
+		return joinErrors(errs)
+	}, func(err error) {
+		app.Journal.Record("Move duplicates", ops...)
+		app.RefreshViewsShowing(destination)
+		d.finish(done, app)
+	})
+}
+
+func (d *DuplicatesView) finish(done []string, app *App) {
+	var folders []string
+	for _, fullPath := range done {
+		folder := getParentPath(fullPath)
+		if IndexOf(folders, folder) < 0 {
+			folders = append(folders, folder)
+		}
+	}
+
+	for _, folder := range folders {
+		app.RefreshViewsShowing(folder)
+	}
+
+	d.removeFiles(done)
+}
+
+func (d *DuplicatesView) Render(renderer *sdl.Renderer, parentRect *sdl.Rect, app *App) {
+	theme := app.Theme.PanelTheme
+
+	width := d.MaxWidth
+	if width > parentRect.W-20 {
+		width = parentRect.W - 20
+	}
+
+	rect := sdl.Rect{
+		X: parentRect.X + (parentRect.W-width)/2,
+		Y: parentRect.Y + 40,
+		W: width,
+		H: parentRect.H - 80,
+	}
+
+	scope := "in"
+	if d.Recursive {
+		scope = "in and under"
+	}
+
+	var wasted int64
+	for _, group := range d.Groups {
+		wasted += group.Wasted()
+	}
+
+	title := fmt.Sprintf("Duplicates %s %s, %s can be freed (v to mark, x/X to delete, L to link, m to move, R for subfolders)", scope, d.Path, bytesToString(wasted))
+	emptyText := "No duplicates found"
+
+	if d.scanJob != nil {
+		files, _ := d.scanJob.Files()
+		title = fmt.Sprintf("Finding duplicates %s %s: %d files, %d%%", scope, d.Path, files, int(d.scanJob.Progress()*100))
+		emptyText = "Searching..."
+	}
+
+	insetRect := DrawPanel(renderer, &rect, title, d.HeaderHeight, d.Padding, &app.Font, theme)
+	d.List.Render(renderer, &insetRect, &app.Font, theme, emptyText)
+}
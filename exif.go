@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// A small reader for the EXIF metadata stored in JPEG and TIFF files. EXIF is a TIFF structure: a header with the
+// byte order followed by IFDs (image file directories), which are lists of tagged values. The main IFD points to
+// the EXIF IFD with the camera settings and to the GPS IFD.
+
+const (
+	exifTagImageWidth       = 0x0100
+	exifTagImageLength      = 0x0101
+	exifTagBitsPerSample    = 0x0102
+	exifTagMake             = 0x010F
+	exifTagModel            = 0x0110
+	exifTagOrientation      = 0x0112
+	exifTagDateTime         = 0x0132
+	exifTagExposureTime     = 0x829A
+	exifTagFNumber          = 0x829D
+	exifTagExifIFD          = 0x8769
+	exifTagISOSpeed         = 0x8827
+	exifTagGPSIFD           = 0x8825
+	exifTagDateTimeOriginal = 0x9003
+	exifTagFocalLength      = 0x920A
+	exifTagLensModel        = 0xA434
+
+	exifTagGPSLatitudeRef  = 0x0001
+	exifTagGPSLatitude     = 0x0002
+	exifTagGPSLongitudeRef = 0x0003
+	exifTagGPSLongitude    = 0x0004
+
+	exifTypeByte      = 1
+	exifTypeASCII     = 2
+	exifTypeShort     = 3
+	exifTypeLong      = 4
+	exifTypeRational  = 5
+	exifTypeUndefined = 7
+	exifTypeSLong     = 9
+	exifTypeSRational = 10
+
+	exifDateFormat = "2006:01:02 15:04:05"
+
+	// Metadata is at the start of the file, there's no need to read the whole image
+	maxExifSearchSize = 256 * 1024
+)
+
+var exifTypeSizes = map[uint16]uint32{
+	exifTypeByte:      1,
+	exifTypeASCII:     1,
+	exifTypeShort:     2,
+	exifTypeLong:      4,
+	exifTypeRational:  8,
+	exifTypeUndefined: 1,
+	exifTypeSLong:     4,
+	exifTypeSRational: 8,
+}
+
+type ExifTag struct {
+	Type  uint16
+	Count uint32
+	Data  []byte
+}
+
+type ExifData struct {
+	Order binary.ByteOrder
+	Main  map[uint16]ExifTag
+	Exif  map[uint16]ExifTag
+	GPS   map[uint16]ExifTag
+}
+
+func ReadExif(fullPath string) (*ExifData, error) {
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	header := make([]byte, 4)
+	_, err = io.ReadFull(file, header)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.Equal(header, []byte("II*\x00")) || bytes.Equal(header, []byte("MM\x00*")) {
+		data, err := io.ReadAll(io.LimitReader(io.MultiReader(bytes.NewReader(header), file), maxExifSearchSize))
+		if err != nil {
+			return nil, err
+		}
+
+		return parseExif(data)
+	}
+
+	if header[0] != 0xFF || header[1] != 0xD8 {
+		return nil, errors.New("no EXIF data")
+	}
+
+	return readJpegExif(io.MultiReader(bytes.NewReader(header[2:]), file))
+}
+
+// Goes through the JPEG segments until the APP1 segment with the EXIF data
+func readJpegExif(reader io.Reader) (*ExifData, error) {
+	marker := make([]byte, 4)
+	for {
+		_, err := io.ReadFull(reader, marker)
+		if err != nil {
+			return nil, errors.New("no EXIF data")
+		}
+
+		if marker[0] != 0xFF {
+			return nil, errors.New("invalid JPEG segment")
+		}
+
+		// Start of scan or end of image, the metadata always comes before those
+		if marker[1] == 0xDA || marker[1] == 0xD9 {
+			return nil, errors.New("no EXIF data")
+		}
+
+		size := int64(binary.BigEndian.Uint16(marker[2:])) - 2
+		if size < 0 {
+			return nil, errors.New("invalid JPEG segment")
+		}
+
+		if marker[1] != 0xE1 {
+			_, err = io.CopyN(io.Discard, reader, size)
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		data := make([]byte, size)
+		_, err = io.ReadFull(reader, data)
+		if err != nil {
+			return nil, err
+		}
+
+		if bytes.HasPrefix(data, []byte("Exif\x00\x00")) {
+			return parseExif(data[6:])
+		}
+	}
+}
+
+func parseExif(data []byte) (*ExifData, error) {
+	if len(data) < 8 {
+		return nil, errors.New("invalid EXIF data")
+	}
+
+	result := &ExifData{}
+	switch string(data[:2]) {
+	case "II":
+		result.Order = binary.LittleEndian
+	case "MM":
+		result.Order = binary.BigEndian
+	default:
+		return nil, errors.New("invalid EXIF byte order")
+	}
+
+	var err error
+	result.Main, err = result.parseIFD(data, result.Order.Uint32(data[4:]))
+	if err != nil {
+		return nil, err
+	}
+
+	if offset, ok := result.getUint(result.Main, exifTagExifIFD); ok {
+		result.Exif, _ = result.parseIFD(data, offset)
+	}
+
+	if offset, ok := result.getUint(result.Main, exifTagGPSIFD); ok {
+		result.GPS, _ = result.parseIFD(data, offset)
+	}
+
+	return result, nil
+}
+
+func (e *ExifData) parseIFD(data []byte, offset uint32) (map[uint16]ExifTag, error) {
+	if uint64(offset)+2 > uint64(len(data)) {
+		return nil, errors.New("invalid EXIF offset")
+	}
+
+	count := uint32(e.Order.Uint16(data[offset:]))
+	start := offset + 2
+	if uint64(start)+uint64(count)*12 > uint64(len(data)) {
+		return nil, errors.New("invalid EXIF directory")
+	}
+
+	result := make(map[uint16]ExifTag, count)
+	for i := uint32(0); i < count; i++ {
+		entry := data[start+i*12 : start+i*12+12]
+
+		tag := ExifTag{
+			Type:  e.Order.Uint16(entry[2:]),
+			Count: e.Order.Uint32(entry[4:]),
+		}
+
+		typeSize, ok := exifTypeSizes[tag.Type]
+		if !ok {
+			continue
+		}
+
+		size := uint64(typeSize) * uint64(tag.Count)
+		if size <= 4 {
+			// Small values are stored in the entry itself instead of an offset
+			tag.Data = entry[8 : 8+size]
+		} else {
+			valueOffset := uint64(e.Order.Uint32(entry[8:]))
+			if valueOffset+size > uint64(len(data)) {
+				continue
+			}
+
+			tag.Data = data[valueOffset : valueOffset+size]
+		}
+
+		result[e.Order.Uint16(entry)] = tag
+	}
+
+	return result, nil
+}
+
+func (e *ExifData) getUint(ifd map[uint16]ExifTag, id uint16) (uint32, bool) {
+	tag, ok := ifd[id]
+	if !ok || tag.Count == 0 {
+		return 0, false
+	}
+
+	switch tag.Type {
+	case exifTypeByte, exifTypeUndefined:
+		return uint32(tag.Data[0]), true
+	case exifTypeShort:
+		return uint32(e.Order.Uint16(tag.Data)), true
+	case exifTypeLong, exifTypeSLong:
+		return e.Order.Uint32(tag.Data), true
+	}
+
+	return 0, false
+}
+
+func (e *ExifData) getString(ifd map[uint16]ExifTag, id uint16) (string, bool) {
+	tag, ok := ifd[id]
+	if !ok || tag.Type != exifTypeASCII {
+		return "", false
+	}
+
+	return strings.TrimSpace(strings.TrimRight(string(tag.Data), "\x00")), true
+}
+
+// Returns the value at the index of a rational tag, which is a fraction of two numbers
+func (e *ExifData) getRational(ifd map[uint16]ExifTag, id uint16, index uint32) (float64, bool) {
+	tag, ok := ifd[id]
+	if !ok || index >= tag.Count || (tag.Type != exifTypeRational && tag.Type != exifTypeSRational) {
+		return 0, false
+	}
+
+	numerator := e.Order.Uint32(tag.Data[index*8:])
+	denominator := e.Order.Uint32(tag.Data[index*8+4:])
+	if denominator == 0 {
+		return 0, false
+	}
+
+	if tag.Type == exifTypeSRational {
+		return float64(int32(numerator)) / float64(int32(denominator)), true
+	}
+
+	return float64(numerator) / float64(denominator), true
+}
+
+// Returns the make and the model of the camera. Most cameras repeat the make in the model.
+func (e *ExifData) Camera() string {
+	maker, _ := e.getString(e.Main, exifTagMake)
+	model, _ := e.getString(e.Main, exifTagModel)
+
+	if maker == "" || strings.HasPrefix(strings.ToLower(model), strings.ToLower(maker)) {
+		return model
+	}
+
+	return strings.TrimSpace(maker + " " + model)
+}
+
+func (e *ExifData) Lens() string {
+	result, _ := e.getString(e.Exif, exifTagLensModel)
+	return result
+}
+
+// Returns how the image has to be turned to be shown upright, the pixels are stored as the sensor saw them
+func (e *ExifData) Orientation() string {
+	value, ok := e.getUint(e.Main, exifTagOrientation)
+	if !ok {
+		return ""
+	}
+
+	switch value {
+	case 1:
+		return "Normal"
+	case 2:
+		return "Mirrored horizontally"
+	case 3:
+		return "Rotated 180°"
+	case 4:
+		return "Mirrored vertically"
+	case 5:
+		return "Mirrored and rotated 90° counterclockwise"
+	case 6:
+		return "Rotated 90° clockwise"
+	case 7:
+		return "Mirrored and rotated 90° clockwise"
+	case 8:
+		return "Rotated 90° counterclockwise"
+	}
+
+	return ""
+}
+
+// Returns the settings the photo was taken with, e.g. "1/250 s, f/2.8, ISO 100, 50 mm"
+func (e *ExifData) Exposure() string {
+	var parts []string
+
+	if value, ok := e.getRational(e.Exif, exifTagExposureTime, 0); ok && value > 0 {
+		if value < 1 {
+			parts = append(parts, fmt.Sprintf("1/%.0f s", 1/value))
+		} else {
+			parts = append(parts, fmt.Sprintf("%g s", value))
+		}
+	}
+
+	if value, ok := e.getRational(e.Exif, exifTagFNumber, 0); ok {
+		parts = append(parts, fmt.Sprintf("f/%g", value))
+	}
+
+	if value, ok := e.getUint(e.Exif, exifTagISOSpeed); ok {
+		parts = append(parts, fmt.Sprintf("ISO %d", value))
+	}
+
+	if value, ok := e.getRational(e.Exif, exifTagFocalLength, 0); ok {
+		parts = append(parts, fmt.Sprintf("%g mm", value))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// Returns the latitude and the longitude in degrees, negative to the south and to the west
+func (e *ExifData) Location() (latitude float64, longitude float64, ok bool) {
+	latitude, ok = e.getCoordinate(exifTagGPSLatitude, exifTagGPSLatitudeRef, "S")
+	if !ok {
+		return
+	}
+
+	longitude, ok = e.getCoordinate(exifTagGPSLongitude, exifTagGPSLongitudeRef, "W")
+	return
+}
+
+// Coordinates are stored as degrees, minutes and seconds, and the hemisphere is in a tag of its own
+func (e *ExifData) getCoordinate(id uint16, refID uint16, negativeRef string) (float64, bool) {
+	degrees, ok := e.getRational(e.GPS, id, 0)
+	minutes, okMinutes := e.getRational(e.GPS, id, 1)
+	seconds, okSeconds := e.getRational(e.GPS, id, 2)
+	if !ok || !okMinutes || !okSeconds {
+		return 0, false
+	}
+
+	result := degrees + minutes/60 + seconds/3600
+	if ref, _ := e.getString(e.GPS, refID); ref == negativeRef {
+		result = -result
+	}
+
+	return result, true
+}
+
+// Returns the size of the image, which is only in the main IFD of TIFF files
+func (e *ExifData) Size() (width uint32, height uint32, ok bool) {
+	width, ok = e.getUint(e.Main, exifTagImageWidth)
+	if !ok {
+		return
+	}
+
+	height, ok = e.getUint(e.Main, exifTagImageLength)
+	return
+}
+
+// Returns the date the photo was taken, or the date the file was last changed by the camera if that's missing
+func (e *ExifData) Date() (time.Time, bool) {
+	value, ok := e.getString(e.Exif, exifTagDateTimeOriginal)
+	if !ok {
+		value, ok = e.getString(e.Main, exifTagDateTime)
+	}
+
+	if !ok {
+		return time.Time{}, false
+	}
+
+	result, err := time.ParseInLocation(exifDateFormat, value, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return result, true
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// The details shown in the info view besides the size and the times. The quick ones are read from the stats of the
+// item, the ones that have to read the whole file, like the line count, are counted in another thread.
+
+// The content is sniffed the way browsers do it, the extension is only used if the content says nothing
+func DetectMimeType(fullPath string) string {
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	buffer := make([]byte, 512)
+	read, _ := io.ReadFull(file, buffer)
+
+	// The charset is part of the encoding row
+	result, _, _ := strings.Cut(http.DetectContentType(buffer[:read]), ";")
+	if result == "application/octet-stream" || result == "text/plain" {
+		if byExtension := MimeTypeByExtension(fullPath); byExtension != "" {
+			result = byExtension
+		}
+	}
+
+	return result
+}
+
+func MimeTypeByExtension(name string) string {
+	result, _, _ := strings.Cut(mime.TypeByExtension(path.Ext(name)), ";")
+	return result
+}
+
+func isTextMimeType(mimeType string) bool {
+	switch mimeType {
+	case "application/json", "application/xml", "application/javascript", "application/x-shellscript":
+		return true
+	}
+
+	return strings.HasPrefix(mimeType, "text/")
+}
+
+// Formats the mode the way ls does, followed by the octal mode, e.g. "rwxr-xr-x (755)"
+func FormatPermissions(mode fs.FileMode) string {
+	result := []byte("rwxrwxrwx")
+	for i := range result {
+		if mode&(1<<uint(8-i)) == 0 {
+			result[i] = '-'
+		}
+	}
+
+	setSpecialBit := func(index int, isSet bool, letter byte) {
+		if !isSet {
+			return
+		}
+
+		// Upper case if the bit is set without the execute bit it goes with
+		if result[index] == '-' {
+			result[index] = letter - 'a' + 'A'
+		} else {
+			result[index] = letter
+		}
+	}
+
+	setSpecialBit(2, mode&fs.ModeSetuid != 0, 's')
+	setSpecialBit(5, mode&fs.ModeSetgid != 0, 's')
+	setSpecialBit(8, mode&fs.ModeSticky != 0, 't')
+
+	return string(result) + " (" + FormatOctalMode(mode) + ")"
+}
+
+// Returns the mount point the item is on, which is the deepest mount point that contains it
+func FindMountPoint(fullPath string) (result MountPoint, ok bool) {
+	if resolved, err := filepath.EvalSymlinks(fullPath); err == nil {
+		fullPath = filepath.ToSlash(resolved)
+	}
+
+	for _, mount := range getMountPoints() {
+		if IsSubPath(mount.Path, fullPath) && len(mount.Path) >= len(result.Path) {
+			result = mount
+			ok = true
+		}
+	}
+
+	return
+}
+
+// Counts the items directly in the folder
+func CountFolderItems(fullPath string) (files int64, folders int64, err error) {
+	entries, err := os.ReadDir(fullPath)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			folders++
+		} else {
+			files++
+		}
+	}
+
+	return
+}
+
+func formatCount(count int64, singular string, plural string) string {
+	if count == 1 {
+		return "1 " + singular
+	}
+
+	return fmt.Sprintf("%d %s", count, plural)
+}
+
+type TextStats struct {
+	Lines    int64
+	Encoding string // e.g. "UTF-8, LF"
+}
+
+// Reads the whole file to count its lines and to tell its encoding. The encoding is known from the byte order mark
+// if the file has one, otherwise the file is UTF-8 if every character is valid UTF-8, and some 8-bit encoding if
+// not, which one can't be told from the bytes alone.
+func ReadTextStats(fullPath string) (result TextStats, err error) {
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	encoding := ""
+	unitSize := 1 // 2 for UTF-16 and 4 for UTF-32, whose lines are counted by code units
+	isBigEndian := false
+	isASCII := true
+	isUTF8 := true
+
+	var lineFeeds, carriageReturnLineFeeds, units int64
+	var lastUnit uint32
+	var pending []byte // The part of a character or of a code unit that continues in the next chunk
+	isFirst := true
+
+	buffer := make([]byte, 64*1024)
+	for {
+		read, readErr := file.Read(buffer)
+		if readErr != nil && readErr != io.EOF {
+			return result, readErr
+		}
+
+		isLast := readErr == io.EOF
+		chunk := append(pending, buffer[:read]...)
+		pending = nil
+
+		// The byte order mark needs the first four bytes
+		if isFirst && len(chunk) < 4 && !isLast {
+			pending = chunk
+			continue
+		}
+
+		if isFirst {
+			isFirst = false
+
+			boms := []struct {
+				Mark        []byte
+				Encoding    string
+				UnitSize    int
+				IsBigEndian bool
+			}{
+				{[]byte{0xEF, 0xBB, 0xBF}, "UTF-8 with BOM", 1, false},
+				{[]byte{0xFF, 0xFE, 0, 0}, "UTF-32 LE", 4, false},
+				{[]byte{0, 0, 0xFE, 0xFF}, "UTF-32 BE", 4, true},
+				{[]byte{0xFF, 0xFE}, "UTF-16 LE", 2, false},
+				{[]byte{0xFE, 0xFF}, "UTF-16 BE", 2, true},
+			}
+
+			for _, bom := range boms {
+				if bytes.HasPrefix(chunk, bom.Mark) {
+					encoding, unitSize, isBigEndian = bom.Encoding, bom.UnitSize, bom.IsBigEndian
+					chunk = chunk[len(bom.Mark):]
+					break
+				}
+			}
+		}
+
+		end := len(chunk) - len(chunk)%unitSize
+		if unitSize == 1 && !isLast {
+			// Stops before a character that continues in the next chunk
+			for start := end - 1; start >= 0 && start >= end-utf8.UTFMax; start-- {
+				if utf8.RuneStart(chunk[start]) {
+					if !utf8.FullRune(chunk[start:end]) {
+						end = start
+					}
+					break
+				}
+			}
+		}
+
+		pending = append(pending, chunk[end:]...)
+		chunk = chunk[:end]
+
+		if unitSize == 1 {
+			isUTF8 = isUTF8 && utf8.Valid(chunk)
+		}
+
+		for index := 0; index < len(chunk); index += unitSize {
+			var unit uint32
+			for offset := 0; offset < unitSize; offset++ {
+				if isBigEndian {
+					unit = unit<<8 | uint32(chunk[index+offset])
+				} else {
+					unit |= uint32(chunk[index+offset]) << (8 * offset)
+				}
+			}
+
+			if unit >= 0x80 {
+				isASCII = false
+			}
+
+			if unit == '\n' {
+				lineFeeds++
+				if lastUnit == '\r' {
+					carriageReturnLineFeeds++
+				}
+			}
+
+			lastUnit = unit
+			units++
+		}
+
+		if isLast {
+			break
+		}
+	}
+
+	if encoding == "" {
+		switch {
+		case isASCII:
+			encoding = "ASCII"
+		case isUTF8:
+			encoding = "UTF-8"
+		default:
+			encoding = "8-bit, not UTF-8"
+		}
+	}
+
+	// The last line counts even without a line break at the end
+	result.Lines = lineFeeds
+	if units > 0 && lastUnit != '\n' {
+		result.Lines++
+	}
+
+	switch {
+	case lineFeeds == 0:
+	case carriageReturnLineFeeds == lineFeeds:
+		encoding += ", CRLF"
+	case carriageReturnLineFeeds == 0:
+		encoding += ", LF"
+	default:
+		encoding += ", mixed line breaks"
+	}
+
+	result.Encoding = encoding
+	return result, nil
+}
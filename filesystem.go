@@ -1,16 +1,30 @@
 package main
 
 import (
+	"errors"
 	"io/fs"
 	"os"
 	"path"
+	"path/filepath"
 	"strconv"
 	"strings"
-	"syscall"
 )
 
 type FileType int32
 
+// A place the user can start browsing from: a drive on Windows, the root or a mount point elsewhere
+type Root struct {
+	Key   byte // Key that opens the root in the drive selection mode
+	Path  string
+	Label string
+}
+
+type MountPoint struct {
+	Device string
+	Path   string
+	Type   string
+}
+
 const (
 	FileTypeDefault = iota
 	FileTypeExe     = iota
@@ -52,6 +66,35 @@ func ReadDirectory(fullPath string) ([]fs.DirEntry, bool) {
 	return items, true
 }
 
+// Returns what the link points to and the type of the target. broken is true if the target doesn't exist.
+func ReadLink(fullPath string) (target string, targetType ItemType, broken bool) {
+	target, err := os.Readlink(fullPath)
+	if err != nil {
+		return "", ItemTypeFile, true
+	}
+
+	stats, err := os.Stat(fullPath)
+	if err != nil {
+		return target, ItemTypeFile, true
+	}
+
+	if stats.IsDir() {
+		return target, ItemTypeFolder, false
+	}
+
+	return target, ItemTypeFile, false
+}
+
+// Returns the full path of the link target. Relative targets are relative to the folder of the link.
+func GetLinkTargetPath(fullPath string, target string) string {
+	target = filepath.ToSlash(target)
+	if path.IsAbs(target) || filepath.IsAbs(target) {
+		return path.Clean(target)
+	}
+
+	return path.Join(getParentPath(fullPath), target)
+}
+
 func GetDirectorySize(fullPath string) (result int64) {
 	items, _ := ReadDirectory(fullPath)
 	for _, item := range items {
@@ -71,69 +114,352 @@ func GetDirectorySize(fullPath string) (result int64) {
 	return
 }
 
-func DuplicateFile(dirname string, filename string) (bool, string) {
-	name := GetAvailableFileName(dirname, filename)
+// Receives progress updates from long running file operations. If any of the methods returns an error,
+// the operation stops and returns that error.
+type ProgressReporter interface {
+	AddBytes(bytes int64) error
+	AddFile() error
+}
+
+const copyChunkSize = 1024 * 1024
 
-	data, err := os.ReadFile(path.Join(dirname, filename))
+// Copies a single file by streaming it in chunks, so that the whole file is never kept in memory.
+// Mode bits, access and modification times and, where permitted, the owner are preserved.
+// Returns the number of bytes copied.
+func CopyFile(source string, destination string, progress ProgressReporter) (int64, error) {
+	in, err := os.Open(source)
 	if err != nil {
-		NotifyError(err.Error())
-		return false, ""
+		return 0, err
 	}
+	defer in.Close()
 
-	err = os.WriteFile(path.Join(dirname, name), data, 0644)
+	stats, err := in.Stat()
 	if err != nil {
-		NotifyError(err.Error())
-		return false, ""
+		return 0, err
 	}
 
-	return true, name
-}
+	out, err := os.OpenFile(destination, os.O_WRONLY|os.O_CREATE|os.O_EXCL, stats.Mode().Perm()|0200)
+	if err != nil {
+		return 0, err
+	}
 
-func MakeFileCopy(dirname string, filename string, destDirname string) (bool, string) {
-	name := GetAvailableFileName(destDirname, filename)
+	copied, err := copyFileContents(out, in, stats.Size(), progress)
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
 
-	data, err := os.ReadFile(path.Join(dirname, filename))
 	if err != nil {
-		NotifyError(err.Error())
-		return false, ""
+		// Don't leave a half written file behind
+		os.Remove(destination)
+		return copied, err
 	}
 
-	err = os.WriteFile(path.Join(destDirname, name), data, 0644)
+	err = copyMetadata(destination, stats)
 	if err != nil {
-		NotifyError(err.Error())
-		return false, ""
+		return copied, err
 	}
 
-	return true, name
+	if progress != nil {
+		return copied, progress.AddFile()
+	}
+
+	return copied, nil
 }
 
-func GetAvailableFileName(dirName string, filename string) (result string) {
-	fullPath := path.Join(dirName, filename)
+// Tries the platform specific fast path first and falls back to a plain chunked copy if it isn't available
+func copyFileContents(out *os.File, in *os.File, size int64, progress ProgressReporter) (int64, error) {
+	copied, handled, err := copyFileContentsFast(out, in, size, progress)
+	if handled || err != nil {
+		return copied, err
+	}
 
-	extension := path.Ext(filename)
-	name := strings.TrimSuffix(path.Base(filename), extension)
+	return copyStream(out, in, progress)
+}
+
+func copyMetadata(destination string, stats fs.FileInfo) error {
+	// The owner has to be changed before the mode, because chown clears the setuid and setgid bits.
+	// Only privileged users can give files away, so failing to do that is not an error.
+	copyOwner(destination, stats)
+
+	err := os.Chmod(destination, stats.Mode()&(fs.ModePerm|fs.ModeSetuid|fs.ModeSetgid|fs.ModeSticky))
+	if err != nil {
+		return err
+	}
+
+	return os.Chtimes(destination, getAccessTime(stats), stats.ModTime())
+}
+
+// Recursively copies a folder with all of its files and subfolders. The permissions and timestamps are preserved.
+// Errors on individual entries don't stop the copy, they are collected and returned together at the end.
+func CopyDirectory(source string, destination string, progress ProgressReporter) error {
+	if IsSubPath(source, destination) {
+		return errors.New("cannot copy " + source + " into itself")
+	}
 
-	fileDoesNotExist := true
-	count := 0
-	for fileDoesNotExist {
-		_, err := os.Stat(fullPath)
-		if err == nil {
-			count += 1
-			fullPath = path.Join(dirName, name+" ("+strconv.Itoa(count)+")"+extension)
+	stats, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+
+	err = os.Mkdir(destination, stats.Mode().Perm()|0700)
+	if err != nil {
+		return err
+	}
+
+	items, err := os.ReadDir(source)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, item := range items {
+		if progress != nil {
+			// Passing zero bytes gives the reporter a chance to stop the copy between the files
+			err = progress.AddBytes(0)
+			if err != nil {
+				return err
+			}
+		}
+
+		from := path.Join(source, item.Name())
+		to := path.Join(destination, item.Name())
+
+		if item.Type()&fs.ModeSymlink != 0 {
+			err = CopyLink(from, to, progress)
+		} else if item.IsDir() {
+			err = CopyDirectory(from, to, progress)
 		} else {
-			fileDoesNotExist = false
+			_, err = CopyFile(from, to, progress)
+		}
+
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// The timestamps are restored last, because creating the children updates the modification time of the folder
+	err = copyMetadata(destination, stats)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	return joinErrors(errs)
+}
+
+// Creates a symbolic link that points to the same target as the source link. The target is copied as is, so
+// relative links stay relative.
+func CopyLink(source string, destination string, progress ProgressReporter) error {
+	target, err := os.Readlink(source)
+	if err != nil {
+		return err
+	}
+
+	err = os.Symlink(target, destination)
+	if err != nil {
+		return err
+	}
+
+	if progress != nil {
+		return progress.AddFile()
+	}
+
+	return nil
+}
+
+// Copies a file or a folder to the exact destination path, which must not exist yet. Symbolic links are copied as
+// links instead of copying what they point to.
+func CopyPath(source string, destination string, progress ProgressReporter) error {
+	stats, err := os.Lstat(source)
+	if err != nil {
+		return err
+	}
+
+	if stats.Mode()&fs.ModeSymlink != 0 {
+		return CopyLink(source, destination, progress)
+	}
+
+	if stats.IsDir() {
+		return CopyDirectory(source, destination, progress)
+	}
+
+	_, err = CopyFile(source, destination, progress)
+	return err
+}
+
+// Moves a file or a folder to the exact destination path, which must not exist yet. A plain rename is tried first and,
+// if that is not possible (e.g. the destination is on another drive), the item is copied and the original is removed.
+func MovePath(source string, destination string, progress ProgressReporter) error {
+	if IsSubPath(source, destination) {
+		return errors.New("cannot move " + source + " into itself")
+	}
+
+	if DoesFileExist(destination) {
+		return errors.New(destination + " already exists")
+	}
+
+	err := os.Rename(source, destination)
+	if err == nil {
+		return nil
+	}
+
+	err = CopyPath(source, destination, progress)
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(source)
+}
 
-			if count > 0 {
-				result = name + " (" + strconv.Itoa(count) + ")" + extension
-			} else {
-				result = filename
+// Removes a file or a folder with everything inside of it, reporting every removed file
+func RemoveItem(fullPath string, progress ProgressReporter) error {
+	stats, err := os.Lstat(fullPath)
+	if err != nil {
+		return err
+	}
+
+	if stats.IsDir() {
+		items, err := os.ReadDir(fullPath)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			err = RemoveItem(path.Join(fullPath, item.Name()), progress)
+			if err != nil {
+				return err
 			}
 		}
+
+		return os.Remove(fullPath)
+	}
+
+	err = os.Remove(fullPath)
+	if err != nil {
+		return err
+	}
+
+	if progress != nil {
+		err = progress.AddBytes(stats.Size())
+		if err != nil {
+			return err
+		}
+
+		return progress.AddFile()
+	}
+
+	return nil
+}
+
+// Returns the amount of files and their total size in bytes. Folders are walked recursively.
+func MeasureItem(fullPath string) (files int64, bytes int64) {
+	stats, err := os.Lstat(fullPath)
+	if err != nil {
+		return
+	}
+
+	if !stats.IsDir() {
+		return 1, stats.Size()
+	}
+
+	items, err := os.ReadDir(fullPath)
+	if err != nil {
+		return
+	}
+
+	for _, item := range items {
+		f, b := MeasureItem(path.Join(fullPath, item.Name()))
+		files += f
+		bytes += b
 	}
 
 	return
 }
 
+// Splits the path into the names shown in the breadcrumbs. The root is kept as "/" or as the drive, e.g. "D:".
+func splitPath(fullPath string) []string {
+	if fullPath == "/" {
+		return []string{"/"}
+	}
+
+	split := strings.Split(strings.TrimSuffix(fullPath, "/"), "/")
+	if split[0] == "" {
+		split[0] = "/"
+	}
+
+	return split
+}
+
+// Same as path.Dir, except that the root of a drive keeps its slash (path.Dir returns "D:" for "D:/folder")
+func getParentPath(fullPath string) string {
+	dir := path.Dir(fullPath)
+	if strings.HasSuffix(dir, ":") {
+		dir += "/"
+	}
+
+	return dir
+}
+
+// Case insensitive file systems report that the new name exists when only the case of the name changes
+func isSameItem(a string, b string) bool {
+	aStats, err := os.Lstat(a)
+	if err != nil {
+		return false
+	}
+
+	bStats, err := os.Lstat(b)
+	if err != nil {
+		return false
+	}
+
+	return os.SameFile(aStats, bStats)
+}
+
+// Returns true if child is the same path as parent or is located somewhere inside of it
+func IsSubPath(parent string, child string) bool {
+	parent = path.Clean(parent)
+	child = path.Clean(child)
+
+	parent = normalizePathCase(parent)
+	child = normalizePathCase(child)
+
+	if parent == child {
+		return true
+	}
+
+	return strings.HasPrefix(child, strings.TrimSuffix(parent, "/")+"/")
+}
+
+func joinErrors(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	messages := make([]string, len(errs))
+	for index, err := range errs {
+		messages[index] = err.Error()
+	}
+
+	return errors.New(strings.Join(messages, "; "))
+}
+
+func GetAvailableFileName(dirName string, filename string) string {
+	return getAvailableName(dirName, filename, DoesFileExist)
+}
+
+// Adds a number to the name, e.g. "notes (2).txt", until exists says that no item with that name is in the folder
+func getAvailableName(dirName string, filename string, exists func(fullPath string) bool) string {
+	extension := path.Ext(filename)
+	name := strings.TrimSuffix(path.Base(filename), extension)
+
+	result := filename
+	for count := 1; exists(path.Join(dirName, result)); count++ {
+		result = name + " (" + strconv.Itoa(count) + ")" + extension
+	}
+
+	return result
+}
+
 func CreateNewFile(dirname string) (bool, string) {
 	name := GetAvailableFileName(dirname, "New File")
 
@@ -163,9 +489,15 @@ func DoesFileExist(fullPath string) bool {
 	return err == nil
 }
 
+// Links are followed, so a link to a folder is a folder. Only broken links are reported as links.
 func GetItemType(fullPath string) ItemType {
 	stat, err := os.Stat(fullPath)
 	if err != nil {
+		linkStat, linkErr := os.Lstat(fullPath)
+		if linkErr == nil && linkStat.Mode()&fs.ModeSymlink != 0 {
+			return ItemTypeLink
+		}
+
 		NotifyError(err.Error())
 		return ItemTypeFile
 	}
@@ -180,10 +512,6 @@ func GetItemType(fullPath string) ItemType {
 func GetFileType(filename string) FileType {
 	lowercase := strings.ToLower(filename)
 
-	if strings.HasSuffix(lowercase, ".exe") {
-		return FileTypeExe
-	}
-
 	imageExtensions := []string{".png", ".jpg", ".jpeg", ".bmp", ".gif", ".ico"}
 	for _, ext := range imageExtensions {
 		if strings.HasSuffix(lowercase, ext) {
@@ -200,31 +528,3 @@ func GetFileType(filename string) FileType {
 
 	return FileTypeDefault
 }
-
-func GetAvailableDrives() (result []string) {
-	for _, drive := range "ABCDEFGHIJKLMNOPQRSTUVWXYZ" {
-		file, err := os.Open(string(drive) + ":\\")
-		if err == nil {
-			result = append(result, string(drive))
-			file.Close()
-		}
-	}
-
-	return
-}
-
-func IsFileHidden(fullPath string) bool {
-	pointer, err := syscall.UTF16PtrFromString(fullPath)
-	if err != nil {
-		NotifyError(err.Error())
-		return false
-	}
-
-	attr, err := syscall.GetFileAttributes(pointer)
-	if err != nil {
-		NotifyError(err.Error())
-		return false
-	}
-
-	return attr&syscall.FILE_ATTRIBUTE_HIDDEN != 0
-}
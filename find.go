@@ -0,0 +1,316 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The recursive find walks the folder in a job and hands the matches over to the find view while it runs, so they
+// show up as soon as they are found. Names are matched by a glob, which matches anywhere in the name if it has no
+// wildcards, or by a regular expression written between slashes, e.g. /^IMG_\d+/. Both ignore the case. The filters
+// narrow the matches down by type, size and modification time. The folders named in the settings, such as .git and
+// node_modules, aren't entered.
+
+const maxFindResults = 10000
+
+type FindItemType int
+
+const (
+	FindAnyType FindItemType = iota
+	FindFiles
+	FindFolders
+	FindLinks
+)
+
+type FindQuery struct {
+	Glob  string         // Lower case, empty if the name is matched by Regex
+	Regex *regexp.Regexp // nil if the name is matched by Glob
+
+	Type    FindItemType
+	MinSize int64 // -1 if not set
+	MaxSize int64
+	After   time.Time // Zero if not set
+	Before  time.Time
+}
+
+type FindResult struct {
+	FullPath string
+	IsFolder bool
+	Size     int64
+}
+
+// Parses the name pattern and the filters, which are separated by spaces, e.g. "type:file size:>10M newer:7d"
+func ParseFindQuery(pattern string, filters string) (result FindQuery, err error) {
+	result.MinSize, result.MaxSize = -1, -1
+
+	pattern = strings.TrimSpace(pattern)
+	if len(pattern) > 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		result.Regex, err = regexp.Compile("(?i)" + pattern[1:len(pattern)-1])
+		if err != nil {
+			return result, errors.New("invalid regular expression " + pattern)
+		}
+	} else {
+		result.Glob = strings.ToLower(pattern)
+		if !strings.ContainsAny(result.Glob, "*?[") {
+			result.Glob = "*" + result.Glob + "*"
+		}
+
+		if _, err = path.Match(result.Glob, ""); err != nil {
+			return result, errors.New("invalid pattern " + pattern)
+		}
+	}
+
+	for _, filter := range strings.Fields(filters) {
+		name, value, _ := strings.Cut(filter, ":")
+
+		switch name {
+		case "type":
+			switch value {
+			case "file", "f":
+				result.Type = FindFiles
+			case "folder", "d":
+				result.Type = FindFolders
+			case "link", "l":
+				result.Type = FindLinks
+			default:
+				return result, errors.New("unknown type " + value + ", use file, folder or link")
+			}
+		case "size":
+			if len(value) < 2 || (value[0] != '<' && value[0] != '>') {
+				return result, errors.New("invalid size filter " + filter + ", use e.g. size:>10M or size:<1K")
+			}
+
+			size, ok := parseFindSize(value[1:])
+			if !ok {
+				return result, errors.New("invalid size " + value[1:])
+			}
+
+			if value[0] == '>' {
+				result.MinSize = size
+			} else {
+				result.MaxSize = size
+			}
+		case "newer", "older":
+			age, ok := parseFindAge(value)
+			if !ok {
+				return result, errors.New("invalid age " + value + ", use e.g. 12h, 7d or 4w")
+			}
+
+			if name == "newer" {
+				result.After = time.Now().Add(-age)
+			} else {
+				result.Before = time.Now().Add(-age)
+			}
+		case "after", "before":
+			date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+			if err != nil {
+				return result, errors.New("invalid date " + value + ", use e.g. 2024-01-31")
+			}
+
+			if name == "after" {
+				result.After = date
+			} else {
+				result.Before = date
+			}
+		default:
+			return result, errors.New("unknown filter " + filter + ", use type, size, newer, older, after or before")
+		}
+	}
+
+	return result, nil
+}
+
+// Parses sizes like "512", "1.5K" or "10M", in powers of 1024 like the sizes shown everywhere else
+func parseFindSize(value string) (int64, bool) {
+	value = strings.TrimSuffix(strings.ToUpper(value), "B")
+
+	multiplier := float64(1)
+	if index := strings.IndexAny(value, "KMGT"); index >= 0 && index == len(value)-1 {
+		multiplier = float64(int64(1) << (10 * (strings.IndexByte("KMGT", value[index]) + 1)))
+		value = value[:index]
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		return 0, false
+	}
+
+	return int64(number * multiplier), true
+}
+
+// Parses ages like "12h", "7d" or "4w"
+func parseFindAge(value string) (time.Duration, bool) {
+	if len(value) < 2 {
+		return 0, false
+	}
+
+	number, err := strconv.ParseFloat(value[:len(value)-1], 64)
+	if err != nil || number < 0 {
+		return 0, false
+	}
+
+	units := map[byte]time.Duration{'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	unit, ok := units[value[len(value)-1]]
+	if !ok {
+		return 0, false
+	}
+
+	return time.Duration(number * float64(unit)), true
+}
+
+func (q *FindQuery) matchesName(name string) bool {
+	if q.Regex != nil {
+		return q.Regex.MatchString(name)
+	}
+
+	matched, _ := path.Match(q.Glob, strings.ToLower(name))
+	return matched
+}
+
+// Only the filters on the size and the time need the stats, which are skipped otherwise to keep the walk fast
+func (q *FindQuery) needsStats() bool {
+	return q.MinSize >= 0 || q.MaxSize >= 0 || !q.After.IsZero() || !q.Before.IsZero()
+}
+
+func (q *FindQuery) matches(entry fs.DirEntry, stats fs.FileInfo) bool {
+	isLink := entry.Type()&fs.ModeSymlink != 0
+
+	switch q.Type {
+	case FindFiles:
+		if entry.IsDir() || isLink {
+			return false
+		}
+	case FindFolders:
+		if !entry.IsDir() {
+			return false
+		}
+	case FindLinks:
+		if !isLink {
+			return false
+		}
+	}
+
+	if !q.matchesName(entry.Name()) {
+		return false
+	}
+
+	if stats == nil {
+		return true
+	}
+
+	// Folders have no size of their own, so only files are found by size
+	if q.MinSize >= 0 || q.MaxSize >= 0 {
+		if entry.IsDir() || (q.MinSize >= 0 && stats.Size() < q.MinSize) || (q.MaxSize >= 0 && stats.Size() > q.MaxSize) {
+			return false
+		}
+	}
+
+	if (!q.After.IsZero() && stats.ModTime().Before(q.After)) || (!q.Before.IsZero() && !stats.ModTime().Before(q.Before)) {
+		return false
+	}
+
+	return true
+}
+
+// The matches found by the job that haven't been shown yet. The job adds them and the find view takes them every
+// frame.
+type FindResults struct {
+	mutex   sync.Mutex
+	pending []FindResult
+	count   int
+}
+
+// Returns false once there are as many results as can be shown
+func (r *FindResults) add(result FindResult) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.pending = append(r.pending, result)
+	r.count++
+
+	return r.count < maxFindResults
+}
+
+func (r *FindResults) Take() (result []FindResult) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	result = r.pending
+	r.pending = nil
+
+	return
+}
+
+// Finds the items in the folder and in the folders inside of it that match the query. Links to folders aren't
+// followed, so that the walk can't go around in circles.
+func FindItems(fullPath string, query FindQuery, ignored []string, results *FindResults, job *Job) error {
+	_, err := findInFolder(fullPath, query, ignored, results, job)
+	return err
+}
+
+// Returns false once the search should stop because there are enough results
+func findInFolder(fullPath string, query FindQuery, ignored []string, results *FindResults, job *Job) (bool, error) {
+	// Folders that can't be read are skipped, the rest of the tree is still searched
+	entries, _ := os.ReadDir(fullPath)
+
+	for _, entry := range entries {
+		err := job.Checkpoint()
+		if err != nil {
+			return false, err
+		}
+
+		job.AddFile()
+
+		if entry.IsDir() && isFindIgnored(entry.Name(), ignored) {
+			continue
+		}
+
+		entryPath := path.Join(fullPath, entry.Name())
+
+		var stats fs.FileInfo
+		if query.needsStats() {
+			stats, err = entry.Info()
+			if err != nil {
+				continue
+			}
+		}
+
+		if query.matches(entry, stats) {
+			result := FindResult{FullPath: entryPath, IsFolder: entry.IsDir()}
+			if stats != nil {
+				result.Size = stats.Size()
+			}
+
+			if !results.add(result) {
+				return false, nil
+			}
+		}
+
+		if entry.IsDir() {
+			more, err := findInFolder(entryPath, query, ignored, results, job)
+			if !more || err != nil {
+				return more, err
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// The ignored folders are names or patterns of names, e.g. "node_modules" or "*.cache"
+func isFindIgnored(name string, ignored []string) bool {
+	for _, pattern := range ignored {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Finds items by name in the folder of the active view and in every folder inside of it, see find.go. The matches
+// are listed while the search runs, Enter on one of them shows it in the active view. The search is kept when the
+// view is closed, so that the next match can be picked by opening it again.
+
+type FindView struct {
+	IsOpen      bool
+	Root        string
+	Fields      [2]*InputField // Name, Filters
+	ActiveField int
+	Results     []FindResult
+	List        ListView
+	Error       string
+
+	searchJob *Job         // The search that is running, nil if there's none
+	pending   *FindResults // The matches of the running search that aren't in Results yet
+	query     []string     // The pattern and the filters the results were found with, nil before the first search
+
+	MaxWidth     int32
+	Padding      int32
+	HeaderHeight int32
+	FieldHeight  int32
+	LabelWidth   int32
+	LineHeight   int32
+}
+
+func NewFindView() *FindView {
+	result := &FindView{
+		List:         *NewListView(),
+		MaxWidth:     800,
+		Padding:      8,
+		HeaderHeight: 28,
+		FieldHeight:  40,
+		LabelWidth:   90,
+		LineHeight:   24,
+	}
+
+	result.List.ItemHeight = 24
+
+	for i := range result.Fields {
+		result.Fields[i] = NewInputField(sdl.Rect{H: result.FieldHeight}, nil)
+	}
+
+	return result
+}
+
+// Opens the view for the folder. The results are kept if it's the folder that was searched last.
+func (f *FindView) Open(root string) {
+	f.IsOpen = true
+	f.ActiveField = 0
+
+	if f.Root == root {
+		return
+	}
+
+	f.cancel()
+	f.Root = root
+	f.Results = nil
+	f.query = nil
+	f.Error = ""
+	f.List.ActiveItem = 0
+	f.updateList()
+}
+
+func (f *FindView) Close() {
+	f.IsOpen = false
+}
+
+func (f *FindView) cancel() {
+	if f.searchJob != nil {
+		f.searchJob.Cancel()
+		f.searchJob = nil
+		f.pending = nil
+	}
+}
+
+func (f *FindView) getQuery() []string {
+	return []string{f.Fields[0].Value.String(), f.Fields[1].Value.String()}
+}
+
+func (f *FindView) search(app *App) {
+	f.cancel()
+
+	f.Error = ""
+	f.Results = nil
+	f.List.ActiveItem = 0
+	f.query = f.getQuery()
+	f.updateList()
+
+	query, err := ParseFindQuery(f.query[0], f.query[1])
+	if err != nil {
+		f.Error = err.Error()
+		return
+	}
+
+	root := f.Root
+	ignored := app.Settings.FindIgnored
+	pending := &FindResults{}
+
+	var searchJob *Job
+	searchJob = app.Jobs.Add("Finding "+f.query[0]+" in "+root, func(job *Job) error {
+		return FindItems(root, query, ignored, pending, job)
+	}, func(err error) {
+		// Another search was started in the meantime
+		if f.searchJob != searchJob {
+			return
+		}
+
+		f.Poll()
+		f.searchJob = nil
+		f.pending = nil
+
+		if err != nil {
+			f.Error = err.Error()
+		}
+	})
+
+	f.searchJob = searchJob
+	f.pending = pending
+}
+
+// Shows the matches found since the last frame
+func (f *FindView) Poll() {
+	if f.pending == nil {
+		return
+	}
+
+	results := f.pending.Take()
+	if len(results) == 0 {
+		return
+	}
+
+	f.Results = append(f.Results, results...)
+	f.updateList()
+}
+
+func (f *FindView) updateList() {
+	items := make([]ListItem, len(f.Results))
+	for index, result := range f.Results {
+		text := getRelativePath(f.Root, result.FullPath)
+		if result.IsFolder {
+			text += "/"
+		} else if result.Size > 0 {
+			text += "  (" + bytesToString(result.Size) + ")"
+		}
+
+		items[index] = ListItem{Text: text}
+	}
+
+	f.List.SetItems(items)
+}
+
+// Shows the active match in the active view
+func (f *FindView) openActive(app *App) {
+	if !f.List.HasActive() {
+		return
+	}
+
+	result := f.Results[f.List.ActiveItem]
+	f.Close()
+
+	// Hidden items are shown, otherwise the match couldn't be made active
+	view := app.ItemViews[app.ActiveView]
+	if !view.ShowHidden && IsFileHidden(result.FullPath) {
+		view.ShowHidden = true
+	}
+
+	if app.GoToPath(getParentPath(result.FullPath)) {
+		app.ItemViews[app.ActiveView].SetActiveByName(path.Base(result.FullPath))
+	}
+}
+
+func (f *FindView) Tick(input *Input, app *App) {
+	if input.Escape {
+		f.Close()
+		return
+	}
+
+	switch {
+	case input.TypedCharacter == '\t':
+		f.ActiveField = (f.ActiveField + 1) % len(f.Fields)
+	case input.TypedCharacter == '\n':
+		// Enter searches again if the pattern or the filters changed, the results can be opened while it runs
+		if f.query == nil || strings.Join(f.query, "\n") != strings.Join(f.getQuery(), "\n") {
+			f.search(app)
+		} else {
+			f.openActive(app)
+		}
+	case input.Up:
+		if f.List.ActiveItem > 0 {
+			f.List.ActiveItem--
+		}
+	case input.Down:
+		if f.List.ActiveItem < int32(len(f.List.Items))-1 {
+			f.List.ActiveItem++
+		}
+	default:
+		f.Fields[f.ActiveField].Tick(input)
+	}
+}
+
+func (f *FindView) Render(renderer *sdl.Renderer, parentRect *sdl.Rect, app *App) {
+	theme := app.Theme.PanelTheme
+
+	width := f.MaxWidth
+	if width > parentRect.W-20 {
+		width = parentRect.W - 20
+	}
+
+	rect := sdl.Rect{
+		X: parentRect.X + (parentRect.W-width)/2,
+		Y: parentRect.Y + 40,
+		W: width,
+		H: parentRect.H - 80,
+	}
+
+	title := "Find in " + f.Root + " (Tab to switch fields, Enter to search or to show the match, Esc to close)"
+	insetRect := DrawPanel(renderer, &rect, title, f.HeaderHeight, f.Padding, &app.Font, theme)
+
+	y := insetRect.Y
+	labels := []string{"Name", "Filters"}
+	for index, field := range f.Fields {
+		color := GetColor(theme, "secondary_text_color")
+		if index == f.ActiveField {
+			color = GetColor(theme, "header_color")
+		}
+
+		labelRect := sdl.Rect{X: insetRect.X + f.Padding, Y: y, W: f.LabelWidth, H: f.FieldHeight}
+		DrawTextInRect(renderer, &app.Font, labels[index], &labelRect, color)
+
+		field.Rect.W = insetRect.W - f.LabelWidth - f.Padding
+		field.Render(renderer, insetRect.X+f.LabelWidth, y, &app.Font, app.Theme.InputFieldTheme)
+
+		y += f.FieldHeight
+	}
+
+	summary := "A glob like *.go or a /regular expression/, filters like type:file size:>10M newer:7d before:2024-01-31"
+	summaryColor := GetColor(theme, "secondary_text_color")
+	switch {
+	case f.Error != "":
+		summary = f.Error
+		summaryColor = GetColor(theme, "error_color")
+	case f.searchJob != nil:
+		summary = fmt.Sprintf("Searching... %s so far", formatCount(int64(len(f.Results)), "match", "matches"))
+	case len(f.Results) >= maxFindResults:
+		summary = fmt.Sprintf("Showing the first %d matches, narrow the search down to see the rest", maxFindResults)
+	case f.query != nil:
+		summary = formatCount(int64(len(f.Results)), "match", "matches")
+	}
+
+	summaryRect := sdl.Rect{X: insetRect.X + f.Padding, Y: y, W: insetRect.W - f.Padding*2, H: f.LineHeight}
+	DrawTextInRect(renderer, &app.Font, summary, &summaryRect, summaryColor)
+	y += f.LineHeight
+
+	emptyText := "Nothing found"
+	if f.searchJob != nil {
+		emptyText = "Searching..."
+	} else if f.query == nil {
+		emptyText = ""
+	}
+
+	listRect := sdl.Rect{X: insetRect.X, Y: y, W: insetRect.W, H: insetRect.Y + insetRect.H - y}
+	f.List.Render(renderer, &listRect, &app.Font, theme, emptyText)
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// MP3 files start with an ID3v2 tag, followed by the MPEG frames, and sometimes end with an ID3v1 tag. The duration
+// is the frame count from the Xing or VBRI header of the first frame for files with a variable bitrate, and is
+// worked out from the bitrate for the rest.
+
+// The names the frames are shown with. ID3v2.2 has three letter names.
+var id3FrameTags = map[string]string{
+	"TIT2": "Title", "TT2": "Title",
+	"TPE1": "Artist", "TP1": "Artist",
+	"TALB": "Album", "TAL": "Album",
+	"TPE2": "Album artist", "TP2": "Album artist",
+	"TDRC": "Year", "TYER": "Year", "TYE": "Year",
+	"TRCK": "Track", "TRK": "Track",
+	"TCON": "Genre", "TCO": "Genre",
+	"TCOM": "Composer", "TCM": "Composer",
+	"COMM": "Comment", "COM": "Comment",
+}
+
+// The genres of ID3v1, which ID3v2 refers to by their number too, e.g. "(17)"
+var id3Genres = []string{
+	"Blues", "Classic Rock", "Country", "Dance", "Disco", "Funk", "Grunge", "Hip-Hop", "Jazz", "Metal", "New Age",
+	"Oldies", "Other", "Pop", "R&B", "Rap", "Reggae", "Rock", "Techno", "Industrial", "Alternative", "Ska",
+	"Death Metal", "Pranks", "Soundtrack", "Euro-Techno", "Ambient", "Trip-Hop", "Vocal", "Jazz+Funk", "Fusion",
+	"Trance", "Classical", "Instrumental", "Acid", "House", "Game", "Sound Clip", "Gospel", "Noise", "Alternative Rock",
+	"Bass", "Soul", "Punk", "Space", "Meditative", "Instrumental Pop", "Instrumental Rock", "Ethnic", "Gothic",
+	"Darkwave", "Techno-Industrial", "Electronic", "Pop-Folk", "Eurodance", "Dream", "Southern Rock", "Comedy", "Cult",
+	"Gangsta", "Top 40", "Christian Rap", "Pop/Funk", "Jungle", "Native American", "Cabaret", "New Wave", "Psychedelic",
+	"Rave", "Showtunes", "Trailer", "Lo-Fi", "Tribal", "Acid Punk", "Acid Jazz", "Polka", "Retro", "Musical",
+	"Rock & Roll", "Hard Rock",
+}
+
+// In kbps, by the version and the layer, 0 is free and the last index is invalid
+var mpegBitrates = map[[2]int][16]int{
+	{1, 1}: {0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0},
+	{1, 2}: {0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0},
+	{1, 3}: {0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+	{2, 1}: {0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0},
+	{2, 2}: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+	{2, 3}: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+}
+
+var mpegSampleRates = map[int][3]int{
+	1: {44100, 48000, 32000},
+	2: {22050, 24000, 16000},
+	3: {11025, 12000, 8000}, // MPEG-2.5
+}
+
+type mpegFrameHeader struct {
+	Version    int // 3 for MPEG-2.5
+	Layer      int
+	Bitrate    int // In kbps
+	SampleRate int
+	Channels   int
+}
+
+func (h *mpegFrameHeader) samplesPerFrame() int {
+	switch {
+	case h.Layer == 1:
+		return 384
+	case h.Layer == 3 && h.Version != 1:
+		return 576
+	}
+
+	return 1152
+}
+
+func parseMPEGFrameHeader(data []byte) (result mpegFrameHeader, ok bool) {
+	if len(data) < 4 || data[0] != 0xFF || data[1]&0xE0 != 0xE0 {
+		return result, false
+	}
+
+	switch data[1] >> 3 & 3 {
+	case 0:
+		result.Version = 3
+	case 2:
+		result.Version = 2
+	case 3:
+		result.Version = 1
+	default:
+		return result, false
+	}
+
+	result.Layer = 4 - int(data[1]>>1&3)
+	bitrateIndex := int(data[2] >> 4)
+	sampleRateIndex := int(data[2] >> 2 & 3)
+	if result.Layer == 4 || bitrateIndex == 15 || sampleRateIndex == 3 {
+		return result, false
+	}
+
+	// MPEG-2.5 has the same bitrates as MPEG-2
+	bitrateVersion := min(result.Version, 2)
+	result.Bitrate = mpegBitrates[[2]int{bitrateVersion, result.Layer}][bitrateIndex]
+	result.SampleRate = mpegSampleRates[result.Version][sampleRateIndex]
+
+	result.Channels = 2
+	if data[3]>>6 == 3 {
+		result.Channels = 1
+	}
+
+	return result, true
+}
+
+func readMP3(file *os.File, size int64, result *MediaInfo) error {
+	audioStart, err := readID3v2(file, result)
+	if err != nil {
+		return err
+	}
+
+	// Some files have padding or garbage before the first frame
+	buffer := make([]byte, 64*1024)
+	read, err := file.ReadAt(buffer, audioStart)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	buffer = buffer[:read]
+
+	frameStart := -1
+	var header mpegFrameHeader
+	for index := 0; index+4 <= len(buffer); index++ {
+		var ok bool
+		header, ok = parseMPEGFrameHeader(buffer[index:])
+		if ok {
+			frameStart = index
+			break
+		}
+	}
+
+	if frameStart < 0 {
+		return errors.New("no MPEG audio frame found")
+	}
+
+	result.SampleRate = header.SampleRate
+	result.Channels = header.Channels
+	if header.Layer == 3 {
+		result.addCodec("MP3")
+	} else {
+		result.addCodec(fmt.Sprintf("MPEG-%d Layer %d", min(header.Version, 2), header.Layer))
+	}
+
+	audioEnd := size
+	if hasID3v1(file, size) {
+		audioEnd -= 128
+	}
+	audioBytes := audioEnd - audioStart - int64(frameStart)
+
+	frame := buffer[frameStart:]
+	frames := readVBRFrameCount(frame, &header)
+	if frames > 0 {
+		seconds := float64(frames) * float64(header.samplesPerFrame()) / float64(header.SampleRate)
+		result.Duration = time.Duration(seconds * float64(time.Second))
+		result.Bitrate = int64(float64(audioBytes*8) / seconds)
+	} else if header.Bitrate > 0 {
+		result.Bitrate = int64(header.Bitrate) * 1000
+		result.Duration = time.Duration(float64(audioBytes*8) / float64(result.Bitrate) * float64(time.Second))
+	}
+
+	readID3v1(file, size, result)
+	return nil
+}
+
+// Returns the frame count from the Xing (or Info) header, or from the VBRI header, of the first frame. Returns 0 if
+// the file has a constant bitrate without such a header.
+func readVBRFrameCount(frame []byte, header *mpegFrameHeader) uint32 {
+	// The Xing header comes after the side information, whose size depends on the version and the channels
+	sideInfo := 17
+	if header.Version == 1 && header.Channels == 2 {
+		sideInfo = 32
+	} else if header.Version != 1 && header.Channels == 1 {
+		sideInfo = 9
+	}
+
+	xing := 4 + sideInfo
+	if len(frame) >= xing+12 && (string(frame[xing:xing+4]) == "Xing" || string(frame[xing:xing+4]) == "Info") {
+		flags := binary.BigEndian.Uint32(frame[xing+4:])
+		if flags&1 != 0 {
+			return binary.BigEndian.Uint32(frame[xing+8:])
+		}
+	}
+
+	vbri := 4 + 32
+	if len(frame) >= vbri+18 && string(frame[vbri:vbri+4]) == "VBRI" {
+		return binary.BigEndian.Uint32(frame[vbri+14:])
+	}
+
+	return 0
+}
+
+// Reads the frames of the ID3v2 tag at the start of the file. Returns where the audio starts, which is 0 if the
+// file has no tag.
+func readID3v2(file *os.File, result *MediaInfo) (int64, error) {
+	header := make([]byte, 10)
+	_, err := file.ReadAt(header, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	if string(header[:3]) != "ID3" {
+		return 0, nil
+	}
+
+	version := header[3]
+	flags := header[5]
+	tagSize := int64(readSyncsafe(header[6:]))
+
+	audioStart := 10 + tagSize
+	if flags&0x10 != 0 {
+		// The footer repeats the header at the end of the tag
+		audioStart += 10
+	}
+
+	// The frames are what's needed, large frames such as pictures are skipped without reading them
+	offset := int64(10)
+	if flags&0x40 != 0 && version >= 3 {
+		extended := make([]byte, 4)
+		_, err = file.ReadAt(extended, offset)
+		if err != nil {
+			return audioStart, nil
+		}
+
+		if version == 4 {
+			offset += int64(readSyncsafe(extended))
+		} else {
+			offset += 4 + int64(binary.BigEndian.Uint32(extended))
+		}
+	}
+
+	idSize, headerSize := 4, 10
+	if version == 2 {
+		idSize, headerSize = 3, 6
+	}
+
+	frameHeader := make([]byte, headerSize)
+	for offset+int64(headerSize) <= 10+tagSize {
+		_, err = file.ReadAt(frameHeader, offset)
+		if err != nil {
+			break
+		}
+
+		// Padding fills the rest of the tag
+		if frameHeader[0] == 0 {
+			break
+		}
+
+		id := string(frameHeader[:idSize])
+
+		var frameSize int64
+		switch version {
+		case 2:
+			frameSize = int64(frameHeader[3])<<16 | int64(frameHeader[4])<<8 | int64(frameHeader[5])
+		case 3:
+			frameSize = int64(binary.BigEndian.Uint32(frameHeader[4:]))
+		default:
+			frameSize = int64(readSyncsafe(frameHeader[4:]))
+		}
+
+		offset += int64(headerSize)
+
+		name, ok := id3FrameTags[id]
+		if ok && frameSize > 0 && frameSize < 64*1024 {
+			data := make([]byte, frameSize)
+			_, err = file.ReadAt(data, offset)
+			if err != nil {
+				break
+			}
+
+			if strings.HasPrefix(id, "COM") {
+				result.addTag(name, decodeID3Comment(data))
+			} else if name == "Genre" {
+				result.addTag(name, decodeID3Genre(decodeID3Text(data)))
+			} else {
+				result.addTag(name, decodeID3Text(data))
+			}
+		}
+
+		offset += frameSize
+	}
+
+	return audioStart, nil
+}
+
+// Syncsafe integers leave out the highest bit of every byte, so that they can't look like the sync bits of a frame
+func readSyncsafe(data []byte) uint32 {
+	return uint32(data[0]&0x7F)<<21 | uint32(data[1]&0x7F)<<14 | uint32(data[2]&0x7F)<<7 | uint32(data[3]&0x7F)
+}
+
+// The first byte of text frames is the encoding. ID3v2.4 separates multiple values with zero bytes.
+func decodeID3Text(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+
+	values := decodeID3String(data[0], data[1:])
+	return strings.Join(strings.FieldsFunc(values, func(r rune) bool { return r == 0 }), ", ")
+}
+
+// Comments have a language and a short description before the text
+func decodeID3Comment(data []byte) string {
+	if len(data) < 4 {
+		return ""
+	}
+
+	encoding := data[0]
+	text := data[4:]
+
+	// The description ends with a zero, which takes two bytes in UTF-16
+	terminator := []byte{0}
+	if encoding == 1 || encoding == 2 {
+		terminator = []byte{0, 0}
+	}
+
+	for index := 0; index+len(terminator) <= len(text); index += len(terminator) {
+		if bytes.Equal(text[index:index+len(terminator)], terminator) {
+			return decodeID3String(encoding, text[index+len(terminator):])
+		}
+	}
+
+	return ""
+}
+
+func decodeID3String(encoding byte, data []byte) string {
+	switch encoding {
+	case 0:
+		// Latin-1 maps directly to the first 256 code points
+		runes := make([]rune, len(data))
+		for index, value := range data {
+			runes[index] = rune(value)
+		}
+
+		return string(runes)
+	case 1, 2:
+		var order binary.ByteOrder = binary.BigEndian
+		if encoding == 1 && len(data) >= 2 && data[0] == 0xFF && data[1] == 0xFE {
+			order = binary.LittleEndian
+		}
+
+		units := make([]uint16, 0, len(data)/2)
+		for index := 0; index+1 < len(data); index += 2 {
+			unit := order.Uint16(data[index:])
+
+			// The byte order mark can be repeated before every value
+			if unit != 0xFEFF {
+				units = append(units, unit)
+			}
+		}
+
+		return string(utf16.Decode(units))
+	}
+
+	return string(data)
+}
+
+// Genres are either names, or numbers of ID3v1 genres, written as "17" or "(17)", optionally followed by a name
+func decodeID3Genre(value string) string {
+	number := strings.TrimPrefix(value, "(")
+	if end := strings.Index(number, ")"); end >= 0 {
+		if rest := number[end+1:]; rest != "" {
+			return rest
+		}
+
+		number = number[:end]
+	}
+
+	index, err := strconv.Atoi(number)
+	if err != nil || index < 0 || index >= len(id3Genres) {
+		return value
+	}
+
+	return id3Genres[index]
+}
+
+func hasID3v1(file *os.File, size int64) bool {
+	marker := make([]byte, 3)
+	_, err := file.ReadAt(marker, size-128)
+	return err == nil && string(marker) == "TAG"
+}
+
+// The ID3v1 tag has fields of fixed sizes at the end of the file. Its values are only used for the tags that the
+// ID3v2 tag doesn't have.
+func readID3v1(file *os.File, size int64, result *MediaInfo) {
+	if !hasID3v1(file, size) {
+		return
+	}
+
+	data := make([]byte, 128)
+	_, err := file.ReadAt(data, size-128)
+	if err != nil {
+		return
+	}
+
+	result.addTag("Title", decodeID3String(0, data[3:33]))
+	result.addTag("Artist", decodeID3String(0, data[33:63]))
+	result.addTag("Album", decodeID3String(0, data[63:93]))
+	result.addTag("Year", decodeID3String(0, data[93:97]))
+
+	// ID3v1.1 took the last two bytes of the comment for the track number
+	if data[125] == 0 && data[126] != 0 {
+		result.addTag("Comment", decodeID3String(0, data[97:125]))
+		result.addTag("Track", strconv.Itoa(int(data[126])))
+	} else {
+		result.addTag("Comment", decodeID3String(0, data[97:127]))
+	}
+
+	if int(data[127]) < len(id3Genres) {
+		result.addTag("Genre", id3Genres[data[127]])
+	}
+}
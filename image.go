@@ -35,6 +35,36 @@ func LoadImage(path string, renderer *sdl.Renderer) (result Image) {
 	return
 }
 
+func LoadImageFromMemory(data []byte, renderer *sdl.Renderer) (result Image) {
+	src, err := sdl.RWFromMem(data)
+	if err != nil {
+		NotifyError(err.Error())
+		return
+	}
+
+	image, err := img.LoadRW(src, true)
+	if err != nil {
+		NotifyError(err.Error())
+		return
+	}
+
+	texture, err := renderer.CreateTextureFromSurface(image)
+	if err != nil {
+		NotifyError(err.Error())
+		return
+	}
+
+	result = Image{
+		Data:   texture,
+		Width:  image.W,
+		Height: image.H,
+	}
+
+	image.Free()
+
+	return
+}
+
 func LoadIcon(path string) *sdl.Surface {
 	image, err := img.Load(path)
 	if err != nil {
@@ -1,35 +1,64 @@
 package main
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/veandco/go-sdl2/sdl"
 )
 
+type InfoRow struct {
+	Label string
+	Value string
+}
+
+// The rows depend on what the item is, e.g. folders have the count of their items and text files have their line
+// count, so the view grows to fit them
 type Info struct {
-	Name     string
-	Size     string
-	Created  string
-	Modified string
+	Name string
+	Rows []InfoRow
+
+	Checksums []FileChecksums // Set when the checksums were asked for, for one or more files
+}
+
+// Adds a row, unless the value isn't known
+func (i *Info) Add(label string, value string) {
+	if value != "" {
+		i.Rows = append(i.Rows, InfoRow{label, value})
+	}
+}
+
+// Changes the value of a row that is calculated in another thread
+func (i *Info) Set(label string, value string) {
+	for index := range i.Rows {
+		if i.Rows[index].Label == label {
+			i.Rows[index].Value = value
+			return
+		}
+	}
 }
 
 type InfoView struct {
 	IsOpen bool
 	Info
 
-	MaxWidth     int32
-	Padding      int32
-	ItemPadding  int32
-	HeaderHeight int32
-	ItemHeight   int32
+	MaxWidth      int32 // The view is made wider up to ExpandedWidth if the rows don't fit
+	ExpandedWidth int32
+	Padding       int32
+	ItemPadding   int32
+	HeaderHeight  int32
+	ItemHeight    int32
 }
 
 func NewInfoView() *InfoView {
 	return &InfoView{
-		IsOpen:       false,
-		MaxWidth:     300,
-		Padding:      8,
-		ItemPadding:  5,
-		HeaderHeight: 28,
-		ItemHeight:   24,
+		IsOpen:        false,
+		MaxWidth:      320,
+		ExpandedWidth: 640,
+		Padding:       8,
+		ItemPadding:   5,
+		HeaderHeight:  28,
+		ItemHeight:    24,
 	}
 }
 
@@ -45,21 +74,123 @@ func (i *InfoView) Close() {
 func (i *InfoView) Tick(input *Input) {
 	if input.Escape {
 		i.Close()
+		return
+	}
+
+	// Alt and a number copies the checksums of that algorithm
+	if input.Alt && len(i.Checksums) > 0 && input.TypedCharacter >= '1' && input.TypedCharacter < '1'+byte(len(checksumAlgorithms)) {
+		i.copyChecksums(int(input.TypedCharacter - '1'))
+	}
+}
+
+func (i *InfoView) copyChecksums(algorithm int) {
+	computed := false
+	for _, file := range i.Checksums {
+		computed = computed || file.Digests != nil
+	}
+
+	if !computed {
+		return
+	}
+
+	err := sdl.SetClipboardText(FormatChecksums(i.Checksums, algorithm))
+	if err != nil {
+		NotifyError(err.Error())
+		return
+	}
+
+	NotifyInfo("Copied the " + checksumAlgorithms[algorithm].Name + " checksums to the clipboard")
+}
+
+// Sets the checksums once they are computed, unless the info view shows something else by then
+func (i *InfoView) SetChecksums(name string, checksums []FileChecksums) {
+	if i.Name == name && i.Checksums != nil {
+		i.Checksums = checksums
 	}
 }
 
+// Sets a row that was calculated in another thread, unless the info view shows something else by then
+func (i *InfoView) SetRow(name string, label string, value string) {
+	if i.Name == name {
+		i.Info.Set(label, value)
+	}
+}
+
+func (i *Info) rows() []InfoRow {
+	result := append([]InfoRow{}, i.Rows...)
+
+	if len(i.Checksums) == 1 {
+		file := i.Checksums[0]
+		for index, algorithm := range checksumAlgorithms {
+			result = append(result, InfoRow{algorithm.Name, file.getDigest(index)})
+		}
+	} else {
+		// Only SHA-256 fits next to the names, the rest can still be copied
+		for _, file := range i.Checksums {
+			result = append(result, InfoRow{file.Name, file.getDigest(2)})
+		}
+	}
+
+	if len(i.Checksums) > 0 {
+		keys := make([]string, len(checksumAlgorithms))
+		for index, algorithm := range checksumAlgorithms {
+			keys[index] = fmt.Sprintf("Alt+%d %s", index+1, algorithm.Name)
+		}
+
+		result = append(result, InfoRow{"Copy", strings.Join(keys, ", ")})
+	}
+
+	return result
+}
+
+func (f *FileChecksums) getDigest(algorithm int) string {
+	if f.Err != "" {
+		return f.Err
+	}
+
+	if f.Digests == nil {
+		return "Calculating..."
+	}
+
+	return f.Digests[algorithm]
+}
+
 func (i *InfoView) Render(renderer *sdl.Renderer, parentRect *sdl.Rect, app *App) {
 	theme := app.Theme.InfoViewTheme
+	rows := i.Info.rows()
+
+	// Rows that don't fit in the window are left out
+	maxRows := (parentRect.H - 20 - i.HeaderHeight - i.Padding*2) / i.ItemHeight
+	if int32(len(rows)) > maxRows {
+		rows = rows[:max(maxRows, 0)]
+	}
+	rowCount := int32(len(rows))
+
+	width := i.MaxWidth
+	for _, row := range rows {
+		rowWidth := app.Font.GetStringWidth(row.Label) + app.Font.GetStringWidth(row.Value) + i.ItemPadding*3 + i.Padding*2
+		if rowWidth > width {
+			width = rowWidth
+		}
+	}
+
+	if width > i.ExpandedWidth {
+		width = i.ExpandedWidth
+	}
+
+	if width > parentRect.W-20 {
+		width = parentRect.W - 20
+	}
 
 	headerRect := sdl.Rect{
-		X: parentRect.X + parentRect.W - 10 - i.MaxWidth,
-		Y: parentRect.Y + parentRect.H - 10 - i.HeaderHeight - i.ItemHeight*3 - i.Padding*2,
-		W: i.MaxWidth,
+		X: parentRect.X + parentRect.W - 10 - width,
+		Y: parentRect.Y + parentRect.H - 10 - i.HeaderHeight - i.ItemHeight*rowCount - i.Padding*2,
+		W: width,
 		H: i.HeaderHeight,
 	}
 	DrawRect3D(renderer, &headerRect, GetColor(theme, "background_color"))
 
-	clippedName := app.Font.ClipString(i.Name, i.MaxWidth-i.Padding*2)
+	clippedName := app.Font.ClipString(i.Name, width-i.Padding*2)
 
 	nameWidth := app.Font.GetStringWidth(clippedName)
 	nameRect := sdl.Rect{
@@ -71,10 +202,10 @@ func (i *InfoView) Render(renderer *sdl.Renderer, parentRect *sdl.Rect, app *App
 	DrawText(renderer, &app.Font, clippedName, &nameRect, GetColor(theme, "header_color"))
 
 	baseRect := sdl.Rect{
-		X: parentRect.X + parentRect.W - 10 - i.MaxWidth,
+		X: parentRect.X + parentRect.W - 10 - width,
 		Y: headerRect.Y + headerRect.H,
-		W: i.MaxWidth,
-		H: i.ItemHeight*3 + i.Padding*2,
+		W: width,
+		H: i.ItemHeight*rowCount + i.Padding*2,
 	}
 	insetRect := sdl.Rect{
 		X: baseRect.X + i.Padding,
@@ -85,54 +216,32 @@ func (i *InfoView) Render(renderer *sdl.Renderer, parentRect *sdl.Rect, app *App
 	DrawRect3D(renderer, &baseRect, GetColor(theme, "background_color"))
 	DrawRect3DInset(renderer, &insetRect, GetColor(theme, "inset_color"))
 
-	sizePropWidth := app.Font.GetStringWidth("Size")
-	sizeWidth := app.Font.GetStringWidth(i.Info.Size)
-	sizePropRect := sdl.Rect{
-		X: insetRect.X + i.ItemPadding,
-		Y: insetRect.Y + (i.ItemHeight-app.Font.Size)/2,
-		W: sizePropWidth,
-		H: app.Font.Size,
-	}
-	sizeValueRect := sdl.Rect{
-		X: insetRect.X + insetRect.W - i.ItemPadding - sizeWidth,
-		Y: insetRect.Y + (i.ItemHeight-app.Font.Size)/2,
-		W: sizeWidth,
-		H: app.Font.Size,
-	}
-	DrawText(renderer, &app.Font, "Size", &sizePropRect, GetColor(theme, "info_color"))
-	DrawText(renderer, &app.Font, i.Info.Size, &sizeValueRect, GetColor(theme, "info_color"))
-
-	createPropoWidth := app.Font.GetStringWidth("Created")
-	createWidth := app.Font.GetStringWidth(i.Info.Created)
-	createPropRect := sdl.Rect{
-		X: insetRect.X + i.ItemPadding,
-		Y: insetRect.Y + i.ItemHeight*2 + (i.ItemHeight-app.Font.Size)/2,
-		W: createPropoWidth,
-		H: app.Font.Size,
-	}
-	createValueRect := sdl.Rect{
-		X: insetRect.X + insetRect.W - i.ItemPadding - createWidth,
-		Y: insetRect.Y + i.ItemHeight*2 + (i.ItemHeight-app.Font.Size)/2,
-		W: createWidth,
-		H: app.Font.Size,
-	}
-	DrawText(renderer, &app.Font, "Created", &createPropRect, GetColor(theme, "info_color"))
-	DrawText(renderer, &app.Font, i.Info.Modified, &createValueRect, GetColor(theme, "info_color"))
-
-	modPropWidth := app.Font.GetStringWidth("Modified")
-	modWidth := app.Font.GetStringWidth(i.Info.Modified)
-	modPropRect := sdl.Rect{
-		X: insetRect.X + i.ItemPadding,
-		Y: insetRect.Y + i.ItemHeight + (i.ItemHeight-app.Font.Size)/2,
-		W: modPropWidth,
-		H: app.Font.Size,
-	}
-	modValueRect := sdl.Rect{
-		X: insetRect.X + insetRect.W - i.ItemPadding - modWidth,
-		Y: insetRect.Y + i.ItemHeight + (i.ItemHeight-app.Font.Size)/2,
-		W: modWidth,
-		H: app.Font.Size,
+	for index, row := range rows {
+		// The names of the files are used as labels when showing the checksums of multiple files
+		label := app.Font.ClipString(row.Label, insetRect.W/3)
+
+		propWidth := app.Font.GetStringWidth(label)
+		propRect := sdl.Rect{
+			X: insetRect.X + i.ItemPadding,
+			Y: insetRect.Y + i.ItemHeight*int32(index) + (i.ItemHeight-app.Font.Size)/2,
+			W: propWidth,
+			H: app.Font.Size,
+		}
+		DrawText(renderer, &app.Font, label, &propRect, GetColor(theme, "info_color"))
+
+		// Long values, such as link targets, are clipped to the space next to the label
+		value := app.Font.ClipString(row.Value, insetRect.W-i.ItemPadding*3-propWidth)
+		if value == "" {
+			continue
+		}
+
+		valueWidth := app.Font.GetStringWidth(value)
+		valueRect := sdl.Rect{
+			X: insetRect.X + insetRect.W - i.ItemPadding - valueWidth,
+			Y: propRect.Y,
+			W: valueWidth,
+			H: app.Font.Size,
+		}
+		DrawText(renderer, &app.Font, value, &valueRect, GetColor(theme, "info_color"))
 	}
-	DrawText(renderer, &app.Font, "Modified", &modPropRect, GetColor(theme, "info_color"))
-	DrawText(renderer, &app.Font, i.Info.Modified, &modValueRect, GetColor(theme, "info_color"))
 }
@@ -4,6 +4,10 @@ type Input struct {
 	TypedCharacter byte
 	Backspace      bool
 	Escape         bool
+	Up             bool
+	Down           bool
+	Left           bool
+	Right          bool
 	Ctrl           bool
 	Alt            bool
 	Shift          bool
@@ -13,6 +17,10 @@ func (input *Input) Clear() {
 	input.TypedCharacter = 0
 	input.Backspace = false
 	input.Escape = false
+	input.Up = false
+	input.Down = false
+	input.Left = false
+	input.Right = false
 }
 
 type Shortcut struct {
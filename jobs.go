@@ -0,0 +1,277 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// File operations that might take a while (copying, moving, deleting...) are run as jobs. Jobs are queued and picked
+// up by a fixed amount of worker goroutines. A job reports its progress through atomic counters, which are read by
+// the JobsPanel, and checks if it was paused or cancelled by calling Checkpoint. Once a job finishes, its OnDone
+// callback is called from Tick, which runs on the main thread.
+
+type JobState int32
+
+const (
+	JobQueued JobState = iota
+	JobRunning
+	JobPaused
+	JobDone
+	JobFailed
+	JobCancelled
+)
+
+var ErrJobCancelled = errors.New("cancelled")
+
+type Job struct {
+	ID    int32
+	Title string
+
+	run    func(job *Job) error
+	onDone func(err error)
+	Err    error
+
+	totalFiles int64
+	doneFiles  int64
+	totalBytes int64
+	doneBytes  int64
+
+	mutex     sync.Mutex
+	resume    *sync.Cond
+	state     JobState
+	cancelled bool
+	startTime time.Time
+	pausedAt  time.Time
+	pausedFor time.Duration
+}
+
+type JobManager struct {
+	Jobs []*Job
+
+	queue    chan *Job
+	finished chan *Job
+	nextID   int32
+}
+
+func NewJobManager(workers int) *JobManager {
+	result := &JobManager{
+		queue:    make(chan *Job, 1024),
+		finished: make(chan *Job, 1024),
+	}
+
+	for i := 0; i < workers; i++ {
+		go result.work()
+	}
+
+	return result
+}
+
+// Queues a new job. onDone is called on the main thread after the job finishes, whether it succeeded or not.
+// Errors, other than the job being cancelled, are reported before onDone is called.
+func (m *JobManager) Add(title string, run func(job *Job) error, onDone func(err error)) *Job {
+	m.nextID++
+
+	job := &Job{
+		ID:     m.nextID,
+		Title:  title,
+		run:    run,
+		onDone: onDone,
+		state:  JobQueued,
+	}
+	job.resume = sync.NewCond(&job.mutex)
+
+	m.Jobs = append(m.Jobs, job)
+	m.queue <- job
+
+	return job
+}
+
+func (m *JobManager) work() {
+	for job := range m.queue {
+		job.mutex.Lock()
+		if job.cancelled {
+			job.mutex.Unlock()
+			job.Err = ErrJobCancelled
+			m.finished <- job
+			continue
+		}
+		job.state = JobRunning
+		job.startTime = time.Now()
+		job.mutex.Unlock()
+
+		job.Err = job.run(job)
+		m.finished <- job
+	}
+}
+
+func (m *JobManager) Tick() {
+	for {
+		select {
+		case job := <-m.finished:
+			job.mutex.Lock()
+			if job.Err == nil {
+				job.state = JobDone
+			} else if errors.Is(job.Err, ErrJobCancelled) {
+				job.state = JobCancelled
+			} else {
+				job.state = JobFailed
+			}
+			job.mutex.Unlock()
+
+			if job.Err != nil && !errors.Is(job.Err, ErrJobCancelled) {
+				NotifyError(job.Err.Error())
+			}
+
+			if job.onDone != nil {
+				job.onDone(job.Err)
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (m *JobManager) ActiveCount() (result int32) {
+	for _, job := range m.Jobs {
+		if !job.IsFinished() {
+			result++
+		}
+	}
+
+	return
+}
+
+// Removes the jobs that are no longer running from the list
+func (m *JobManager) ClearFinished() {
+	jobs := make([]*Job, 0)
+	for _, job := range m.Jobs {
+		if !job.IsFinished() {
+			jobs = append(jobs, job)
+		}
+	}
+
+	m.Jobs = jobs
+}
+
+// Blocks while the job is paused. Returns ErrJobCancelled if the job was cancelled, in which case the job should stop
+// what it's doing and return the error.
+func (j *Job) Checkpoint() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	for j.state == JobPaused && !j.cancelled {
+		j.resume.Wait()
+	}
+
+	if j.cancelled {
+		return ErrJobCancelled
+	}
+
+	return nil
+}
+
+func (j *Job) SetTotal(files int64, bytes int64) {
+	atomic.StoreInt64(&j.totalFiles, files)
+	atomic.StoreInt64(&j.totalBytes, bytes)
+}
+
+func (j *Job) AddBytes(bytes int64) error {
+	atomic.AddInt64(&j.doneBytes, bytes)
+	return j.Checkpoint()
+}
+
+func (j *Job) AddFile() error {
+	atomic.AddInt64(&j.doneFiles, 1)
+	return j.Checkpoint()
+}
+
+func (j *Job) Files() (done int64, total int64) {
+	return atomic.LoadInt64(&j.doneFiles), atomic.LoadInt64(&j.totalFiles)
+}
+
+func (j *Job) Bytes() (done int64, total int64) {
+	return atomic.LoadInt64(&j.doneBytes), atomic.LoadInt64(&j.totalBytes)
+}
+
+// Returns a value between 0 and 1. Bytes are used if the job knows how many of them it's going to process,
+// otherwise the file count is used.
+func (j *Job) Progress() float32 {
+	doneBytes, totalBytes := j.Bytes()
+	if totalBytes > 0 {
+		return clampProgress(float32(doneBytes) / float32(totalBytes))
+	}
+
+	doneFiles, totalFiles := j.Files()
+	if totalFiles > 0 {
+		return clampProgress(float32(doneFiles) / float32(totalFiles))
+	}
+
+	return 0
+}
+
+// Estimates the remaining time based on the average speed so far. Returns a negative duration if there's not enough
+// information yet.
+func (j *Job) ETA() time.Duration {
+	j.mutex.Lock()
+	if j.state != JobRunning && j.state != JobPaused {
+		j.mutex.Unlock()
+		return -1
+	}
+
+	elapsed := time.Since(j.startTime) - j.pausedFor
+	if j.state == JobPaused {
+		elapsed -= time.Since(j.pausedAt)
+	}
+	j.mutex.Unlock()
+
+	progress := j.Progress()
+	if progress <= 0 || elapsed < time.Second {
+		return -1
+	}
+
+	return time.Duration(float64(elapsed) * float64(1-progress) / float64(progress))
+}
+
+func (j *Job) State() JobState {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	return j.state
+}
+
+func (j *Job) IsFinished() bool {
+	state := j.State()
+	return state == JobDone || state == JobFailed || state == JobCancelled
+}
+
+func (j *Job) TogglePause() {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.state == JobRunning {
+		j.state = JobPaused
+		j.pausedAt = time.Now()
+	} else if j.state == JobPaused {
+		j.state = JobRunning
+		j.pausedFor += time.Since(j.pausedAt)
+		j.resume.Broadcast()
+	}
+}
+
+func (j *Job) Cancel() {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.cancelled = true
+	j.resume.Broadcast()
+}
+
+func clampProgress(value float32) float32 {
+	if value > 1 {
+		return 1
+	}
+
+	return value
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+type JobsPanel struct {
+	IsOpen    bool
+	ActiveJob int32
+
+	MaxWidth     int32
+	MaxJobs      int32
+	Padding      int32
+	ItemPadding  int32
+	HeaderHeight int32
+	ItemHeight   int32
+}
+
+func NewJobsPanel() *JobsPanel {
+	return &JobsPanel{
+		MaxWidth:     394,
+		MaxJobs:      6,
+		Padding:      8,
+		ItemPadding:  5,
+		HeaderHeight: 28,
+		ItemHeight:   44,
+	}
+}
+
+func (p *JobsPanel) Open() {
+	p.IsOpen = true
+	p.ActiveJob = 0
+}
+
+func (p *JobsPanel) Close(jobs *JobManager) {
+	p.IsOpen = false
+	jobs.ClearFinished()
+}
+
+func (p *JobsPanel) Tick(input *Input, jobs *JobManager) {
+	if input.Escape || input.TypedCharacter == 'J' {
+		p.Close(jobs)
+		return
+	}
+
+	count := int32(len(jobs.Jobs))
+	if p.ActiveJob >= count {
+		p.ActiveJob = count - 1
+	}
+	if p.ActiveJob < 0 {
+		p.ActiveJob = 0
+	}
+
+	switch input.TypedCharacter {
+	case 'j':
+		if p.ActiveJob < count-1 {
+			p.ActiveJob++
+		}
+	case 'k':
+		if p.ActiveJob > 0 {
+			p.ActiveJob--
+		}
+	case ' ':
+		if count > 0 {
+			jobs.Jobs[p.ActiveJob].TogglePause()
+		}
+	case 'x':
+		if count > 0 {
+			jobs.Jobs[p.ActiveJob].Cancel()
+		}
+	case 'c':
+		jobs.ClearFinished()
+	}
+}
+
+func (p *JobsPanel) Render(renderer *sdl.Renderer, parentRect *sdl.Rect, app *App) {
+	theme := app.Theme.PanelTheme
+	jobs := app.Jobs.Jobs
+
+	// Only a window of jobs around the active one is shown
+	first := int32(0)
+	if p.ActiveJob >= p.MaxJobs {
+		first = p.ActiveJob - p.MaxJobs + 1
+	}
+	last := first + p.MaxJobs
+	if last > int32(len(jobs)) {
+		last = int32(len(jobs))
+	}
+
+	rows := last - first
+	if rows == 0 {
+		rows = 1
+	}
+
+	rect := sdl.Rect{
+		X: parentRect.X + 10,
+		Y: parentRect.Y + parentRect.H - 10 - p.HeaderHeight - p.ItemHeight*rows - p.Padding*2,
+		W: p.MaxWidth,
+		H: p.HeaderHeight + p.ItemHeight*rows + p.Padding*2,
+	}
+	insetRect := DrawPanel(renderer, &rect, fmt.Sprintf("Jobs (%d running)", app.Jobs.ActiveCount()), p.HeaderHeight, p.Padding, &app.Font, theme)
+
+	if len(jobs) == 0 {
+		emptyRect := sdl.Rect{X: insetRect.X + p.ItemPadding, Y: insetRect.Y, W: insetRect.W - p.ItemPadding*2, H: p.ItemHeight}
+		DrawTextInRect(renderer, &app.Font, "No jobs", &emptyRect, GetColor(theme, "secondary_text_color"))
+		return
+	}
+
+	for index := first; index < last; index++ {
+		job := jobs[index]
+
+		itemRect := sdl.Rect{
+			X: insetRect.X,
+			Y: insetRect.Y + (index-first)*p.ItemHeight,
+			W: insetRect.W,
+			H: p.ItemHeight,
+		}
+
+		if index == p.ActiveJob {
+			DrawRect(renderer, &itemRect, GetColor(theme, "active_item_background_color"))
+		}
+
+		state := job.State()
+
+		titleColor := GetColor(theme, "text_color")
+		if state == JobFailed {
+			titleColor = GetColor(theme, "error_color")
+		}
+
+		titleRect := sdl.Rect{X: itemRect.X + p.ItemPadding, Y: itemRect.Y + 2, W: itemRect.W - p.ItemPadding*2, H: 20}
+		DrawTextInRect(renderer, &app.Font, job.Title, &titleRect, titleColor)
+
+		barRect := sdl.Rect{X: itemRect.X + p.ItemPadding, Y: itemRect.Y + 24, W: 80, H: 12}
+		DrawProgressBar(renderer, &barRect, job.Progress(), theme)
+
+		statusRect := sdl.Rect{X: barRect.X + barRect.W + p.ItemPadding, Y: itemRect.Y + 20, W: itemRect.W - barRect.W - p.ItemPadding*3, H: 20}
+		DrawTextInRect(renderer, &app.Font, jobStatus(job, state), &statusRect, GetColor(theme, "secondary_text_color"))
+	}
+}
+
+// Draws a single line hint about the running jobs when the panel is closed
+func (p *JobsPanel) RenderSummary(renderer *sdl.Renderer, parentRect *sdl.Rect, app *App) {
+	count := app.Jobs.ActiveCount()
+	if count == 0 {
+		return
+	}
+
+	theme := app.Theme.PanelTheme
+
+	text := fmt.Sprintf("%d job(s) running, J to show", count)
+	rect := sdl.Rect{
+		X: parentRect.X + 10,
+		Y: parentRect.Y + parentRect.H - 10 - p.HeaderHeight,
+		W: app.Font.GetStringWidth(text) + p.Padding*2,
+		H: p.HeaderHeight,
+	}
+	DrawRect3D(renderer, &rect, GetColor(theme, "background_color"))
+
+	textRect := sdl.Rect{X: rect.X + p.Padding, Y: rect.Y, W: rect.W - p.Padding*2, H: rect.H}
+	DrawTextInRect(renderer, &app.Font, text, &textRect, GetColor(theme, "text_color"))
+}
+
+func jobStatus(job *Job, state JobState) string {
+	switch state {
+	case JobQueued:
+		return "Queued"
+	case JobDone:
+		return "Done"
+	case JobCancelled:
+		return "Cancelled"
+	case JobFailed:
+		return "Failed"
+	}
+
+	doneFiles, totalFiles := job.Files()
+	doneBytes, totalBytes := job.Bytes()
+
+	status := fmt.Sprintf("%d/%d files, %s/%s", doneFiles, totalFiles, bytesToString(doneBytes), bytesToString(totalBytes))
+
+	if state == JobPaused {
+		return status + ", paused"
+	}
+
+	eta := job.ETA()
+	if eta >= 0 {
+		status += ", " + durationToString(eta) + " left"
+	}
+
+	return status
+}
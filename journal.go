@@ -0,0 +1,441 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// The journal keeps a list of the file operations done by the user, so that they can be undone and redone.
+// Every entry is a single user action, which might consist of multiple operations (e.g. grouping files creates
+// a folder and moves the files into it). Entries before Position have been done, entries from Position onwards
+// have been undone and can be redone, apart from the one entry that might be partly undone on either side of it.
+// The journal is saved in the config folder after every change.
+
+type JournalOpType string
+
+const (
+	JournalOpRename       JournalOpType = "rename"
+	JournalOpCreateFile   JournalOpType = "create"
+	JournalOpCreateFolder JournalOpType = "mkdir"
+	JournalOpRemoveFolder JournalOpType = "rmdir"
+	JournalOpCopy         JournalOpType = "copy"
+	JournalOpMove         JournalOpType = "move"
+	JournalOpTrash        JournalOpType = "trash"
+	JournalOpRestore      JournalOpType = "restore"
+	JournalOpSymlink      JournalOpType = "symlink"
+	JournalOpHardLink     JournalOpType = "hardlink"
+	JournalOpExtract      JournalOpType = "extract" // From is the item inside of the archive, as GetFileSystemPath writes it
+)
+
+const maxJournalEntries = 100
+
+type JournalOp struct {
+	Type JournalOpType
+	From string
+	To   string
+
+	Trashed string // Copies and extracted items only: where the item is in the trash while the op is undone
+}
+
+type JournalEntry struct {
+	Description string
+	Ops         []JournalOp
+
+	// How many of the ops, counted from the last one, are undone. Undoing or redoing stops at the first op that
+	// fails, which leaves the entry partly undone, and the next undo or redo goes on from there instead of running
+	// the ops that already ran again.
+	Undone int
+}
+
+type Journal struct {
+	Entries  []JournalEntry
+	Position int
+	Busy     bool
+
+	// Entries recorded while an undo or redo runs. They are added once it's done, so that the entry it runs stays
+	// where it is until then.
+	pending []JournalEntry
+}
+
+func NewJournal() *Journal {
+	fullPath := getJournalPath()
+	if fullPath != "" && DoesFileExist(fullPath) {
+		return loadJournal(fullPath)
+	}
+
+	return &Journal{}
+}
+
+func getJournalPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return path.Join(dir, "bonfire", "journal.bfj")
+}
+
+func loadJournal(fullPath string) (result *Journal) {
+	result = &Journal{}
+
+	lines := strings.Split(ReadFile(fullPath), "\n")
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		split := strings.SplitN(line, " ", 2)
+		if len(split) < 2 {
+			continue
+		}
+
+		if split[0] == ":position" {
+			result.Position, _ = strconv.Atoi(split[1])
+			continue
+		}
+
+		// Lines that can't be read are left out rather than undoing something to the wrong path
+		fields, err := unquoteJournalFields(split[1])
+		if err != nil {
+			continue
+		}
+
+		if split[0] == ":entry" && len(fields) == 2 {
+			undone, err := strconv.Atoi(fields[1])
+			if err == nil {
+				result.Entries = append(result.Entries, JournalEntry{Description: fields[0], Undone: undone})
+			}
+		} else if len(result.Entries) > 0 && (len(fields) == 2 || len(fields) == 3) {
+			op := JournalOp{Type: JournalOpType(strings.TrimPrefix(split[0], ":")), From: fields[0], To: fields[1]}
+			if len(fields) == 3 {
+				op.Trashed = fields[2]
+			}
+
+			last := &result.Entries[len(result.Entries)-1]
+			last.Ops = append(last.Ops, op)
+		}
+	}
+
+	if result.Position > len(result.Entries) || result.Position < 0 {
+		result.Position = len(result.Entries)
+	}
+
+	for index := range result.Entries {
+		entry := &result.Entries[index]
+		entry.Undone = min(max(entry.Undone, 0), len(entry.Ops))
+	}
+
+	return
+}
+
+func (j *Journal) Save() {
+	fullPath := getJournalPath()
+	if fullPath == "" {
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(":position ")
+	sb.WriteString(strconv.Itoa(j.Position))
+	sb.WriteByte('\n')
+
+	// The fields are quoted, because names may contain line breaks and tabs
+	for _, entry := range j.Entries {
+		sb.WriteString(":entry ")
+		sb.WriteString(strconv.Quote(entry.Description))
+		sb.WriteByte(' ')
+		sb.WriteString(strconv.Quote(strconv.Itoa(entry.Undone)))
+		sb.WriteByte('\n')
+
+		for _, op := range entry.Ops {
+			sb.WriteByte(':')
+			sb.WriteString(string(op.Type))
+			sb.WriteByte(' ')
+			sb.WriteString(strconv.Quote(op.From))
+			sb.WriteByte(' ')
+			sb.WriteString(strconv.Quote(op.To))
+			if op.Trashed != "" {
+				sb.WriteByte(' ')
+				sb.WriteString(strconv.Quote(op.Trashed))
+			}
+			sb.WriteByte('\n')
+		}
+	}
+
+	err := os.MkdirAll(path.Dir(fullPath), 0755)
+	if err != nil {
+		NotifyError(err.Error())
+		return
+	}
+
+	WriteFile(fullPath, sb.String())
+}
+
+// Splits a line of quoted fields separated by spaces, as written by Save
+func unquoteJournalFields(line string) (result []string, err error) {
+	for line = strings.TrimSpace(line); line != ""; line = strings.TrimSpace(line) {
+		quoted, err := strconv.QuotedPrefix(line)
+		if err != nil {
+			return nil, err
+		}
+
+		field, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, field)
+		line = line[len(quoted):]
+	}
+
+	return result, nil
+}
+
+// Adds a new entry after the last done entry. Anything that was undone can no longer be redone after this.
+func (j *Journal) Record(description string, ops ...JournalOp) {
+	if len(ops) == 0 {
+		return
+	}
+
+	entry := JournalEntry{Description: description, Ops: ops}
+	if j.Busy {
+		j.pending = append(j.pending, entry)
+		return
+	}
+
+	j.add(entry)
+	j.Save()
+}
+
+func (j *Journal) add(entry JournalEntry) {
+	j.Entries = append(j.Entries[:j.Position], entry)
+	if len(j.Entries) > maxJournalEntries {
+		j.Entries = j.Entries[len(j.Entries)-maxJournalEntries:]
+	}
+	j.Position = len(j.Entries)
+}
+
+// Undoes the last done entry. The operations are run as a job, because reverting a copy or a move of a big folder
+// might take a while. onDone is called with the list of folders that were changed.
+func (j *Journal) Undo(jobs *JobManager, onDone func(changedFolders []string)) {
+	if j.Busy {
+		return
+	}
+
+	// An entry that was partly redone is undone first
+	index := j.Position - 1
+	if j.Position < len(j.Entries) && j.Entries[j.Position].Undone < len(j.Entries[j.Position].Ops) {
+		index = j.Position
+	}
+
+	if index < 0 {
+		return
+	}
+
+	j.run(jobs, "Undo ", index, true, onDone)
+}
+
+func (j *Journal) Redo(jobs *JobManager, onDone func(changedFolders []string)) {
+	if j.Busy {
+		return
+	}
+
+	// An entry that was partly undone is redone first
+	index := j.Position
+	if j.Position > 0 && j.Entries[j.Position-1].Undone > 0 {
+		index = j.Position - 1
+	}
+
+	if index >= len(j.Entries) {
+		return
+	}
+
+	j.run(jobs, "Redo ", index, false, onDone)
+}
+
+// Runs the ops of the entry that aren't undone or redone yet. The progress is kept even if an op fails, and the
+// entry only moves to the other side of Position once all of its ops ran.
+func (j *Journal) run(jobs *JobManager, action string, index int, undo bool, onDone func(changedFolders []string)) {
+	j.Busy = true
+
+	entry := j.Entries[index]
+	title := action + entry.Description
+	undone := entry.Undone
+
+	// The job changes a copy of the ops, the journal is saved and shown from the main thread meanwhile
+	ops := append([]JournalOp{}, entry.Ops...)
+
+	jobs.Add(title, func(job *Job) error {
+		if undo {
+			for ; undone < len(ops); undone++ {
+				err := undoJournalOp(&ops[len(ops)-1-undone], job)
+				if err != nil {
+					return err
+				}
+			}
+		} else {
+			for ; undone > 0; undone-- {
+				err := redoJournalOp(&ops[len(ops)-undone], job)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}, func(err error) {
+		j.Busy = false
+
+		j.Entries[index].Ops = ops
+		j.Entries[index].Undone = undone
+
+		if undone == len(ops) && index < j.Position {
+			j.Position = index
+		} else if undone == 0 && index >= j.Position {
+			j.Position = index + 1
+		}
+
+		for _, pending := range j.pending {
+			j.add(pending)
+		}
+		j.pending = nil
+
+		j.Save()
+
+		if err == nil {
+			NotifyInfo(title)
+		}
+
+		onDone(entry.changedFolders())
+	})
+}
+
+// The op is changed when undoing it moves something to the trash, so that redoing it can bring it back from there
+func undoJournalOp(op *JournalOp, job *Job) error {
+	switch op.Type {
+	case JournalOpRename:
+		return renameNoReplace(op.To, op.From)
+	case JournalOpCreateFile:
+		stats, err := os.Stat(op.To)
+		if err != nil {
+			return err
+		}
+
+		if stats.Size() > 0 {
+			return errors.New(op.To + " is no longer empty, it will not be removed")
+		}
+
+		return os.Remove(op.To)
+	case JournalOpCreateFolder:
+		return os.Remove(op.To)
+	case JournalOpRemoveFolder:
+		return os.Mkdir(op.From, 0755)
+	case JournalOpCopy, JournalOpExtract:
+		// The copy might have been changed since, so it goes to the trash instead of being deleted
+		job.SetTotal(MeasureItem(op.To))
+		trashedPath, err := MoveToTrash(op.To, job)
+		if err != nil {
+			return err
+		}
+
+		op.Trashed = trashedPath
+		return nil
+	case JournalOpMove:
+		job.SetTotal(MeasureItem(op.To))
+		return MovePath(op.To, op.From, job)
+	case JournalOpTrash:
+		item := trashItemFromPath(op.To)
+		item.OriginalPath = op.From
+		return RestoreFromTrash(item, job)
+	case JournalOpRestore:
+		return moveToTrashAt(op.To, op.From, job)
+	case JournalOpSymlink, JournalOpHardLink:
+		return os.Remove(op.To)
+	}
+
+	return errors.New("unknown journal operation " + string(op.Type))
+}
+
+func redoJournalOp(op *JournalOp, job *Job) error {
+	switch op.Type {
+	case JournalOpRename:
+		return renameNoReplace(op.From, op.To)
+	case JournalOpCreateFile:
+		file, err := os.OpenFile(op.To, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return err
+		}
+
+		return file.Close()
+	case JournalOpCreateFolder:
+		return os.Mkdir(op.To, 0755)
+	case JournalOpRemoveFolder:
+		return os.Remove(op.From)
+	case JournalOpCopy, JournalOpExtract:
+		if op.Trashed != "" {
+			item := trashItemFromPath(op.Trashed)
+			item.OriginalPath = op.To
+
+			job.SetTotal(MeasureItem(op.Trashed))
+			err := RestoreFromTrash(item, job)
+			if err != nil {
+				return err
+			}
+
+			op.Trashed = ""
+			return nil
+		}
+
+		if op.Type == JournalOpExtract {
+			return errors.New(op.To + " is no longer in the trash, extract it again instead")
+		}
+
+		job.SetTotal(MeasureItem(op.From))
+		return CopyPath(op.From, op.To, job)
+	case JournalOpMove:
+		job.SetTotal(MeasureItem(op.From))
+		return MovePath(op.From, op.To, job)
+	case JournalOpTrash:
+		return moveToTrashAt(op.From, op.To, job)
+	case JournalOpRestore:
+		item := trashItemFromPath(op.From)
+		item.OriginalPath = op.To
+		return RestoreFromTrash(item, job)
+	case JournalOpSymlink:
+		return os.Symlink(op.From, op.To)
+	case JournalOpHardLink:
+		return os.Link(op.From, op.To)
+	}
+
+	return errors.New("unknown journal operation " + string(op.Type))
+}
+
+// os.Rename replaces the destination on some platforms, which must never happen when undoing something
+func renameNoReplace(from string, to string) error {
+	if DoesFileExist(to) && !isSameItem(from, to) {
+		return errors.New(to + " already exists")
+	}
+
+	return os.Rename(from, to)
+}
+
+func (e *JournalEntry) changedFolders() (result []string) {
+	for _, op := range e.Ops {
+		for _, p := range []string{op.From, op.To} {
+			if p == "" {
+				continue
+			}
+
+			dir := getParentPath(p)
+			if IndexOf(result, dir) < 0 {
+				result = append(result, dir)
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,134 @@
+package main
+
+import "github.com/veandco/go-sdl2/sdl"
+
+// A scrollable list of rows used by the panels. Every row has a main text and an optional second line with details.
+// The colors come from the theme of the panel that owns the list.
+
+type ListItem struct {
+	Text       string
+	Detail     string
+	ColorKey   string // Overrides the text color if set, e.g. "error_color"
+	IsSelected bool
+}
+
+type ListView struct {
+	Items        []ListItem
+	ActiveItem   int32
+	FirstVisible int32
+
+	ItemHeight  int32
+	ItemPadding int32
+}
+
+func NewListView() *ListView {
+	return &ListView{
+		ItemHeight:  40,
+		ItemPadding: 5,
+	}
+}
+
+func (l *ListView) SetItems(items []ListItem) {
+	l.Items = items
+
+	if l.ActiveItem >= int32(len(items)) {
+		l.ActiveItem = int32(len(items)) - 1
+	}
+
+	if l.ActiveItem < 0 {
+		l.ActiveItem = 0
+	}
+}
+
+func (l *ListView) HasActive() bool {
+	return l.ActiveItem >= 0 && l.ActiveItem < int32(len(l.Items))
+}
+
+// Handles the navigation keys. Returns true if the input was consumed.
+func (l *ListView) Tick(input *Input) bool {
+	if input.Ctrl || input.Alt {
+		return false
+	}
+
+	switch input.TypedCharacter {
+	case 'j':
+		if l.ActiveItem < int32(len(l.Items))-1 {
+			l.ActiveItem++
+		}
+	case 'k':
+		if l.ActiveItem > 0 {
+			l.ActiveItem--
+		}
+	case 'G':
+		l.ActiveItem = int32(len(l.Items)) - 1
+		if l.ActiveItem < 0 {
+			l.ActiveItem = 0
+		}
+	case 'g':
+		l.ActiveItem = 0
+	default:
+		return false
+	}
+
+	return true
+}
+
+func (l *ListView) Render(renderer *sdl.Renderer, rect *sdl.Rect, font *Font, theme Subtheme, emptyText string) {
+	if len(l.Items) == 0 {
+		emptyRect := sdl.Rect{X: rect.X + l.ItemPadding, Y: rect.Y, W: rect.W - l.ItemPadding*2, H: l.ItemHeight}
+		DrawTextInRect(renderer, font, emptyText, &emptyRect, GetColor(theme, "secondary_text_color"))
+		return
+	}
+
+	visible := rect.H / l.ItemHeight
+	if visible < 1 {
+		visible = 1
+	}
+
+	if l.ActiveItem < l.FirstVisible {
+		l.FirstVisible = l.ActiveItem
+	} else if l.ActiveItem >= l.FirstVisible+visible {
+		l.FirstVisible = l.ActiveItem - visible + 1
+	}
+
+	for i := int32(0); i < visible; i++ {
+		index := l.FirstVisible + i
+		if index >= int32(len(l.Items)) {
+			break
+		}
+
+		item := l.Items[index]
+
+		itemRect := sdl.Rect{
+			X: rect.X,
+			Y: rect.Y + i*l.ItemHeight,
+			W: rect.W,
+			H: l.ItemHeight,
+		}
+
+		if item.IsSelected && HasColor(theme, "selected_item_background_color") {
+			DrawRect(renderer, &itemRect, GetColor(theme, "selected_item_background_color"))
+		}
+
+		if index == l.ActiveItem {
+			DrawRect(renderer, &itemRect, GetColor(theme, "active_item_background_color"))
+		}
+
+		color := GetColor(theme, "text_color")
+		if item.ColorKey != "" && HasColor(theme, item.ColorKey) {
+			color = GetColor(theme, item.ColorKey)
+		}
+
+		if item.Detail == "" {
+			textRect := sdl.Rect{X: itemRect.X + l.ItemPadding, Y: itemRect.Y, W: itemRect.W - l.ItemPadding*2, H: itemRect.H}
+			DrawTextInRect(renderer, font, item.Text, &textRect, color)
+			continue
+		}
+
+		textRect := sdl.Rect{X: itemRect.X + l.ItemPadding, Y: itemRect.Y, W: itemRect.W - l.ItemPadding*2, H: itemRect.H / 2}
+		DrawTextInRect(renderer, font, item.Text, &textRect, color)
+
+		detailRect := sdl.Rect{X: textRect.X, Y: itemRect.Y + itemRect.H/2, W: textRect.W, H: itemRect.H / 2}
+		DrawTextInRect(renderer, font, item.Detail, &detailRect, GetColor(theme, "secondary_text_color"))
+	}
+}
@@ -195,6 +195,22 @@ func main() {
 					if t.State != sdl.RELEASED {
 						input.Escape = true
 					}
+				case sdl.K_UP:
+					if t.State != sdl.RELEASED {
+						input.Up = true
+					}
+				case sdl.K_DOWN:
+					if t.State != sdl.RELEASED {
+						input.Down = true
+					}
+				case sdl.K_LEFT:
+					if t.State != sdl.RELEASED {
+						input.Left = true
+					}
+				case sdl.K_RIGHT:
+					if t.State != sdl.RELEASED {
+						input.Right = true
+					}
 				default:
 					if t.State != sdl.RELEASED {
 						input.TypedCharacter = keyToCharacter(keycode, t.Keysym.Mod)
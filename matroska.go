@@ -0,0 +1,391 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+// Matroska and WebM files are EBML, a binary XML where every element is an ID and a size followed by its content,
+// both as variable length integers. The top level Segment holds the Info, Tracks and Tags elements, which usually
+// come before the first Cluster of media data. Tags written after the media data are found through the SeekHead.
+
+const (
+	ebmlIDSegment   = 0x18538067
+	ebmlIDSeekHead  = 0x114D9B74
+	ebmlIDSeek      = 0x4DBB
+	ebmlIDSeekID    = 0x53AB
+	ebmlIDSeekPos   = 0x53AC
+	ebmlIDInfo      = 0x1549A966
+	ebmlIDTimescale = 0x2AD7B1
+	ebmlIDDuration  = 0x4489
+	ebmlIDTitle     = 0x7BA9
+	ebmlIDTracks    = 0x1654AE6B
+	ebmlIDTrack     = 0xAE
+	ebmlIDTrackType = 0x83
+	ebmlIDCodecID   = 0x86
+	ebmlIDVideo     = 0xE0
+	ebmlIDWidth     = 0xB0
+	ebmlIDHeight    = 0xBA
+	ebmlIDAudio     = 0xE1
+	ebmlIDRate      = 0xB5
+	ebmlIDChannels  = 0x9F
+	ebmlIDBitDepth  = 0x6264
+	ebmlIDTags      = 0x1254C367
+	ebmlIDTag       = 0x7373
+	ebmlIDSimpleTag = 0x67C8
+	ebmlIDTagName   = 0x45A3
+	ebmlIDTagString = 0x4487
+	ebmlIDCluster   = 0x1F43B675
+
+	matroskaTrackVideo = 1
+	matroskaTrackAudio = 2
+
+	ebmlUnknownSize = math.MaxUint64
+)
+
+// The names of the codecs by the start of their codec IDs
+var matroskaCodecs = []struct {
+	Prefix string
+	Name   string
+}{
+	{"V_MPEG4/ISO/AVC", "H.264"},
+	{"V_MPEGH/ISO/HEVC", "H.265"},
+	{"V_MPEG4/ISO", "MPEG-4 Visual"},
+	{"V_MPEG2", "MPEG-2"},
+	{"V_AV1", "AV1"},
+	{"V_VP8", "VP8"},
+	{"V_VP9", "VP9"},
+	{"V_THEORA", "Theora"},
+	{"A_AAC", "AAC"},
+	{"A_OPUS", "Opus"},
+	{"A_VORBIS", "Vorbis"},
+	{"A_FLAC", "FLAC"},
+	{"A_MPEG/L3", "MP3"},
+	{"A_MPEG/L2", "MP2"},
+	{"A_AC3", "AC-3"},
+	{"A_EAC3", "E-AC-3"},
+	{"A_DTS", "DTS"},
+	{"A_TRUEHD", "TrueHD"},
+	{"A_PCM", "PCM"},
+}
+
+func matroskaCodecName(codecID string) string {
+	for _, codec := range matroskaCodecs {
+		if strings.HasPrefix(codecID, codec.Prefix) {
+			return codec.Name
+		}
+	}
+
+	// Other IDs are shown without the track type, e.g. "V_PRORES" as "PRORES"
+	return codecID[min(len(codecID), 2):]
+}
+
+type ebmlElement struct {
+	ID   uint32
+	Data []byte
+}
+
+// Reads a variable length integer, whose length is told by the leading zero bits of the first byte. IDs keep the
+// length bit, sizes don't, and sizes with every bit set are unknown.
+func readEBMLVarint(data []byte, isID bool) (value uint64, length int, ok bool) {
+	if len(data) == 0 || data[0] == 0 {
+		return
+	}
+
+	length = 1
+	for data[0]&(0x80>>(length-1)) == 0 {
+		length++
+	}
+
+	if len(data) < length || (isID && length > 4) {
+		return 0, 0, false
+	}
+
+	for index := 0; index < length; index++ {
+		value = value<<8 | uint64(data[index])
+	}
+
+	if !isID {
+		value &^= 1 << (7*length + length - 1)
+		if value == 1<<(7*length)-1 {
+			value = ebmlUnknownSize
+		}
+	}
+
+	return value, length, true
+}
+
+// Splits the content of an element into the elements in it
+func readEBMLElements(data []byte) (result []ebmlElement) {
+	for len(data) > 0 {
+		id, idLength, ok := readEBMLVarint(data, true)
+		if !ok {
+			return
+		}
+
+		size, sizeLength, ok := readEBMLVarint(data[idLength:], false)
+		start := uint64(idLength + sizeLength)
+		if !ok || size > uint64(len(data))-start {
+			return
+		}
+
+		result = append(result, ebmlElement{uint32(id), data[start : start+size]})
+		data = data[start+size:]
+	}
+
+	return
+}
+
+func readEBMLUint(data []byte) (result uint64) {
+	for _, value := range data {
+		result = result<<8 | uint64(value)
+	}
+
+	return
+}
+
+func readEBMLFloat(data []byte) float64 {
+	switch len(data) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(data))
+	}
+
+	return 0
+}
+
+// Reads the ID and the size of the element at the offset, and returns where its content starts
+func readEBMLHeader(file *os.File, offset int64) (id uint32, size uint64, start int64, err error) {
+	header := make([]byte, 12)
+	read, err := file.ReadAt(header, offset)
+	if err != nil && (err != io.EOF || read == 0) {
+		return
+	}
+	header = header[:read]
+
+	value, idLength, ok := readEBMLVarint(header, true)
+	if !ok {
+		return 0, 0, 0, errors.New("invalid EBML element")
+	}
+
+	size, sizeLength, ok := readEBMLVarint(header[idLength:], false)
+	if !ok {
+		return 0, 0, 0, errors.New("invalid EBML element")
+	}
+
+	return uint32(value), size, offset + int64(idLength+sizeLength), nil
+}
+
+func readEBMLContent(file *os.File, start int64, size uint64) ([]byte, error) {
+	if size > maxMetadataBlockSize {
+		return nil, errors.New("the EBML element is too large")
+	}
+
+	data := make([]byte, size)
+	_, err := file.ReadAt(data, start)
+	return data, err
+}
+
+func readMatroska(file *os.File, result *MediaInfo) error {
+	// Skips the EBML header to the Segment
+	_, size, start, err := readEBMLHeader(file, 0)
+	if err != nil {
+		return err
+	}
+
+	id, size, segmentStart, err := readEBMLHeader(file, start+int64(size))
+	if err != nil {
+		return err
+	}
+
+	if id != ebmlIDSegment {
+		return errors.New("no Matroska segment")
+	}
+
+	var tagsPosition int64 = -1
+	hasTags := false
+	offset := segmentStart
+
+	for {
+		id, size, start, err := readEBMLHeader(file, offset)
+		if err != nil || id == ebmlIDCluster || size == ebmlUnknownSize {
+			break
+		}
+
+		switch id {
+		case ebmlIDSeekHead, ebmlIDInfo, ebmlIDTracks, ebmlIDTags:
+			data, err := readEBMLContent(file, start, size)
+			if err != nil {
+				break
+			}
+
+			switch id {
+			case ebmlIDSeekHead:
+				tagsPosition = findMatroskaSeekPosition(data, ebmlIDTags)
+			case ebmlIDInfo:
+				readMatroskaInfo(data, result)
+			case ebmlIDTracks:
+				readMatroskaTracks(data, result)
+			case ebmlIDTags:
+				readMatroskaTags(data, result)
+				hasTags = true
+			}
+		}
+
+		offset = start + int64(size)
+	}
+
+	if !hasTags && tagsPosition >= 0 {
+		id, size, start, err := readEBMLHeader(file, segmentStart+tagsPosition)
+		if err == nil && id == ebmlIDTags {
+			if data, err := readEBMLContent(file, start, size); err == nil {
+				readMatroskaTags(data, result)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Returns the position of the element in the segment, or -1 if the SeekHead doesn't point to it
+func findMatroskaSeekPosition(data []byte, id uint32) int64 {
+	for _, seek := range readEBMLElements(data) {
+		if seek.ID != ebmlIDSeek {
+			continue
+		}
+
+		var seekID uint64
+		var position int64 = -1
+		for _, element := range readEBMLElements(seek.Data) {
+			switch element.ID {
+			case ebmlIDSeekID:
+				seekID = readEBMLUint(element.Data)
+			case ebmlIDSeekPos:
+				position = int64(readEBMLUint(element.Data))
+			}
+		}
+
+		if seekID == uint64(id) {
+			return position
+		}
+	}
+
+	return -1
+}
+
+func readMatroskaInfo(data []byte, result *MediaInfo) {
+	// The duration is a count of ticks, which are a millisecond unless told otherwise
+	timescale := uint64(1000000)
+	duration := 0.0
+
+	for _, element := range readEBMLElements(data) {
+		switch element.ID {
+		case ebmlIDTimescale:
+			timescale = readEBMLUint(element.Data)
+		case ebmlIDDuration:
+			duration = readEBMLFloat(element.Data)
+		case ebmlIDTitle:
+			result.addTag("Title", string(element.Data))
+		}
+	}
+
+	result.Duration = time.Duration(duration * float64(timescale))
+}
+
+func readMatroskaTracks(data []byte, result *MediaInfo) {
+	// The video codecs come first whatever the order of the tracks
+	var videoCodecs, audioCodecs []string
+
+	for _, track := range readEBMLElements(data) {
+		if track.ID != ebmlIDTrack {
+			continue
+		}
+
+		var trackType uint64
+		codec := ""
+		var video, audio []ebmlElement
+
+		for _, element := range readEBMLElements(track.Data) {
+			switch element.ID {
+			case ebmlIDTrackType:
+				trackType = readEBMLUint(element.Data)
+			case ebmlIDCodecID:
+				codec = matroskaCodecName(strings.TrimRight(string(element.Data), "\x00"))
+			case ebmlIDVideo:
+				video = readEBMLElements(element.Data)
+			case ebmlIDAudio:
+				audio = readEBMLElements(element.Data)
+			}
+		}
+
+		switch trackType {
+		case matroskaTrackVideo:
+			videoCodecs = append(videoCodecs, codec)
+			if result.Width > 0 {
+				break
+			}
+
+			for _, element := range video {
+				switch element.ID {
+				case ebmlIDWidth:
+					result.Width = int(readEBMLUint(element.Data))
+				case ebmlIDHeight:
+					result.Height = int(readEBMLUint(element.Data))
+				}
+			}
+		case matroskaTrackAudio:
+			audioCodecs = append(audioCodecs, codec)
+			if result.SampleRate > 0 {
+				break
+			}
+
+			for _, element := range audio {
+				switch element.ID {
+				case ebmlIDRate:
+					result.SampleRate = int(readEBMLFloat(element.Data))
+				case ebmlIDChannels:
+					result.Channels = int(readEBMLUint(element.Data))
+				case ebmlIDBitDepth:
+					result.BitsPerSample = int(readEBMLUint(element.Data))
+				}
+			}
+		}
+	}
+
+	for _, codec := range append(videoCodecs, audioCodecs...) {
+		result.addCodec(codec)
+	}
+}
+
+// Every Tag has the targets it applies to and its SimpleTag names and values
+func readMatroskaTags(data []byte, result *MediaInfo) {
+	for _, tag := range readEBMLElements(data) {
+		if tag.ID != ebmlIDTag {
+			continue
+		}
+
+		for _, simpleTag := range readEBMLElements(tag.Data) {
+			if simpleTag.ID != ebmlIDSimpleTag {
+				continue
+			}
+
+			name, value := "", ""
+			for _, element := range readEBMLElements(simpleTag.Data) {
+				switch element.ID {
+				case ebmlIDTagName:
+					name = string(element.Data)
+				case ebmlIDTagString:
+					value = string(element.Data)
+				}
+			}
+
+			result.addTag(mediaTagNames[strings.ToUpper(name)], value)
+		}
+	}
+}
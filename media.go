@@ -0,0 +1,440 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// The properties of images, audio and video files shown in the info view. Only the headers are read: the size and
+// the color model of images, their EXIF data, and the duration, the codecs and the tags of audio and video files.
+// Every container has a reader of its own, see id3.go, vorbis.go, mp4.go and matroska.go.
+
+type MediaTag struct {
+	Name  string
+	Value string
+}
+
+type MediaInfo struct {
+	Duration      time.Duration
+	Codecs        []string // The video codec first
+	Bitrate       int64    // In bits per second, 0 to work it out from the size of the file
+	Width         int
+	Height        int
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+	Tags          []MediaTag
+}
+
+// The names the tags are shown with, by the names Vorbis comments, Matroska and RIFF use for them. ID3 and MP4 have
+// their own names, see id3FrameTags and mp4ItemTags.
+var mediaTagNames = map[string]string{
+	"TITLE":         "Title",
+	"INAM":          "Title",
+	"ARTIST":        "Artist",
+	"IART":          "Artist",
+	"ALBUM":         "Album",
+	"IPRD":          "Album",
+	"ALBUMARTIST":   "Album artist",
+	"DATE":          "Year",
+	"DATE_RECORDED": "Year",
+	"DATE_RELEASED": "Year",
+	"ICRD":          "Year",
+	"TRACKNUMBER":   "Track",
+	"PART_NUMBER":   "Track",
+	"GENRE":         "Genre",
+	"IGNR":          "Genre",
+	"COMPOSER":      "Composer",
+	"COMMENT":       "Comment",
+	"DESCRIPTION":   "Comment",
+	"ICMT":          "Comment",
+}
+
+// Adds a tag under the name it is shown with. Tags that aren't shown are left out, and the first value wins when
+// a file has the same tag twice, e.g. in ID3v2 and in ID3v1.
+func (m *MediaInfo) addTag(name string, value string) {
+	value = strings.TrimSpace(strings.TrimRight(value, "\x00"))
+	if name == "" || value == "" {
+		return
+	}
+
+	for _, tag := range m.Tags {
+		if tag.Name == name {
+			return
+		}
+	}
+
+	m.Tags = append(m.Tags, MediaTag{name, value})
+}
+
+func (m *MediaInfo) addCodec(codec string) {
+	if codec != "" && IndexOf(m.Codecs, codec) < 0 {
+		m.Codecs = append(m.Codecs, codec)
+	}
+}
+
+// Adds the rows of images, audio and video files to the info. Other files, and files that can't be read, get no
+// rows.
+func AddMediaInfo(info *Info, fullPath string) {
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	stats, err := file.Stat()
+	if err != nil {
+		return
+	}
+
+	header := make([]byte, 16)
+	read, _ := io.ReadFull(file, header)
+	header = header[:read]
+
+	if addImageInfo(info, fullPath, file, header) {
+		return
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return
+	}
+
+	media := &MediaInfo{}
+	switch {
+	case bytes.HasPrefix(header, []byte("ID3")) || isMPEGAudioFile(fullPath, header):
+		err = readMP3(file, stats.Size(), media)
+	case bytes.HasPrefix(header, []byte("fLaC")):
+		err = readFLAC(file, media)
+	case bytes.HasPrefix(header, []byte("OggS")):
+		err = readOgg(file, stats.Size(), media)
+	case bytes.HasPrefix(header, []byte("RIFF")) && len(header) >= 12 && string(header[8:12]) == "WAVE":
+		err = readWAV(file, media)
+	case len(header) >= 8 && IndexOf([]string{"ftyp", "moov", "mdat", "free", "wide"}, string(header[4:8])) >= 0:
+		err = readMP4(file, stats.Size(), media)
+	case bytes.HasPrefix(header, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		err = readMatroska(file, media)
+	default:
+		return
+	}
+
+	if err != nil {
+		return
+	}
+
+	media.addRows(info, stats.Size())
+}
+
+func (m *MediaInfo) addRows(info *Info, size int64) {
+	if m.Duration > 0 {
+		info.Add("Duration", formatDuration(m.Duration))
+	}
+
+	info.Add("Codec", strings.Join(m.Codecs, ", "))
+
+	if m.Width > 0 && m.Height > 0 {
+		info.Add("Dimensions", fmt.Sprintf("%d x %d", m.Width, m.Height))
+	}
+
+	var audio []string
+	if m.SampleRate > 0 {
+		audio = append(audio, fmt.Sprintf("%g kHz", float64(m.SampleRate)/1000))
+	}
+
+	switch m.Channels {
+	case 0:
+	case 1:
+		audio = append(audio, "mono")
+	case 2:
+		audio = append(audio, "stereo")
+	default:
+		audio = append(audio, fmt.Sprintf("%d channels", m.Channels))
+	}
+
+	if m.BitsPerSample > 0 {
+		audio = append(audio, fmt.Sprintf("%d-bit", m.BitsPerSample))
+	}
+
+	info.Add("Audio", strings.Join(audio, ", "))
+
+	// The average over the whole file, which also counts the headers and the tags
+	bitrate := m.Bitrate
+	if bitrate == 0 && m.Duration > 0 {
+		bitrate = int64(float64(size*8) / m.Duration.Seconds())
+	}
+
+	if kilobits := (bitrate + 500) / 1000; kilobits > 0 {
+		info.Add("Bitrate", fmt.Sprintf("%d kbps", kilobits))
+	}
+
+	for _, tag := range m.Tags {
+		info.Add(tag.Name, tag.Value)
+	}
+}
+
+// Formats the duration as "3:07" or "1:23:45", or as "0.25 s" for sounds shorter than a second
+func formatDuration(duration time.Duration) string {
+	if duration < time.Second {
+		return fmt.Sprintf("%.2f s", duration.Seconds())
+	}
+
+	seconds := int64(duration.Round(time.Second).Seconds())
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// Adds the size, the color model and the EXIF data of an image. Returns false if the file isn't an image.
+func addImageInfo(info *Info, fullPath string, file *os.File, header []byte) bool {
+	width, height := 0, 0
+	colorModel := ""
+
+	switch {
+	case bytes.HasPrefix(header, []byte("BM")):
+		width, height, colorModel = readBMPSize(file)
+	case bytes.HasPrefix(header, []byte("RIFF")) && len(header) >= 12 && string(header[8:12]) == "WEBP":
+		width, height = readWebPSize(file)
+	case bytes.HasPrefix(header, []byte{0, 0, 1, 0}) && len(header) >= 8:
+		// Icons have several sizes, the first one is shown. Sizes of 256 are stored as 0.
+		width, height = int(header[6]), int(header[7])
+		if width == 0 {
+			width = 256
+		}
+		if height == 0 {
+			height = 256
+		}
+	case bytes.HasPrefix(header, []byte("II*\x00")) || bytes.HasPrefix(header, []byte("MM\x00*")):
+		// The size of TIFF images is in the same IFD as the EXIF data
+	default:
+		_, err := file.Seek(0, io.SeekStart)
+		if err != nil {
+			return false
+		}
+
+		config, _, err := image.DecodeConfig(file)
+		if err != nil {
+			return false
+		}
+
+		width, height = config.Width, config.Height
+		colorModel = describeColorModel(config.ColorModel)
+	}
+
+	exif, err := ReadExif(fullPath)
+	if err == nil && width == 0 {
+		if exifWidth, exifHeight, ok := exif.Size(); ok {
+			width, height = int(exifWidth), int(exifHeight)
+		}
+	}
+
+	if width == 0 || height == 0 {
+		return false
+	}
+
+	info.Add("Dimensions", fmt.Sprintf("%d x %d", width, height))
+	info.Add("Color model", colorModel)
+
+	if err != nil {
+		return true
+	}
+
+	info.Add("Camera", exif.Camera())
+	info.Add("Lens", exif.Lens())
+	info.Add("Exposure", exif.Exposure())
+
+	if date, ok := exif.Date(); ok {
+		info.Add("Taken", date.Format("2006-01-02 15:04:05"))
+	}
+
+	info.Add("Orientation", exif.Orientation())
+
+	if latitude, longitude, ok := exif.Location(); ok {
+		info.Add("GPS", fmt.Sprintf("%.6f, %.6f", latitude, longitude))
+	}
+
+	return true
+}
+
+func describeColorModel(model color.Model) string {
+	switch model {
+	case color.RGBAModel, color.NRGBAModel:
+		return "RGBA, 8 bits per channel"
+	case color.RGBA64Model, color.NRGBA64Model:
+		return "RGBA, 16 bits per channel"
+	case color.GrayModel:
+		return "Grayscale, 8-bit"
+	case color.Gray16Model:
+		return "Grayscale, 16-bit"
+	case color.YCbCrModel:
+		return "YCbCr"
+	case color.CMYKModel:
+		return "CMYK"
+	}
+
+	if palette, ok := model.(color.Palette); ok {
+		return fmt.Sprintf("Paletted, %d colors", len(palette))
+	}
+
+	return ""
+}
+
+func readBMPSize(file *os.File) (width int, height int, colorModel string) {
+	header := make([]byte, 30)
+	_, err := file.ReadAt(header, 0)
+	if err != nil {
+		return
+	}
+
+	// A negative height means the rows are stored from the top
+	width = int(int32(binary.LittleEndian.Uint32(header[18:])))
+	height = int(int32(binary.LittleEndian.Uint32(header[22:])))
+	if height < 0 {
+		height = -height
+	}
+
+	bits := binary.LittleEndian.Uint16(header[28:])
+	colorModel = fmt.Sprintf("%d bits per pixel", bits)
+	if bits <= 8 {
+		colorModel = fmt.Sprintf("Paletted, %d colors", 1<<bits)
+	}
+
+	return
+}
+
+// WebP files are lossy (VP8), lossless (VP8L), or extended (VP8X) with the size of the canvas in their header
+func readWebPSize(file *os.File) (width int, height int) {
+	header := make([]byte, 30)
+	_, err := file.ReadAt(header, 0)
+	if err != nil {
+		return
+	}
+
+	switch string(header[12:16]) {
+	case "VP8 ":
+		width = int(binary.LittleEndian.Uint16(header[26:]) & 0x3FFF)
+		height = int(binary.LittleEndian.Uint16(header[28:]) & 0x3FFF)
+	case "VP8L":
+		bits := binary.LittleEndian.Uint32(header[21:])
+		width = int(bits&0x3FFF) + 1
+		height = int(bits>>14&0x3FFF) + 1
+	case "VP8X":
+		width = int(uint32(header[24])|uint32(header[25])<<8|uint32(header[26])<<16) + 1
+		height = int(uint32(header[27])|uint32(header[28])<<8|uint32(header[29])<<16) + 1
+	}
+
+	return
+}
+
+// Without an ID3 tag, MP3 files start with the sync bits of the first frame, which other files can start with too
+func isMPEGAudioFile(fullPath string, header []byte) bool {
+	return len(header) >= 2 && header[0] == 0xFF && header[1]&0xE0 == 0xE0 && strings.EqualFold(path.Ext(fullPath), ".mp3")
+}
+
+// WAV files are RIFF chunks: the format, the samples, and optionally a list of tags
+func readWAV(file *os.File, result *MediaInfo) error {
+	_, err := file.Seek(12, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	var byteRate uint32
+	chunkHeader := make([]byte, 8)
+	for {
+		_, err = io.ReadFull(file, chunkHeader)
+		if err != nil {
+			break
+		}
+
+		id := string(chunkHeader[:4])
+		size := int64(binary.LittleEndian.Uint32(chunkHeader[4:]))
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return errors.New("invalid WAV format")
+			}
+
+			format := make([]byte, 16)
+			_, err = io.ReadFull(file, format)
+			if err != nil {
+				return err
+			}
+
+			result.addCodec(wavFormats[binary.LittleEndian.Uint16(format)])
+			result.Channels = int(binary.LittleEndian.Uint16(format[2:]))
+			result.SampleRate = int(binary.LittleEndian.Uint32(format[4:]))
+			byteRate = binary.LittleEndian.Uint32(format[8:])
+			result.BitsPerSample = int(binary.LittleEndian.Uint16(format[14:]))
+			result.Bitrate = int64(byteRate) * 8
+			size -= 16
+		case "data":
+			if byteRate > 0 {
+				result.Duration = time.Duration(float64(size) / float64(byteRate) * float64(time.Second))
+			}
+		case "LIST":
+			if size <= 4 || size > 1024*1024 {
+				break
+			}
+
+			data := make([]byte, size)
+			_, err = io.ReadFull(file, data)
+			if err != nil {
+				return err
+			}
+			size = 0
+
+			if string(data[:4]) == "INFO" {
+				readRIFFInfo(data[4:], result)
+			}
+		}
+
+		// Chunks are padded to an even size
+		_, err = file.Seek(size+size%2, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+	}
+
+	if result.SampleRate == 0 {
+		return errors.New("invalid WAV file")
+	}
+
+	return nil
+}
+
+var wavFormats = map[uint16]string{
+	0x0001: "PCM",
+	0x0003: "PCM (floating point)",
+	0x0006: "A-law",
+	0x0007: "μ-law",
+	0x0055: "MP3",
+	0xFFFE: "PCM",
+}
+
+func readRIFFInfo(data []byte, result *MediaInfo) {
+	for len(data) >= 8 {
+		id := string(data[:4])
+		size := int(binary.LittleEndian.Uint32(data[4:]))
+		if 8+size > len(data) {
+			return
+		}
+
+		result.addTag(mediaTagNames[id], string(data[8:8+size]))
+
+		data = data[min(8+size+size%2, len(data)):]
+	}
+}
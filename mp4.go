@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// MP4, M4A and QuickTime files are a tree of atoms, each a size and a type followed by its content. Everything but
+// the media data is in the moov atom, which is read whole: the duration from mvhd, the tracks from trak and the
+// tags from the ilst list in udta/meta.
+
+const maxMovieAtomSize = 32 * 1024 * 1024
+
+// The names the tags are shown with, by the types of the items in the ilst atom
+var mp4ItemTags = map[string]string{
+	"\xA9nam": "Title",
+	"\xA9ART": "Artist",
+	"\xA9alb": "Album",
+	"aART":    "Album artist",
+	"\xA9day": "Year",
+	"trkn":    "Track",
+	"\xA9gen": "Genre",
+	"gnre":    "Genre",
+	"\xA9wrt": "Composer",
+	"\xA9cmt": "Comment",
+}
+
+var mp4Codecs = map[string]string{
+	"avc1": "H.264",
+	"avc3": "H.264",
+	"hvc1": "H.265",
+	"hev1": "H.265",
+	"av01": "AV1",
+	"vp08": "VP8",
+	"vp09": "VP9",
+	"mp4v": "MPEG-4 Visual",
+	"apcn": "ProRes",
+	"apch": "ProRes",
+	"apcs": "ProRes",
+	"apco": "ProRes",
+	"ap4h": "ProRes",
+	"mp4a": "AAC",
+	"ac-3": "AC-3",
+	"ec-3": "E-AC-3",
+	"alac": "ALAC",
+	"Opus": "Opus",
+	"fLaC": "FLAC",
+	".mp3": "MP3",
+	"lpcm": "PCM",
+	"sowt": "PCM",
+	"twos": "PCM",
+}
+
+type mp4Atom struct {
+	Type string
+	Data []byte
+}
+
+// Splits the content of an atom into the atoms in it. An atom that claims to be larger than what is left ends the
+// list.
+func readMP4Atoms(data []byte) (result []mp4Atom) {
+	for len(data) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(data))
+		atomType := string(data[4:8])
+		headerSize := uint64(8)
+
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return
+			}
+			size = binary.BigEndian.Uint64(data[8:])
+			headerSize = 16
+		}
+
+		if size < headerSize || size > uint64(len(data)) {
+			return
+		}
+
+		result = append(result, mp4Atom{atomType, data[headerSize:size]})
+		data = data[size:]
+	}
+
+	return
+}
+
+func findMP4Atom(atoms []mp4Atom, path ...string) (mp4Atom, bool) {
+	for _, atom := range atoms {
+		if atom.Type != path[0] {
+			continue
+		}
+
+		if len(path) == 1 {
+			return atom, true
+		}
+
+		return findMP4Atom(readMP4Atoms(atom.Data), path[1:]...)
+	}
+
+	return mp4Atom{}, false
+}
+
+func readMP4(file *os.File, size int64, result *MediaInfo) error {
+	// Finds the moov atom among the top level atoms, which may come after the media data
+	header := make([]byte, 16)
+	offset := int64(0)
+	for {
+		if offset+8 > size {
+			return errors.New("no MP4 movie atom")
+		}
+
+		_, err := file.ReadAt(header, offset)
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		atomSize := int64(binary.BigEndian.Uint32(header))
+		headerSize := int64(8)
+		switch atomSize {
+		case 0:
+			atomSize = size - offset
+		case 1:
+			atomSize = int64(binary.BigEndian.Uint64(header[8:]))
+			headerSize = 16
+		}
+
+		if atomSize < headerSize || offset+atomSize > size {
+			return errors.New("invalid MP4 atom")
+		}
+
+		if string(header[4:8]) == "moov" {
+			if atomSize > maxMovieAtomSize {
+				return errors.New("the MP4 movie atom is too large")
+			}
+
+			data := make([]byte, atomSize-headerSize)
+			_, err = file.ReadAt(data, offset+headerSize)
+			if err != nil {
+				return err
+			}
+
+			readMP4Movie(readMP4Atoms(data), result)
+			return nil
+		}
+
+		offset += atomSize
+	}
+}
+
+func readMP4Movie(atoms []mp4Atom, result *MediaInfo) {
+	if header, ok := findMP4Atom(atoms, "mvhd"); ok {
+		data := header.Data
+		var timescale, duration uint64
+
+		if len(data) >= 32 && data[0] == 1 {
+			timescale = uint64(binary.BigEndian.Uint32(data[20:]))
+			duration = binary.BigEndian.Uint64(data[24:])
+		} else if len(data) >= 20 {
+			timescale = uint64(binary.BigEndian.Uint32(data[12:]))
+			duration = uint64(binary.BigEndian.Uint32(data[16:]))
+		}
+
+		if timescale > 0 {
+			result.Duration = time.Duration(float64(duration) / float64(timescale) * float64(time.Second))
+		}
+	}
+
+	// The video codecs come first whatever the order of the tracks
+	var videoCodecs, audioCodecs []string
+	for _, atom := range atoms {
+		if atom.Type != "trak" {
+			continue
+		}
+
+		track := readMP4Atoms(atom.Data)
+		handler, ok := findMP4Atom(track, "mdia", "hdlr")
+		if !ok || len(handler.Data) < 12 {
+			continue
+		}
+
+		description, _ := findMP4Atom(track, "mdia", "minf", "stbl", "stsd")
+		entry := description.Data
+		if len(entry) < 16 {
+			continue
+		}
+
+		// The first sample entry follows the version, the flags and the count of entries
+		entry = entry[8:]
+		codec := mp4Codecs[string(entry[4:8])]
+
+		switch string(handler.Data[8:12]) {
+		case "vide":
+			videoCodecs = append(videoCodecs, codec)
+
+			// The size the track is shown at, in 16.16 fixed point
+			if trackHeader, ok := findMP4Atom(track, "tkhd"); ok && result.Width == 0 {
+				data := trackHeader.Data
+				if len(data) >= 96 && data[0] == 1 {
+					data = data[88:]
+				} else if len(data) >= 84 && data[0] == 0 {
+					data = data[76:]
+				} else {
+					break
+				}
+
+				result.Width = int(binary.BigEndian.Uint32(data) >> 16)
+				result.Height = int(binary.BigEndian.Uint32(data[4:]) >> 16)
+			}
+		case "soun":
+			audioCodecs = append(audioCodecs, codec)
+
+			if len(entry) >= 36 && result.SampleRate == 0 {
+				result.Channels = int(binary.BigEndian.Uint16(entry[24:]))
+				result.SampleRate = int(binary.BigEndian.Uint16(entry[32:]))
+			}
+		}
+	}
+
+	for _, codec := range append(videoCodecs, audioCodecs...) {
+		result.addCodec(codec)
+	}
+
+	// The meta atom has a version and flags in MP4 files but not in QuickTime files
+	meta, ok := findMP4Atom(atoms, "udta", "meta")
+	if !ok {
+		return
+	}
+
+	data := meta.Data
+	if len(data) >= 8 && string(data[4:8]) != "hdlr" {
+		data = data[4:]
+	}
+
+	list, ok := findMP4Atom(readMP4Atoms(data), "ilst")
+	if !ok {
+		return
+	}
+
+	for _, item := range readMP4Atoms(list.Data) {
+		name := mp4ItemTags[item.Type]
+		if name == "" {
+			continue
+		}
+
+		// The value follows the type of the data and the locale
+		value, ok := findMP4Atom(readMP4Atoms(item.Data), "data")
+		if !ok || len(value.Data) < 8 {
+			continue
+		}
+
+		content := value.Data[8:]
+		switch item.Type {
+		case "trkn":
+			if len(content) >= 4 && binary.BigEndian.Uint16(content[2:]) > 0 {
+				result.addTag(name, strconv.Itoa(int(binary.BigEndian.Uint16(content[2:]))))
+			}
+		case "gnre":
+			// The number of an ID3v1 genre, plus 1
+			if len(content) >= 2 {
+				index := int(binary.BigEndian.Uint16(content)) - 1
+				if index >= 0 && index < len(id3Genres) {
+					result.addTag(name, id3Genres[index])
+				}
+			}
+		default:
+			result.addTag(name, string(content))
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import "github.com/veandco/go-sdl2/sdl"
+
+// Draws the frame shared by the floating panels: a header with the title and a base with an inset area below it.
+// Returns the inset area, which is where the panel should draw its contents.
+func DrawPanel(renderer *sdl.Renderer, rect *sdl.Rect, title string, headerHeight int32, padding int32, font *Font, theme Subtheme) sdl.Rect {
+	headerRect := sdl.Rect{
+		X: rect.X,
+		Y: rect.Y,
+		W: rect.W,
+		H: headerHeight,
+	}
+	DrawRect3D(renderer, &headerRect, GetColor(theme, "background_color"))
+
+	clippedTitle := font.ClipString(title, rect.W-padding*2)
+	titleRect := sdl.Rect{
+		X: headerRect.X + 10,
+		Y: headerRect.Y + (headerRect.H-font.Size)/2,
+		W: font.GetStringWidth(clippedTitle),
+		H: font.Size,
+	}
+	DrawText(renderer, font, clippedTitle, &titleRect, GetColor(theme, "header_color"))
+
+	baseRect := sdl.Rect{
+		X: rect.X,
+		Y: rect.Y + headerHeight,
+		W: rect.W,
+		H: rect.H - headerHeight,
+	}
+	insetRect := sdl.Rect{
+		X: baseRect.X + padding,
+		Y: baseRect.Y + padding,
+		W: baseRect.W - padding*2,
+		H: baseRect.H - padding*2,
+	}
+	DrawRect3D(renderer, &baseRect, GetColor(theme, "background_color"))
+	DrawRect3DInset(renderer, &insetRect, GetColor(theme, "inset_color"))
+
+	return insetRect
+}
+
+// Draws a single line of text inside of the given rect, vertically centered and clipped to fit
+func DrawTextInRect(renderer *sdl.Renderer, font *Font, text string, rect *sdl.Rect, color sdl.Color) {
+	if text == "" {
+		return
+	}
+
+	clipped := font.ClipString(text, rect.W)
+	textRect := sdl.Rect{
+		X: rect.X,
+		Y: rect.Y + (rect.H-font.Size)/2,
+		W: font.GetStringWidth(clipped),
+		H: font.Size,
+	}
+	DrawText(renderer, font, clipped, &textRect, color)
+}
+
+func DrawProgressBar(renderer *sdl.Renderer, rect *sdl.Rect, progress float32, theme Subtheme) {
+	DrawRect3DInset(renderer, rect, GetColor(theme, "inset_color"))
+
+	fillRect := sdl.Rect{
+		X: rect.X + 1,
+		Y: rect.Y + 1,
+		W: int32(float32(rect.W-2) * progress),
+		H: rect.H - 2,
+	}
+	if fillRect.W > 0 {
+		DrawRect(renderer, &fillRect, GetColor(theme, "progress_color"))
+	}
+}
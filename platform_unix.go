@@ -0,0 +1,184 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"os/user"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Keys used to pick a root in the drive selection mode, in order
+const rootKeys = "123456789abcdefghijklmnopqrstuvwxyz"
+
+func IsFileHidden(fullPath string) bool {
+	return strings.HasPrefix(path.Base(fullPath), ".")
+}
+
+func IsExecutable(name string, mode fs.FileMode) bool {
+	return mode.IsRegular() && mode&0111 != 0
+}
+
+// Paths are case sensitive on most unix file systems
+func normalizePathCase(fullPath string) string {
+	return fullPath
+}
+
+// Returns "/" followed by the mount points of the storage devices and network shares
+func GetRoots() (result []Root) {
+	paths := []string{"/"}
+	for _, mount := range getMountPoints() {
+		if mount.Path != "/" && isUserMount(mount) && IndexOf(paths, mount.Path) < 0 {
+			paths = append(paths, mount.Path)
+		}
+	}
+
+	sort.Strings(paths[1:])
+
+	for index, fullPath := range paths {
+		if index >= len(rootKeys) {
+			break
+		}
+
+		result = append(result, Root{
+			Key:   rootKeys[index],
+			Path:  fullPath,
+			Label: string(rootKeys[index]) + " " + fullPath,
+		})
+	}
+
+	return
+}
+
+// Leaves out the virtual file systems (proc, sysfs, cgroups, ...) and the read-only images of snap packages
+func isUserMount(mount MountPoint) bool {
+	if strings.HasPrefix(mount.Path, "/snap/") || strings.HasPrefix(mount.Path, "/boot") {
+		return false
+	}
+
+	switch mount.Type {
+	case "nfs", "nfs4", "cifs", "smbfs", "smb3", "9p":
+		return true
+	}
+
+	return strings.HasPrefix(mount.Device, "/dev/") && mount.Type != "squashfs" || strings.HasPrefix(mount.Type, "fuse.")
+}
+
+func getMountPoints() (result []MountPoint) {
+	data, err := os.ReadFile("/proc/self/mounts")
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+
+		// Spaces and other special characters are escaped as octal numbers
+		result = append(result, MountPoint{
+			Device: unescapeMountPath(fields[0]),
+			Path:   unescapeMountPath(fields[1]),
+			Type:   fields[2],
+		})
+	}
+
+	return
+}
+
+func unescapeMountPath(value string) string {
+	var sb strings.Builder
+
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+3 < len(value) {
+			code, err := strconv.ParseUint(value[i+1:i+4], 8, 8)
+			if err == nil {
+				sb.WriteByte(byte(code))
+				i += 3
+				continue
+			}
+		}
+
+		sb.WriteByte(value[i])
+	}
+
+	return sb.String()
+}
+
+// Blocks are counted in units of 512 bytes, whatever the block size of the file system is
+func getAllocatedSize(stats fs.FileInfo) int64 {
+	sys, ok := stats.Sys().(*syscall.Stat_t)
+	if !ok {
+		return stats.Size()
+	}
+
+	return int64(sys.Blocks) * 512
+}
+
+func getFileIdentity(stats fs.FileInfo) (FileIdentity, bool) {
+	sys, ok := stats.Sys().(*syscall.Stat_t)
+	if !ok {
+		return FileIdentity{}, false
+	}
+
+	return FileIdentity{Device: uint64(sys.Dev), Inode: uint64(sys.Ino), Links: uint64(sys.Nlink)}, true
+}
+
+// Returns the names of the owner and the group, or their ids if they have no name
+func getOwnership(stats fs.FileInfo) (owner string, group string, ok bool) {
+	sys, ok := stats.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", "", false
+	}
+
+	owner = strconv.FormatUint(uint64(sys.Uid), 10)
+	if found, err := user.LookupId(owner); err == nil {
+		owner = found.Username
+	}
+
+	group = strconv.FormatUint(uint64(sys.Gid), 10)
+	if found, err := user.LookupGroupId(group); err == nil {
+		group = found.Name
+	}
+
+	return owner, group, true
+}
+
+// Takes names or ids, returns -1 for the empty ones so that chown keeps them
+func lookupOwnership(owner string, group string) (uid int, gid int, err error) {
+	uid, gid = -1, -1
+
+	if owner != "" {
+		uid, err = strconv.Atoi(owner)
+		if err != nil {
+			found, lookupErr := user.Lookup(owner)
+			if lookupErr != nil {
+				return -1, -1, errors.New("there is no user called " + owner)
+			}
+
+			uid, _ = strconv.Atoi(found.Uid)
+		}
+	}
+
+	if group != "" {
+		gid, err = strconv.Atoi(group)
+		if err != nil {
+			found, lookupErr := user.LookupGroup(group)
+			if lookupErr != nil {
+				return -1, -1, errors.New("there is no group called " + group)
+			}
+
+			gid, _ = strconv.Atoi(found.Gid)
+		}
+	}
+
+	return uid, gid, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func IsFileHidden(fullPath string) bool {
+	pointer, err := syscall.UTF16PtrFromString(fullPath)
+	if err != nil {
+		NotifyError(err.Error())
+		return false
+	}
+
+	attr, err := syscall.GetFileAttributes(pointer)
+	if err != nil {
+		NotifyError(err.Error())
+		return false
+	}
+
+	return attr&syscall.FILE_ATTRIBUTE_HIDDEN != 0
+}
+
+func IsExecutable(name string, mode fs.FileMode) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".exe", ".bat", ".cmd", ".com":
+		return true
+	}
+
+	return false
+}
+
+// Paths are case insensitive on Windows
+func normalizePathCase(fullPath string) string {
+	return strings.ToLower(fullPath)
+}
+
+// Returns the drives that can be opened, picked by their letter
+func GetRoots() (result []Root) {
+	for _, drive := range "ABCDEFGHIJKLMNOPQRSTUVWXYZ" {
+		// When opening the drive where the cwd is, go reads the cwd instead of the drive without the slash
+		fullPath := string(drive) + ":/"
+
+		file, err := os.Open(fullPath)
+		if err == nil {
+			result = append(result, Root{Key: byte(drive), Path: fullPath, Label: string(drive)})
+			file.Close()
+		}
+	}
+
+	return
+}
+
+func GetBirthTime(fullPath string, stats os.FileInfo) (time.Time, bool) {
+	data, ok := stats.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, data.CreationTime.Nanoseconds()), true
+}
+
+func getMountPoints() (result []MountPoint) {
+	for _, root := range GetRoots() {
+		result = append(result, MountPoint{Device: root.Label + ":", Path: root.Path, Type: "drive"})
+	}
+
+	return
+}
+
+// Compressed and sparse files would need another call per file, so the size is used as is
+func getAllocatedSize(stats fs.FileInfo) int64 {
+	return stats.Size()
+}
+
+// Windows doesn't report the volume and the file index through os.Lstat, so every file is counted and mounted
+// folders are treated like links, which aren't followed
+func getFileIdentity(stats fs.FileInfo) (FileIdentity, bool) {
+	return FileIdentity{}, false
+}
+
+// Windows has owners too, but they are access control entries that chown can't change
+func getOwnership(stats fs.FileInfo) (owner string, group string, ok bool) {
+	return "", "", false
+}
+
+func lookupOwnership(owner string, group string) (uid int, gid int, err error) {
+	if owner != "" || group != "" {
+		return -1, -1, errors.New("the owner can't be changed on Windows")
+	}
+
+	return -1, -1, nil
+}
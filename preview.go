@@ -6,6 +6,9 @@ import (
 	"github.com/veandco/go-sdl2/sdl"
 )
 
+// Files inside of archives have to be read into memory to be previewed, so big ones are refused
+const maxPreviewSize = 64 * 1024 * 1024
+
 type PreviewMode string
 
 const (
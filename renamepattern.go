@@ -0,0 +1,287 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// A rename pattern builds a new name for every item from a template. Tokens in braces are replaced with values of
+// the item, e.g. "trip_{n:03}{ext}". If a regular expression is given, only the parts of the name that match it are
+// replaced and the template can use the capture groups, otherwise the template replaces the whole name.
+//
+// Tokens:
+//   {name}    name without the extension        {ext}     extension, including the dot
+//   {n}       counter, {n:03} pads it to 3 digits
+//   {0}, {1}  whole match and capture groups of the regular expression
+//   {parent}  name of the folder the item is in
+//   {date}    modification date, {date:YYYYMMDD_hhmmss} changes the format
+//   {exif}    date the photo was taken, same format as {date}
+// Text tokens can be converted with :upper, :lower or :title, e.g. {name:lower}. {{ and }} are literal braces.
+
+const defaultRenameDateFormat = "YYYY-MM-DD"
+
+var renameDateReplacer = strings.NewReplacer("YYYY", "2006", "MM", "01", "DD", "02", "hh", "15", "mm", "04", "ss", "05")
+
+type RenamePattern struct {
+	Find     *regexp.Regexp
+	Template []renameToken
+}
+
+type renameToken struct {
+	Literal  string
+	Key      string
+	Modifier string
+}
+
+// Values of an item that are expensive to get, cached between previews
+type RenameMetadata struct {
+	ModTime  time.Time
+	ExifDate time.Time
+	HasExif  bool
+	Err      error
+}
+
+func ParseRenamePattern(find string, template string) (result RenamePattern, err error) {
+	if find != "" {
+		result.Find, err = regexp.Compile(find)
+		if err != nil {
+			return
+		}
+	}
+
+	result.Template, err = parseRenameTemplate(template)
+	return
+}
+
+func parseRenameTemplate(template string) (result []renameToken, err error) {
+	var literal strings.Builder
+
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+
+		if (c == '{' || c == '}') && i+1 < len(template) && template[i+1] == c {
+			literal.WriteByte(c)
+			i++
+			continue
+		}
+
+		if c == '}' {
+			return nil, errors.New("unexpected } in the template, use }} for a literal brace")
+		}
+
+		if c != '{' {
+			literal.WriteByte(c)
+			continue
+		}
+
+		end := strings.IndexByte(template[i:], '}')
+		if end < 0 {
+			return nil, errors.New("missing } in the template")
+		}
+
+		if literal.Len() > 0 {
+			result = append(result, renameToken{Literal: literal.String()})
+			literal.Reset()
+		}
+
+		split := strings.SplitN(template[i+1:i+end], ":", 2)
+		token := renameToken{Key: split[0]}
+		if len(split) > 1 {
+			token.Modifier = split[1]
+		}
+
+		err = token.validate()
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, token)
+		i += end
+	}
+
+	if literal.Len() > 0 {
+		result = append(result, renameToken{Literal: literal.String()})
+	}
+
+	return
+}
+
+func (t *renameToken) validate() error {
+	switch t.Key {
+	case "n":
+		if t.Modifier == "" {
+			return nil
+		}
+
+		_, err := strconv.Atoi(t.Modifier)
+		if err != nil {
+			return errors.New("{n:" + t.Modifier + "} should be a number of digits, e.g. {n:03}")
+		}
+
+		return nil
+	case "date", "exif":
+		return nil
+	case "name", "ext", "parent":
+	default:
+		_, err := strconv.Atoi(t.Key)
+		if err != nil {
+			return errors.New("unknown token {" + t.Key + "}")
+		}
+	}
+
+	switch t.Modifier {
+	case "", "upper", "lower", "title":
+		return nil
+	}
+
+	return errors.New("unknown conversion :" + t.Modifier + ", use :upper, :lower or :title")
+}
+
+// Builds the new name of an item. index is the position of the item among the items that are being renamed.
+// getMetadata is only called if the template uses the dates.
+func (p *RenamePattern) Apply(directory string, name string, index int, getMetadata func() *RenameMetadata) (string, error) {
+	if p.Find == nil {
+		return p.expand(directory, name, []string{name}, index, getMetadata)
+	}
+
+	matches := p.Find.FindAllStringSubmatchIndex(name, -1)
+	if len(matches) == 0 {
+		return name, nil
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, match := range matches {
+		groups := make([]string, len(match)/2)
+		for i := range groups {
+			if match[i*2] >= 0 {
+				groups[i] = name[match[i*2]:match[i*2+1]]
+			}
+		}
+
+		value, err := p.expand(directory, name, groups, index, getMetadata)
+		if err != nil {
+			return "", err
+		}
+
+		sb.WriteString(name[last:match[0]])
+		sb.WriteString(value)
+		last = match[1]
+	}
+	sb.WriteString(name[last:])
+
+	return sb.String(), nil
+}
+
+// Upper cases the first letter of every word and lower cases the rest, e.g. "the BEST_of 2024" as "The Best_Of 2024"
+func toTitleCase(value string) string {
+	var sb strings.Builder
+	isWordStart := true
+
+	for _, r := range value {
+		if isWordStart {
+			sb.WriteRune(unicode.ToTitle(r))
+		} else {
+			sb.WriteRune(unicode.ToLower(r))
+		}
+
+		// Apostrophes are part of the word, so "don't" doesn't become "Don'T"
+		isWordStart = !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsMark(r) && r != '\''
+	}
+
+	return sb.String()
+}
+
+func (p *RenamePattern) expand(directory string, name string, groups []string, index int, getMetadata func() *RenameMetadata) (string, error) {
+	var sb strings.Builder
+
+	for _, token := range p.Template {
+		if token.Key == "" {
+			sb.WriteString(token.Literal)
+			continue
+		}
+
+		var value string
+		switch token.Key {
+		case "name":
+			value = strings.TrimSuffix(name, path.Ext(name))
+		case "ext":
+			value = path.Ext(name)
+		case "parent":
+			value = path.Base(directory)
+		case "n":
+			value = strconv.Itoa(index + 1)
+			width, _ := strconv.Atoi(token.Modifier)
+			if len(value) < width {
+				value = strings.Repeat("0", width-len(value)) + value
+			}
+		case "date", "exif":
+			metadata := getMetadata()
+			if metadata.Err != nil {
+				return "", metadata.Err
+			}
+
+			date := metadata.ModTime
+			if token.Key == "exif" {
+				if !metadata.HasExif {
+					return "", errors.New(name + " has no EXIF date")
+				}
+
+				date = metadata.ExifDate
+			}
+
+			format := token.Modifier
+			if format == "" {
+				format = defaultRenameDateFormat
+			}
+
+			value = date.Format(renameDateReplacer.Replace(format))
+		default:
+			group, _ := strconv.Atoi(token.Key)
+			if group >= len(groups) {
+				return "", errors.New("the pattern has no group " + token.Key)
+			}
+
+			value = groups[group]
+		}
+
+		switch token.Modifier {
+		case "upper":
+			value = strings.ToUpper(value)
+		case "lower":
+			value = strings.ToLower(value)
+		case "title":
+			value = toTitleCase(value)
+		}
+
+		sb.WriteString(value)
+	}
+
+	return sb.String(), nil
+}
+
+func ReadRenameMetadata(fullPath string) *RenameMetadata {
+	result := &RenameMetadata{}
+
+	stats, err := os.Stat(fullPath)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.ModTime = stats.ModTime()
+
+	if !stats.IsDir() {
+		exif, err := ReadExif(fullPath)
+		if err == nil {
+			result.ExifDate, result.HasExif = exif.Date()
+		}
+	}
+
+	return result
+}
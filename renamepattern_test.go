@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRenamePattern(t *testing.T) {
+	getMetadata := func() *RenameMetadata {
+		return &RenameMetadata{ModTime: time.Date(2024, 3, 9, 14, 5, 6, 0, time.UTC)}
+	}
+
+	tests := []struct {
+		find     string
+		template string
+		name     string
+		index    int
+		expected string
+	}{
+		{template: "trip_{n:03}{ext}", name: "IMG_1.jpg", index: 6, expected: "trip_007.jpg"},
+		{template: "{n}", name: "a", index: 11, expected: "12"},
+		{template: "{name:title}{ext}", name: "the BEST_of 2024.mp3", expected: "The Best_Of 2024.mp3"},
+		{template: "{name:title}", name: "don't stop", expected: "Don't Stop"},
+		{template: "{name:upper}{ext:lower}", name: "notes.TXT", expected: "NOTES.txt"},
+		{template: "{name}{ext}", name: ".bashrc", expected: ".bashrc"},
+		{template: "{name}{ext}", name: "archive.tar.gz", expected: "archive.tar.gz"},
+		{template: "{ext}", name: "archive.tar.gz", expected: ".gz"},
+		{template: "{parent}_{name}{ext}", name: "a.txt", expected: "photos_a.txt"},
+		{template: "{{{name}}}", name: "a", expected: "{a}"},
+		{template: "{date:YYYYMMDD_hhmmss}{ext}", name: "a.jpg", expected: "20240309_140506.jpg"},
+		{find: `(\d+)-(\d+)`, template: "{2}-{1}", name: "photo 12-34.jpg", expected: "photo 34-12.jpg"},
+		{find: `IMG_(\d+)`, template: "{0}_{n:02}", name: "IMG_5.jpg", expected: "IMG_5_01.jpg"},
+		{find: `x`, template: "y", name: "box.txt", expected: "boy.tyt"},
+		{find: `nothing`, template: "y", name: "box.txt", expected: "box.txt"},
+	}
+
+	for _, test := range tests {
+		pattern, err := ParseRenamePattern(test.find, test.template)
+		if err != nil {
+			t.Errorf("%q, %q: %v", test.find, test.template, err)
+			continue
+		}
+
+		result, err := pattern.Apply("/home/user/photos", test.name, test.index, getMetadata)
+		if err != nil {
+			t.Errorf("%q, %q on %s: %v", test.find, test.template, test.name, err)
+			continue
+		}
+
+		if result != test.expected {
+			t.Errorf("%q, %q on %s: got %q, expected %q", test.find, test.template, test.name, result, test.expected)
+		}
+	}
+}
+
+func TestInvalidRenamePattern(t *testing.T) {
+	tests := []struct {
+		find     string
+		template string
+	}{
+		{template: "{name"},
+		{template: "name}"},
+		{template: "{size}"},
+		{template: "{n:abc}"},
+		{template: "{name:reverse}"},
+		{find: "(", template: "{name}"},
+	}
+
+	for _, test := range tests {
+		_, err := ParseRenamePattern(test.find, test.template)
+		if err == nil {
+			t.Errorf("%q, %q: expected an error", test.find, test.template)
+		}
+	}
+
+	// Groups are only known once the pattern is applied
+	pattern, err := ParseRenamePattern(`(\d+)`, "{2}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = pattern.Apply("/", "a1", 0, nil)
+	if err == nil {
+		t.Error("a missing group should be an error")
+	}
+}
@@ -0,0 +1,456 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/logging"
+)
+
+// Buckets of S3 and of compatible object storages are browsed as file systems. A location is written as
+// s3://bucket/prefix. Object storages don't have folders, so the keys are split at "/" and every common prefix is
+// shown as a folder. Credentials, the region and the endpoint are read the same way the AWS CLI reads them: from the
+// AWS_* environment variables and from ~/.aws/config and ~/.aws/credentials. Setting AWS_ENDPOINT_URL points bonfire
+// to a different storage, such as MinIO or a local test server.
+
+const s3Scheme = "s3://"
+
+// Objects bigger than this are uploaded in parts
+const s3PartSize = 16 * 1024 * 1024
+
+func isS3Location(location string) bool {
+	return strings.HasPrefix(strings.ToLower(location), s3Scheme)
+}
+
+// Splits s3://bucket/prefix into the bucket and the path inside of it
+func ParseS3Location(location string) (bucket string, fullPath string, err error) {
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return
+	}
+
+	if parsed.Scheme != "s3" || parsed.Host == "" {
+		return "", "", errors.New(location + " is not a valid location, expected s3://bucket/prefix")
+	}
+
+	return parsed.Host, path.Clean("/" + parsed.Path), nil
+}
+
+type S3FileSystem struct {
+	Client *s3.Client
+	Bucket string
+}
+
+// Object info with the entity tag S3 computed for the object, which is shown in the info view
+type s3ObjectInfo struct {
+	virtualFileInfo
+	ETag string
+}
+
+var s3Buckets = struct {
+	sync.Mutex
+	open map[string]*S3FileSystem
+}{open: make(map[string]*S3FileSystem)}
+
+func NewS3FileSystem(client *s3.Client, bucket string) *S3FileSystem {
+	return &S3FileSystem{Client: client, Bucket: bucket}
+}
+
+// Returns the file system of the bucket and the path the location points to inside of it. Makes requests to the
+// storage, so it should be called from a job.
+func ConnectS3(location string) (*S3FileSystem, string, error) {
+	bucket, fullPath, err := ParseS3Location(location)
+	if err != nil {
+		return nil, "", err
+	}
+
+	s3Buckets.Lock()
+	existing := s3Buckets.open[bucket]
+	s3Buckets.Unlock()
+
+	if existing != nil {
+		return existing, fullPath, nil
+	}
+
+	ctx := context.Background()
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The SDK writes its warnings to the console otherwise
+	cfg.Logger = logging.Nop{}
+
+	customEndpoint := false
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		// Storages other than AWS usually don't have a DNS name for every bucket
+		if o.BaseEndpoint != nil {
+			customEndpoint = true
+			o.UsePathStyle = true
+		}
+
+		if o.Region == "" {
+			o.Region = "us-east-1"
+		}
+	})
+
+	if cfg.Region == "" && !customEndpoint {
+		region, err := manager.GetBucketRegion(ctx, client, bucket)
+		if err != nil {
+			return nil, "", err
+		}
+
+		client = s3.NewFromConfig(cfg, func(o *s3.Options) {
+			o.Region = region
+		})
+	}
+
+	result := NewS3FileSystem(client, bucket)
+
+	// Fails early if the bucket doesn't exist or the credentials are wrong
+	_, err = result.Client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucket)})
+	if err != nil {
+		return nil, "", err
+	}
+
+	s3Buckets.Lock()
+	s3Buckets.open[bucket] = result
+	s3Buckets.Unlock()
+
+	return result, fullPath, nil
+}
+
+func (s *S3FileSystem) Location() string {
+	return s3Scheme + s.Bucket
+}
+
+// The key of the object at the path, without the leading slash
+func (s *S3FileSystem) getKey(fullPath string) string {
+	return strings.TrimPrefix(path.Clean("/"+fullPath), "/")
+}
+
+// The prefix all the keys inside of the folder at the path start with
+func (s *S3FileSystem) getPrefix(fullPath string) string {
+	key := s.getKey(fullPath)
+	if key == "" {
+		return ""
+	}
+
+	return key + "/"
+}
+
+func (s *S3FileSystem) notFound(op string, fullPath string) error {
+	return &fs.PathError{Op: op, Path: GetFileSystemPath(s, fullPath), Err: fs.ErrNotExist}
+}
+
+func isS3NotFound(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		code := apiErr.ErrorCode()
+		return code == "NotFound" || code == "NoSuchKey"
+	}
+
+	return false
+}
+
+func newS3ObjectInfo(key string, size *int64, modTime *time.Time, etag *string) *s3ObjectInfo {
+	return &s3ObjectInfo{
+		virtualFileInfo: virtualFileInfo{
+			name:    path.Base(key),
+			size:    aws.ToInt64(size),
+			mode:    0644,
+			modTime: aws.ToTime(modTime),
+		},
+		ETag: strings.Trim(aws.ToString(etag), "\""),
+	}
+}
+
+func (s *S3FileSystem) ReadDir(dirPath string) ([]fs.FileInfo, error) {
+	prefix := s.getPrefix(dirPath)
+
+	var result []fs.FileInfo
+	folders := make(map[string]bool)
+	addFolder := func(name string) {
+		if name != "" && !folders[name] {
+			folders[name] = true
+			result = append(result, &virtualFileInfo{name: name, mode: fs.ModeDir | 0755})
+		}
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(s.Client, &s3.ListObjectsV2Input{
+		Bucket:    aws.String(s.Bucket),
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, common := range page.CommonPrefixes {
+			addFolder(strings.TrimSuffix(strings.TrimPrefix(aws.ToString(common.Prefix), prefix), "/"))
+		}
+
+		for _, object := range page.Contents {
+			key := aws.ToString(object.Key)
+
+			// The empty objects that mark folders. Some storages list the ones of the subfolders as objects.
+			if key == prefix {
+				continue
+			}
+
+			if strings.HasSuffix(key, "/") {
+				addFolder(strings.TrimSuffix(strings.TrimPrefix(key, prefix), "/"))
+				continue
+			}
+
+			result = append(result, newS3ObjectInfo(key, object.Size, object.LastModified, object.ETag))
+		}
+	}
+
+	if len(result) == 0 && prefix != "" {
+		// Listing a prefix that doesn't exist isn't an error in S3
+		_, err := s.Stat(dirPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// A path is a folder if any key starts with its prefix, which includes the empty objects that mark folders
+func (s *S3FileSystem) Stat(fullPath string) (fs.FileInfo, error) {
+	key := s.getKey(fullPath)
+	if key == "" {
+		return &virtualFileInfo{name: s.Bucket, mode: fs.ModeDir | 0755}, nil
+	}
+
+	ctx := context.Background()
+	head, err := s.Client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String(s.Bucket), Key: aws.String(key)})
+	if err == nil {
+		return newS3ObjectInfo(key, head.ContentLength, head.LastModified, head.ETag), nil
+	}
+
+	if !isS3NotFound(err) {
+		return nil, err
+	}
+
+	list, err := s.Client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(s.Bucket),
+		Prefix:  aws.String(key + "/"),
+		MaxKeys: aws.Int32(1),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list.Contents) == 0 {
+		return nil, s.notFound("stat", fullPath)
+	}
+
+	return &virtualFileInfo{name: path.Base(key), mode: fs.ModeDir | 0755}, nil
+}
+
+func (s *S3FileSystem) Open(fullPath string) (io.ReadCloser, error) {
+	object, err := s.Client.GetObject(context.Background(), &s3.GetObjectInput{Bucket: aws.String(s.Bucket), Key: aws.String(s.getKey(fullPath))})
+	if err != nil {
+		if isS3NotFound(err) {
+			return nil, s.notFound("open", fullPath)
+		}
+
+		return nil, err
+	}
+
+	return object.Body, nil
+}
+
+// The object is uploaded while it's being written and only appears in the bucket once the writer is closed.
+// Object storages have no permissions, so perm is ignored.
+func (s *S3FileSystem) Create(fullPath string, perm fs.FileMode) (io.WriteCloser, error) {
+	_, err := s.Stat(fullPath)
+	if err == nil {
+		return nil, &fs.PathError{Op: "create", Path: GetFileSystemPath(s, fullPath), Err: fs.ErrExist}
+	}
+
+	reader, writer := io.Pipe()
+	result := &s3Upload{PipeWriter: writer, done: make(chan error, 1)}
+
+	uploader := manager.NewUploader(s.Client, func(u *manager.Uploader) {
+		u.PartSize = s3PartSize
+	})
+
+	go func() {
+		_, err := uploader.Upload(context.Background(), &s3.PutObjectInput{
+			Bucket: aws.String(s.Bucket),
+			Key:    aws.String(s.getKey(fullPath)),
+			Body:   reader,
+		})
+
+		// Unblocks the writer if the upload failed before everything was written
+		reader.CloseWithError(err)
+		result.done <- err
+	}()
+
+	return result, nil
+}
+
+type s3Upload struct {
+	*io.PipeWriter
+	done chan error
+}
+
+// Waits until the upload is finished
+func (u *s3Upload) Close() error {
+	u.PipeWriter.Close()
+	return <-u.done
+}
+
+// Folders only exist while there are objects inside of them, so an empty object is created to keep a new folder
+func (s *S3FileSystem) Mkdir(fullPath string, perm fs.FileMode) error {
+	_, err := s.Stat(fullPath)
+	if err == nil {
+		return &fs.PathError{Op: "mkdir", Path: GetFileSystemPath(s, fullPath), Err: fs.ErrExist}
+	}
+
+	_, err = s.Client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.getPrefix(fullPath)),
+		Body:   strings.NewReader(""),
+	})
+	return err
+}
+
+// S3 can't rename, so every object is copied to its new key and then removed. Objects bigger than 5 GB can't be
+// copied this way.
+func (s *S3FileSystem) Rename(from string, to string) error {
+	_, err := s.Stat(to)
+	if err == nil {
+		return &fs.PathError{Op: "rename", Path: GetFileSystemPath(s, to), Err: fs.ErrExist}
+	}
+
+	stats, err := s.Stat(from)
+	if err != nil {
+		return err
+	}
+
+	if !stats.IsDir() {
+		err = s.copyObject(s.getKey(from), s.getKey(to))
+		if err != nil {
+			return err
+		}
+
+		return s.deleteKeys([]string{s.getKey(from)})
+	}
+
+	keys, err := s.listKeys(s.getPrefix(from))
+	if err != nil {
+		return err
+	}
+
+	fromPrefix := s.getPrefix(from)
+	toPrefix := s.getPrefix(to)
+	for _, key := range keys {
+		err = s.copyObject(key, toPrefix+strings.TrimPrefix(key, fromPrefix))
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.deleteKeys(keys)
+}
+
+func (s *S3FileSystem) copyObject(from string, to string) error {
+	source := (&url.URL{Path: s.Bucket + "/" + from}).EscapedPath()
+	_, err := s.Client.CopyObject(context.Background(), &s3.CopyObjectInput{
+		Bucket:     aws.String(s.Bucket),
+		Key:        aws.String(to),
+		CopySource: aws.String(source),
+	})
+	return err
+}
+
+// Lists every key that starts with the prefix, including the ones inside of nested folders
+func (s *S3FileSystem) listKeys(prefix string) ([]string, error) {
+	var result []string
+
+	paginator := s3.NewListObjectsV2Paginator(s.Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.Bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range page.Contents {
+			result = append(result, aws.ToString(object.Key))
+		}
+	}
+
+	return result, nil
+}
+
+// Deletes the objects in batches of 1000, the most a single request can delete
+func (s *S3FileSystem) deleteKeys(keys []string) error {
+	for start := 0; start < len(keys); start += 1000 {
+		end := min(start+1000, len(keys))
+
+		objects := make([]types.ObjectIdentifier, 0, end-start)
+		for _, key := range keys[start:end] {
+			objects = append(objects, types.ObjectIdentifier{Key: aws.String(key)})
+		}
+
+		output, err := s.Client.DeleteObjects(context.Background(), &s3.DeleteObjectsInput{
+			Bucket: aws.String(s.Bucket),
+			Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(output.Errors) > 0 {
+			failed := output.Errors[0]
+			return errors.New("can't delete " + s.Location() + "/" + aws.ToString(failed.Key) + ": " + aws.ToString(failed.Message))
+		}
+	}
+
+	return nil
+}
+
+func (s *S3FileSystem) RemoveAll(fullPath string) error {
+	key := s.getKey(fullPath)
+	if key == "" {
+		return errors.New("the whole bucket can't be removed")
+	}
+
+	keys, err := s.listKeys(key + "/")
+	if err != nil {
+		return err
+	}
+
+	// Removing an object that doesn't exist isn't an error, so the key is removed whether it's an object or not
+	return s.deleteKeys(append(keys, key))
+}
+
+// The modification time of an object is the time it was uploaded and can't be changed
+func (s *S3FileSystem) Chtimes(fullPath string, modTime time.Time) error {
+	return nil
+}
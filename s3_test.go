@@ -0,0 +1,352 @@
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A bucket of an S3 compatible storage that keeps the objects in memory. Only the requests bonfire makes are
+// answered, with path style addresses.
+type fakeS3 struct {
+	mutex   sync.Mutex
+	bucket  string
+	objects map[string][]byte
+	modTime time.Time
+}
+
+type fakeS3Object struct {
+	Key          string
+	LastModified string
+	ETag         string
+	Size         int64
+}
+
+type fakeS3Prefix struct {
+	Prefix string
+}
+
+type fakeS3List struct {
+	XMLName        xml.Name `xml:"ListBucketResult"`
+	Name           string
+	Prefix         string
+	Delimiter      string
+	MaxKeys        int
+	KeyCount       int
+	IsTruncated    bool
+	Contents       []fakeS3Object
+	CommonPrefixes []fakeS3Prefix
+}
+
+type fakeS3Delete struct {
+	Objects []struct {
+		Key string
+	} `xml:"Object"`
+}
+
+func newFakeS3(bucket string) *fakeS3 {
+	return &fakeS3{
+		bucket:  bucket,
+		objects: make(map[string][]byte),
+		modTime: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+func fakeS3ETag(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		f.writeError(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	query := r.URL.Query()
+
+	switch {
+	case key == "" && r.Method == http.MethodHead:
+	case key == "" && r.Method == http.MethodGet && query.Get("list-type") == "2":
+		f.list(w, query.Get("prefix"), query.Get("delimiter"), query.Get("max-keys"))
+	case key == "" && r.Method == http.MethodPost && query.Has("delete"):
+		var request fakeS3Delete
+		err := xml.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			f.writeError(w, http.StatusBadRequest, "MalformedXML")
+			return
+		}
+
+		for _, object := range request.Objects {
+			delete(f.objects, object.Key)
+		}
+
+		w.Write([]byte("<DeleteResult></DeleteResult>"))
+	case key != "" && r.Method == http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			f.writeError(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+
+		if strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+			data = decodeAWSChunked(data)
+		}
+
+		f.objects[key] = data
+		w.Header().Set("ETag", "\""+fakeS3ETag(data)+"\"")
+	case key != "" && (r.Method == http.MethodGet || r.Method == http.MethodHead):
+		data, ok := f.objects[key]
+		if !ok {
+			f.writeError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Header().Set("ETag", "\""+fakeS3ETag(data)+"\"")
+		w.Header().Set("Last-Modified", f.modTime.Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	default:
+		f.writeError(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, prefix string, delimiter string, maxKeys string) {
+	result := fakeS3List{Name: f.bucket, Prefix: prefix, Delimiter: delimiter, MaxKeys: 1000}
+	if value, err := strconv.Atoi(maxKeys); err == nil {
+		result.MaxKeys = value
+	}
+
+	keys := make([]string, 0, len(f.objects))
+	for key := range f.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	prefixes := make(map[string]bool)
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) || result.KeyCount >= result.MaxKeys {
+			continue
+		}
+
+		rest := strings.TrimPrefix(key, prefix)
+		if index := strings.Index(rest, delimiter); delimiter != "" && index >= 0 {
+			common := prefix + rest[:index+len(delimiter)]
+			if !prefixes[common] {
+				prefixes[common] = true
+				result.CommonPrefixes = append(result.CommonPrefixes, fakeS3Prefix{common})
+				result.KeyCount++
+			}
+			continue
+		}
+
+		data := f.objects[key]
+		result.Contents = append(result.Contents, fakeS3Object{
+			Key:          key,
+			LastModified: f.modTime.Format("2006-01-02T15:04:05.000Z"),
+			ETag:         "\"" + fakeS3ETag(data) + "\"",
+			Size:         int64(len(data)),
+		})
+		result.KeyCount++
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+func (f *fakeS3) writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	w.Write([]byte("<Error><Code>" + code + "</Code><Message>" + code + "</Message></Error>"))
+}
+
+// Streamed uploads are sent in chunks, each a hexadecimal size and the data, followed by the trailing checksums
+func decodeAWSChunked(data []byte) (result []byte) {
+	for {
+		line, rest, found := bytes.Cut(data, []byte("\r\n"))
+		if !found {
+			return
+		}
+
+		sizeText, _, _ := strings.Cut(string(line), ";")
+		size, err := strconv.ParseInt(sizeText, 16, 64)
+		if err != nil || size == 0 || int64(len(rest)) < size {
+			return
+		}
+
+		result = append(result, rest[:size]...)
+		data = bytes.TrimPrefix(rest[size:], []byte("\r\n"))
+	}
+}
+
+func startFakeS3(t *testing.T, bucket string) *fakeS3 {
+	t.Helper()
+
+	storage := newFakeS3(bucket)
+	server := httptest.NewServer(storage)
+	t.Cleanup(server.Close)
+
+	// Keeps the settings of the machine the tests run on out of the way
+	directory := t.TempDir()
+	t.Setenv("AWS_ENDPOINT_URL", server.URL)
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_CONFIG_FILE", path.Join(directory, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", path.Join(directory, "credentials"))
+	t.Setenv("AWS_PROFILE", "")
+
+	t.Cleanup(func() {
+		s3Buckets.Lock()
+		delete(s3Buckets.open, bucket)
+		s3Buckets.Unlock()
+	})
+
+	return storage
+}
+
+func TestS3FileSystem(t *testing.T) {
+	storage := startFakeS3(t, "photos")
+	storage.objects["top.txt"] = []byte("top")
+	storage.objects["trips/notes.txt"] = []byte("hello")
+	storage.objects["trips/paris/1.jpg"] = []byte("jpeg")
+	storage.objects["trips/rome/"] = nil
+
+	fsys, fullPath, err := ConnectS3("s3://photos/trips")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fullPath != "/trips" {
+		t.Fatalf("got the path %s, expected /trips", fullPath)
+	}
+
+	// The common prefixes and the empty objects that mark folders are listed as folders
+	items, err := fsys.ReadDir(fullPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listed := make(map[string]fs.FileInfo)
+	for _, item := range items {
+		listed[item.Name()] = item
+	}
+
+	if len(listed) != 3 || !listed["paris"].IsDir() || !listed["rome"].IsDir() || listed["notes.txt"].IsDir() {
+		t.Fatalf("unexpected items %v", listed)
+	}
+
+	if listed["notes.txt"].Size() != 5 {
+		t.Fatalf("notes.txt has the size %d, expected 5", listed["notes.txt"].Size())
+	}
+
+	_, err = fsys.ReadDir("/missing")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("listing a missing folder returned %v", err)
+	}
+
+	// Upload
+	file, err := fsys.Create("/trips/new.txt", 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = io.WriteString(file, "uploaded")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storage.mutex.Lock()
+	uploaded := string(storage.objects["trips/new.txt"])
+	storage.mutex.Unlock()
+
+	if uploaded != "uploaded" {
+		t.Fatalf("uploaded %q, expected \"uploaded\"", uploaded)
+	}
+
+	_, err = fsys.Create("/trips/new.txt", 0644)
+	if !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("creating an existing object returned %v", err)
+	}
+
+	// Download
+	reader, err := fsys.Open("/trips/notes.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("downloaded %q, %v", data, err)
+	}
+
+	_, err = fsys.Open("/trips/missing.txt")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("opening a missing object returned %v", err)
+	}
+
+	// Info
+	view := &ItemView{FS: fsys}
+	info := view.getFileSystemInfo("/trips/notes.txt")
+
+	rows := make(map[string]string)
+	for _, row := range info.Rows {
+		rows[row.Label] = row.Value
+	}
+
+	expected := map[string]string{
+		"Size":     bytesToString(5),
+		"Modified": "2024-01-02 03:04:05",
+		"ETag":     fakeS3ETag([]byte("hello")),
+	}
+
+	for label, value := range expected {
+		if rows[label] != value {
+			t.Errorf("the %s row is %q, expected %q", label, rows[label], value)
+		}
+	}
+
+	// Delete
+	err = fsys.RemoveAll("/trips/paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = fsys.Stat("/trips/paris")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("the removed folder returned %v", err)
+	}
+
+	storage.mutex.Lock()
+	_, kept := storage.objects["trips/notes.txt"]
+	storage.mutex.Unlock()
+
+	if !kept {
+		t.Fatal("removing a folder removed the object next to it")
+	}
+}
@@ -3,22 +3,29 @@ package main
 import (
 	"os"
 	"path"
+	"strconv"
 	"strings"
 )
 
 type Settings struct {
-	Favorites []string
-	ThemeName string
+	Favorites   []string
+	ThemeName   string
+	FollowLinks bool     // Opening a link opens its target instead of jumping to it
+	FindIgnored []string // The folders the recursive find doesn't enter, by name or by pattern
 }
 
+var defaultFindIgnored = []string{".git", "node_modules"}
+
 func NewSettings() Settings {
 	dir, err := os.UserConfigDir()
 	if err != nil {
 		NotifyError(err.Error())
 
 		return Settings{
-			Favorites: []string{},
-			ThemeName: "terminal",
+			Favorites:   []string{},
+			ThemeName:   "terminal",
+			FollowLinks: true,
+			FindIgnored: defaultFindIgnored,
 		}
 	}
 
@@ -30,8 +37,10 @@ func NewSettings() Settings {
 	}
 
 	result := Settings{
-		Favorites: []string{},
-		ThemeName: "terminal",
+		Favorites:   []string{},
+		ThemeName:   "terminal",
+		FollowLinks: true,
+		FindIgnored: defaultFindIgnored,
 	}
 
 	result.Save(true)
@@ -40,6 +49,9 @@ func NewSettings() Settings {
 }
 
 func loadSettings(fullPath string) (result Settings) {
+	result.FollowLinks = true
+	result.FindIgnored = defaultFindIgnored
+
 	data := ReadFile(fullPath)
 
 	lines := strings.Split(data, "\n")
@@ -54,6 +66,10 @@ func loadSettings(fullPath string) (result Settings) {
 			result.Favorites = append(result.Favorites, line[10:])
 		} else if strings.HasPrefix(line, ":theme") {
 			result.ThemeName = line[7:]
+		} else if strings.HasPrefix(line, ":follow_links") {
+			result.FollowLinks = strings.TrimSpace(line[13:]) != "false"
+		} else if strings.HasPrefix(line, ":find_ignore") {
+			result.FindIgnored = strings.Fields(line[12:])
 		}
 	}
 
@@ -72,6 +88,14 @@ func (s *Settings) Save(createFolder bool) {
 	sb.WriteString(s.ThemeName)
 	sb.WriteString("\n")
 
+	sb.WriteString(":follow_links ")
+	sb.WriteString(strconv.FormatBool(s.FollowLinks))
+	sb.WriteString("\n")
+
+	sb.WriteString(":find_ignore ")
+	sb.WriteString(strings.Join(s.FindIgnored, " "))
+	sb.WriteString("\n")
+
 	for _, favorite := range s.Favorites {
 		sb.WriteString(":favorite ")
 		sb.WriteString(favorite)
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Remote folders are browsed over SFTP. A remote location is written as sftp://user@host:port/path, the user, the
+// port and the path are optional. Connections are authenticated with the keys in ssh-agent or with the default key
+// files in ~/.ssh, and the key of the host has to be in ~/.ssh/known_hosts already. A connection is shared by all
+// the views that show the same host and stays open until bonfire is closed.
+
+const sftpScheme = "sftp://"
+
+type RemoteLocation struct {
+	User string
+	Host string
+	Port string
+	Path string // Empty for the home folder of the user
+}
+
+func isSFTPLocation(location string) bool {
+	return strings.HasPrefix(strings.ToLower(location), sftpScheme)
+}
+
+func ParseRemoteLocation(location string) (result RemoteLocation, err error) {
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return
+	}
+
+	if parsed.Scheme != "sftp" || parsed.Hostname() == "" {
+		return result, errors.New(location + " is not a valid location, expected sftp://user@host/path")
+	}
+
+	result.Host = parsed.Hostname()
+	result.Port = parsed.Port()
+	if result.Port == "" {
+		result.Port = "22"
+	}
+
+	result.User = parsed.User.Username()
+	if result.User == "" {
+		current, err := user.Current()
+		if err != nil {
+			return result, err
+		}
+
+		// Windows user names include the domain
+		result.User = current.Username[strings.LastIndex(current.Username, "\\")+1:]
+	}
+
+	if parsed.Path != "" {
+		result.Path = path.Clean(parsed.Path)
+	}
+
+	return
+}
+
+// The part of the location that identifies the connection, e.g. sftp://user@host:22
+func (r RemoteLocation) Connection() string {
+	return sftpScheme + r.User + "@" + net.JoinHostPort(r.Host, r.Port)
+}
+
+type SFTPFileSystem struct {
+	Client *sftp.Client
+
+	location string
+	conn     io.Closer
+}
+
+// Every connection that is being made has a channel, which is closed once it is made, so that a view waits for the
+// connection another view is making to the same host instead of making one more
+var sftpConnections = struct {
+	sync.Mutex
+	open       map[string]*SFTPFileSystem
+	connecting map[string]chan struct{}
+}{open: make(map[string]*SFTPFileSystem), connecting: make(map[string]chan struct{})}
+
+// Wraps an established SFTP session. conn is closed together with the client and may be nil.
+func NewSFTPFileSystem(location string, client *sftp.Client, conn io.Closer) *SFTPFileSystem {
+	return &SFTPFileSystem{Client: client, location: location, conn: conn}
+}
+
+// Returns the open connection to the host of the location, or connects to it. Blocks until the connection is made,
+// so it should be called from a job.
+func ConnectSFTP(remote RemoteLocation) (*SFTPFileSystem, error) {
+	return connectSFTP(remote.Connection(), func(key string) (*SFTPFileSystem, error) {
+		return dialSFTP(key, remote)
+	})
+}
+
+// Returns the open connection with the key, or makes it with dial
+func connectSFTP(key string, dial func(key string) (*SFTPFileSystem, error)) (*SFTPFileSystem, error) {
+	sftpConnections.Lock()
+	for sftpConnections.connecting[key] != nil {
+		connecting := sftpConnections.connecting[key]
+		sftpConnections.Unlock()
+		<-connecting
+		sftpConnections.Lock()
+	}
+
+	existing := sftpConnections.open[key]
+	connecting := make(chan struct{})
+	sftpConnections.connecting[key] = connecting
+	sftpConnections.Unlock()
+
+	defer func() {
+		sftpConnections.Lock()
+		delete(sftpConnections.connecting, key)
+		sftpConnections.Unlock()
+		close(connecting)
+	}()
+
+	if existing != nil {
+		// The server might have closed the connection since it was used last time
+		_, err := existing.Client.Getwd()
+		if err == nil {
+			return existing, nil
+		}
+
+		existing.Close()
+	}
+
+	result, err := dial(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sftpConnections.Lock()
+	sftpConnections.open[key] = result
+	sftpConnections.Unlock()
+
+	return result, nil
+}
+
+func dialSFTP(key string, remote RemoteLocation) (*SFTPFileSystem, error) {
+	config, agentConn, err := getSSHConfig(remote.User)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := ssh.Dial("tcp", net.JoinHostPort(remote.Host, remote.Port), config)
+	if agentConn != nil {
+		// The agent is only needed for signing in
+		agentConn.Close()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return NewSFTPFileSystem(key, client, conn), nil
+}
+
+// Closes every connection, called when bonfire is closed
+func CloseSFTPConnections() {
+	sftpConnections.Lock()
+	open := sftpConnections.open
+	sftpConnections.open = make(map[string]*SFTPFileSystem)
+	sftpConnections.Unlock()
+
+	for _, connection := range open {
+		connection.Close()
+	}
+}
+
+// The returned connection to ssh-agent, if there is one, has to stay open until the client signs in
+func getSSHConfig(username string) (*ssh.ClientConfig, net.Conn, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	knownHostsPath := path.Join(home, ".ssh", "known_hosts")
+	hostKeyCallback, err := knownhosts.New(knownHostsPath)
+	if err != nil {
+		return nil, nil, errors.New("can't read " + knownHostsPath + ", connect to the host with ssh once to add its key")
+	}
+
+	var signers []ssh.Signer
+	var agentConn net.Conn
+
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket != "" {
+		agentConn, err = net.Dial("unix", socket)
+		if err == nil {
+			agentSigners, err := agent.NewClient(agentConn).Signers()
+			if err == nil {
+				signers = append(signers, agentSigners...)
+			}
+		} else {
+			agentConn = nil
+		}
+	}
+
+	// Keys protected with a passphrase can only be used through the agent
+	for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+		data, err := os.ReadFile(path.Join(home, ".ssh", name))
+		if err != nil {
+			continue
+		}
+
+		signer, err := ssh.ParsePrivateKey(data)
+		if err != nil {
+			continue
+		}
+
+		signers = append(signers, signer)
+	}
+
+	if len(signers) == 0 {
+		if agentConn != nil {
+			agentConn.Close()
+		}
+
+		return nil, nil, errors.New("no SSH keys found, start ssh-agent or add a key to ~/.ssh")
+	}
+
+	return &ssh.ClientConfig{
+		User:            username,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signers...)},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         15 * time.Second,
+	}, agentConn, nil
+}
+
+func (s *SFTPFileSystem) Close() error {
+	sftpConnections.Lock()
+	if sftpConnections.open[s.location] == s {
+		delete(sftpConnections.open, s.location)
+	}
+	sftpConnections.Unlock()
+
+	err := s.Client.Close()
+	if s.conn != nil {
+		s.conn.Close()
+	}
+
+	return err
+}
+
+func (s *SFTPFileSystem) Location() string {
+	return s.location
+}
+
+// Links are listed as links, the view resolves them
+func (s *SFTPFileSystem) ReadDir(dirPath string) ([]fs.FileInfo, error) {
+	return s.Client.ReadDir(dirPath)
+}
+
+// Follows links, so links to folders can be opened like folders
+func (s *SFTPFileSystem) Stat(fullPath string) (fs.FileInfo, error) {
+	return s.Client.Stat(fullPath)
+}
+
+func (s *SFTPFileSystem) ReadLink(fullPath string) (string, error) {
+	return s.Client.ReadLink(fullPath)
+}
+
+func (s *SFTPFileSystem) Open(fullPath string) (io.ReadCloser, error) {
+	return s.Client.Open(fullPath)
+}
+
+func (s *SFTPFileSystem) Create(fullPath string, perm fs.FileMode) (io.WriteCloser, error) {
+	file, err := s.Client.OpenFile(fullPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL)
+	if err != nil {
+		return nil, err
+	}
+
+	err = file.Chmod(perm)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func (s *SFTPFileSystem) Mkdir(fullPath string, perm fs.FileMode) error {
+	err := s.Client.Mkdir(fullPath)
+	if err != nil {
+		return err
+	}
+
+	return s.Client.Chmod(fullPath, perm)
+}
+
+// SFTP servers refuse to rename over an existing item, which is what the other operations expect
+func (s *SFTPFileSystem) Rename(from string, to string) error {
+	return s.Client.Rename(from, to)
+}
+
+// Links are removed without touching what they point to, the RemoveAll of the client would empty the folders they
+// point to
+func (s *SFTPFileSystem) RemoveAll(fullPath string) error {
+	stats, err := s.Client.Lstat(fullPath)
+	if err != nil {
+		return err
+	}
+
+	if !stats.IsDir() {
+		return s.Client.Remove(fullPath)
+	}
+
+	items, err := s.Client.ReadDir(fullPath)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, item := range items {
+		err = s.RemoveAll(path.Join(fullPath, item.Name()))
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return joinErrors(errs)
+	}
+
+	return s.Client.RemoveDirectory(fullPath)
+}
+
+func (s *SFTPFileSystem) Chtimes(fullPath string, modTime time.Time) error {
+	return s.Client.Chtimes(fullPath, modTime, modTime)
+}
+
+// Resolves the home folder of the user when the location doesn't have a path
+func (s *SFTPFileSystem) GetStartPath(remote RemoteLocation) (string, error) {
+	if remote.Path != "" {
+		return remote.Path, nil
+	}
+
+	return s.Client.Getwd()
+}
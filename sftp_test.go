@@ -0,0 +1,299 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// Serves the local disk over SFTP on a port of the loopback interface until the test ends, and returns a function
+// that connects to it
+func startSFTPServer(t *testing.T) func(key string) (*SFTPFileSystem, error) {
+	t.Helper()
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hostKey, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go serveSFTP(conn, config)
+		}
+	}()
+
+	return func(key string) (*SFTPFileSystem, error) {
+		conn, err := ssh.Dial("tcp", listener.Addr().String(), &ssh.ClientConfig{
+			User:            "test",
+			HostKeyCallback: ssh.FixedHostKey(hostKey.PublicKey()),
+			Timeout:         5 * time.Second,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		client, err := sftp.NewClient(conn)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		return NewSFTPFileSystem(key, client, conn), nil
+	}
+}
+
+func serveSFTP(conn net.Conn, config *ssh.ServerConfig) {
+	serverConn, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	defer serverConn.Close()
+
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+
+		go func() {
+			defer channel.Close()
+
+			for request := range requests {
+				// The payload of a subsystem request is the length of the name followed by the name
+				isSFTP := request.Type == "subsystem" && len(request.Payload) > 4 && string(request.Payload[4:]) == "sftp"
+				request.Reply(isSFTP, nil)
+				if !isSFTP {
+					continue
+				}
+
+				server, err := sftp.NewServer(channel)
+				if err != nil {
+					return
+				}
+
+				server.Serve()
+				return
+			}
+		}()
+	}
+}
+
+func TestConnectSFTPSharesConnection(t *testing.T) {
+	connect := startSFTPServer(t)
+	key := "sftp://test@shared:22"
+	t.Cleanup(CloseSFTPConnections)
+
+	var dials atomic.Int32
+	dial := func(key string) (*SFTPFileSystem, error) {
+		dials.Add(1)
+		return connect(key)
+	}
+
+	const views = 8
+	results := make([]*SFTPFileSystem, views)
+	errs := make([]error, views)
+
+	var wg sync.WaitGroup
+	for index := range views {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[index], errs[index] = connectSFTP(key, dial)
+		}()
+	}
+	wg.Wait()
+
+	for index := range views {
+		if errs[index] != nil {
+			t.Fatal(errs[index])
+		}
+
+		if results[index] != results[0] {
+			t.Fatalf("view %d got a connection of its own", index)
+		}
+	}
+
+	if dials.Load() != 1 {
+		t.Fatalf("connected %d times, expected once", dials.Load())
+	}
+
+	// A closed connection is made again
+	results[0].Client.Close()
+
+	result, err := connectSFTP(key, dial)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result == results[0] || dials.Load() != 2 {
+		t.Fatalf("the closed connection was used again")
+	}
+}
+
+func TestSFTPFileSystem(t *testing.T) {
+	connect := startSFTPServer(t)
+
+	fsys, err := connect("sftp://test@files:22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	directory := t.TempDir()
+	folder := path.Join(directory, "folder")
+
+	err = fsys.Mkdir(folder, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := fsys.Create(path.Join(folder, "a.txt"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = io.WriteString(file, "hello")
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = fsys.Rename(path.Join(folder, "a.txt"), path.Join(folder, "b.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := fsys.ReadDir(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(items) != 1 || items[0].Name() != "b.txt" || items[0].Size() != 5 {
+		t.Fatalf("unexpected items %v", items)
+	}
+
+	reader, err := fsys.Open(path.Join(folder, "b.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil || string(data) != "hello" {
+		t.Fatalf("read %q, %v", data, err)
+	}
+
+	err = fsys.RemoveAll(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = fsys.Stat(folder)
+	if err == nil {
+		t.Fatal("the folder wasn't removed")
+	}
+}
+
+func TestSFTPRemoveAllKeepsLinkTargets(t *testing.T) {
+	connect := startSFTPServer(t)
+
+	fsys, err := connect("sftp://test@links:22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	directory := t.TempDir()
+	target := path.Join(directory, "target")
+	err = os.MkdirAll(path.Join(target, "inside"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(path.Join(target, "inside", "a.txt"), []byte("a"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	folder := path.Join(directory, "folder")
+	err = os.Mkdir(folder, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, link := range []string{path.Join(directory, "link"), path.Join(folder, "link")} {
+		err = os.Symlink(target, link)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Links are listed as links
+	items, err := fsys.ReadDir(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, item := range items {
+		if item.Name() == "link" && item.Mode()&fs.ModeSymlink == 0 {
+			t.Fatalf("the link is listed with the mode %v", item.Mode())
+		}
+	}
+
+	// Removing the link itself and a folder with a link inside of it
+	for _, fullPath := range []string{path.Join(directory, "link"), folder} {
+		err = fsys.RemoveAll(fullPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = os.Lstat(fullPath)
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("%s wasn't removed: %v", fullPath, err)
+		}
+
+		data, err := os.ReadFile(path.Join(target, "inside", "a.txt"))
+		if err != nil || string(data) != "a" {
+			t.Fatalf("removing %s changed the folder the link points to: %v", fullPath, err)
+		}
+	}
+}
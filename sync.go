@@ -0,0 +1,467 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Syncing makes the folder of the other view match the folder of the active view, or, when syncing both ways, copies
+// the items that are missing on either side and replaces the older copy of the files that changed. Deletions can't
+// be told apart from new items without knowing what the folders looked like before, so syncing both ways never
+// deletes anything. The plan is made first and shown to the user, nothing is changed before it's confirmed.
+//
+// Files are compared by their size and modification time, the same way views are compared (see compare.go). The
+// items that are excluded by the patterns are neither copied nor deleted.
+
+type SyncActionType int32
+
+const (
+	SyncCreateFolder SyncActionType = iota
+	SyncCopy
+	SyncOverwrite
+	SyncDelete
+	SyncConflict // Changed on both sides, left alone
+)
+
+type SyncAction struct {
+	Type     SyncActionType
+	Source   string // Empty for deletions
+	Target   string
+	Relative string // The path inside of the synced folders, shown in the plan
+	Files    int64  // What copying the source takes
+	Bytes    int64
+}
+
+type SyncPlan struct {
+	Source string
+	Target string
+	TwoWay bool
+
+	Actions []SyncAction
+	Files   int64
+	Bytes   int64
+}
+
+func (p *SyncPlan) Count(actionType SyncActionType) (result int) {
+	for _, action := range p.Actions {
+		if action.Type == actionType {
+			result++
+		}
+	}
+
+	return
+}
+
+// Patterns without a slash are matched against the names of the items, the rest against their paths inside of the
+// synced folders, e.g. "*.tmp" or "build/*". Include patterns only apply to files, so that folders are still entered.
+type SyncFilter struct {
+	Include []string
+	Exclude []string
+}
+
+func (f *SyncFilter) IsEmpty() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// Splits a list of patterns separated by spaces or commas
+func ParseSyncPatterns(value string) ([]string, error) {
+	patterns := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ' ' || r == ','
+	})
+
+	for _, pattern := range patterns {
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return nil, errors.New("invalid pattern " + pattern)
+		}
+	}
+
+	return patterns, nil
+}
+
+func matchSyncPatterns(patterns []string, relative string) bool {
+	for _, pattern := range patterns {
+		name := relative
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(relative)
+		}
+
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (f *SyncFilter) allows(relative string, isFolder bool) bool {
+	if matchSyncPatterns(f.Exclude, relative) {
+		return false
+	}
+
+	return isFolder || len(f.Include) == 0 || matchSyncPatterns(f.Include, relative)
+}
+
+type syncPlanner struct {
+	plan   *SyncPlan
+	filter SyncFilter
+	job    *Job
+}
+
+// Plans syncing target with source. Nothing is changed on the disk.
+func PlanSync(source string, target string, twoWay bool, filter SyncFilter, job *Job) (*SyncPlan, error) {
+	if IsSubPath(source, target) || IsSubPath(target, source) {
+		return nil, errors.New("cannot sync a folder with a folder inside of it")
+	}
+
+	planner := &syncPlanner{
+		plan:   &SyncPlan{Source: source, Target: target, TwoWay: twoWay},
+		filter: filter,
+		job:    job,
+	}
+
+	err := planner.planFolder("")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, action := range planner.plan.Actions {
+		planner.plan.Files += action.Files
+		planner.plan.Bytes += action.Bytes
+	}
+
+	return planner.plan, nil
+}
+
+func readSyncFolder(fullPath string) (map[string]fs.FileInfo, error) {
+	entries, err := os.ReadDir(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]fs.FileInfo, len(entries))
+	for _, entry := range entries {
+		// Links are synced as links, so they are not followed
+		stats, err := entry.Info()
+		if err == nil {
+			result[entry.Name()] = stats
+		}
+	}
+
+	return result, nil
+}
+
+func getSortedNames(items map[string]fs.FileInfo) []string {
+	result := make([]string, 0, len(items))
+	for name := range items {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+func (p *syncPlanner) add(actionType SyncActionType, source string, target string, relative string) {
+	action := SyncAction{Type: actionType, Source: source, Target: target, Relative: relative}
+	if actionType == SyncCopy || actionType == SyncOverwrite {
+		action.Files, action.Bytes = MeasureItem(source)
+	}
+
+	p.plan.Actions = append(p.plan.Actions, action)
+}
+
+// Plans a folder that exists on both sides
+func (p *syncPlanner) planFolder(relative string) error {
+	err := p.job.Checkpoint()
+	if err != nil {
+		return err
+	}
+
+	sourcePath := path.Join(p.plan.Source, relative)
+	targetPath := path.Join(p.plan.Target, relative)
+
+	sourceItems, err := readSyncFolder(sourcePath)
+	if err != nil {
+		return err
+	}
+
+	targetItems, err := readSyncFolder(targetPath)
+	if err != nil {
+		return err
+	}
+
+	names := getSortedNames(sourceItems)
+	for _, name := range getSortedNames(targetItems) {
+		if _, ok := sourceItems[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		itemRelative := path.Join(relative, name)
+		source := path.Join(sourcePath, name)
+		target := path.Join(targetPath, name)
+
+		sourceStats, inSource := sourceItems[name]
+		targetStats, inTarget := targetItems[name]
+
+		isFolder := inSource && sourceStats.IsDir() || !inSource && targetStats.IsDir()
+		if !p.filter.allows(itemRelative, isFolder) {
+			continue
+		}
+
+		switch {
+		case !inTarget:
+			err = p.planCopy(source, target, itemRelative, sourceStats.IsDir())
+		case !inSource && p.plan.TwoWay:
+			err = p.planCopy(target, source, itemRelative, targetStats.IsDir())
+		case !inSource:
+			_, err = p.planDelete(target, itemRelative, targetStats.IsDir())
+		case sourceStats.IsDir() && targetStats.IsDir():
+			err = p.planFolder(itemRelative)
+		default:
+			p.planChanged(source, target, itemRelative, sourceStats, targetStats)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Plans an item that exists on both sides and isn't a folder on both of them
+func (p *syncPlanner) planChanged(source string, target string, relative string, sourceStats fs.FileInfo, targetStats fs.FileInfo) {
+	if isSameSyncItem(source, target, sourceStats, targetStats) {
+		return
+	}
+
+	if !p.plan.TwoWay {
+		p.add(SyncOverwrite, source, target, relative)
+		return
+	}
+
+	// The newer copy wins. A file and a folder with the same name can't be sorted out automatically.
+	state := compareStats(sourceStats, targetStats)
+	switch {
+	case sourceStats.IsDir() != targetStats.IsDir():
+		p.add(SyncConflict, source, target, relative)
+	case state == CompareNewer:
+		p.add(SyncOverwrite, source, target, relative)
+	case state == CompareOlder:
+		p.add(SyncOverwrite, target, source, relative)
+	default:
+		p.add(SyncConflict, source, target, relative)
+	}
+}
+
+func isSameSyncItem(source string, target string, sourceStats fs.FileInfo, targetStats fs.FileInfo) bool {
+	if sourceStats.Mode().Type() != targetStats.Mode().Type() {
+		return false
+	}
+
+	if sourceStats.Mode()&fs.ModeSymlink != 0 {
+		sourceLink, sourceErr := os.Readlink(source)
+		targetLink, targetErr := os.Readlink(target)
+		return sourceErr == nil && targetErr == nil && sourceLink == targetLink
+	}
+
+	return compareStats(sourceStats, targetStats) == CompareSame
+}
+
+// Plans copying an item that only exists on one side. Folders are copied as a whole, unless some of the items inside
+// of them might be excluded, in which case the folder is created and its items are planned one by one.
+func (p *syncPlanner) planCopy(source string, target string, relative string, isFolder bool) error {
+	if !isFolder || p.filter.IsEmpty() {
+		p.add(SyncCopy, source, target, relative)
+		return nil
+	}
+
+	err := p.job.Checkpoint()
+	if err != nil {
+		return err
+	}
+
+	items, err := readSyncFolder(source)
+	if err != nil {
+		return err
+	}
+
+	first := len(p.plan.Actions)
+	p.add(SyncCreateFolder, source, target, relative)
+
+	for _, name := range getSortedNames(items) {
+		itemRelative := path.Join(relative, name)
+		if !p.filter.allows(itemRelative, items[name].IsDir()) {
+			continue
+		}
+
+		err = p.planCopy(path.Join(source, name), path.Join(target, name), itemRelative, items[name].IsDir())
+		if err != nil {
+			return err
+		}
+	}
+
+	// Everything inside of the folder is left out, so there's no need to create it
+	if len(items) > 0 && len(p.plan.Actions) == first+1 {
+		p.plan.Actions = p.plan.Actions[:first]
+	}
+
+	return nil
+}
+
+// Plans deleting an item that only exists in the target. A folder is only deleted as a whole if none of the items
+// inside of it are excluded. Returns true if the whole item is deleted.
+func (p *syncPlanner) planDelete(target string, relative string, isFolder bool) (bool, error) {
+	if !isFolder || p.filter.IsEmpty() {
+		p.add(SyncDelete, "", target, relative)
+		return true, nil
+	}
+
+	err := p.job.Checkpoint()
+	if err != nil {
+		return false, err
+	}
+
+	items, err := readSyncFolder(target)
+	if err != nil {
+		return false, err
+	}
+
+	first := len(p.plan.Actions)
+	deletesAll := true
+
+	for _, name := range getSortedNames(items) {
+		stats := items[name]
+		itemRelative := path.Join(relative, name)
+		if !p.filter.allows(itemRelative, stats.IsDir()) {
+			deletesAll = false
+			continue
+		}
+
+		deleted, err := p.planDelete(path.Join(target, name), itemRelative, stats.IsDir())
+		if err != nil {
+			return false, err
+		}
+
+		deletesAll = deletesAll && deleted
+	}
+
+	if !deletesAll {
+		return false, nil
+	}
+
+	p.plan.Actions = p.plan.Actions[:first]
+	p.add(SyncDelete, "", target, relative)
+	return true, nil
+}
+
+// Carries out the plan. Items that can't be synced don't stop the rest of the sync, the errors are returned together
+// at the end. Replaced and deleted items are moved to the trash, so that the sync can be undone.
+func RunSync(plan *SyncPlan, job *Job) (ops []JournalOp, err error) {
+	job.SetTotal(plan.Files, plan.Bytes)
+
+	var errs []error
+	for _, action := range plan.Actions {
+		err = job.Checkpoint()
+		if err != nil {
+			return ops, err
+		}
+
+		var actionOps []JournalOp
+		actionOps, err = runSyncAction(action, job)
+		ops = append(ops, actionOps...)
+
+		if errors.Is(err, ErrJobCancelled) {
+			return ops, err
+		}
+
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return ops, joinErrors(errs)
+}
+
+func runSyncAction(action SyncAction, job *Job) (ops []JournalOp, err error) {
+	switch action.Type {
+	case SyncCreateFolder:
+		err = os.Mkdir(action.Target, 0755)
+		if err != nil {
+			return
+		}
+
+		ops = append(ops, JournalOp{Type: JournalOpCreateFolder, To: action.Target})
+	case SyncCopy:
+		// The folders might have changed since the plan was made
+		if DoesFileExist(action.Target) {
+			return nil, errors.New(action.Target + " already exists")
+		}
+
+		err = CopyPath(action.Source, action.Target, job)
+		if err != nil {
+			return
+		}
+
+		ops = append(ops, JournalOp{Type: JournalOpCopy, From: action.Source, To: action.Target})
+	case SyncOverwrite:
+		var trashedPath string
+		trashedPath, err = MoveToTrash(action.Target, nil)
+		if err != nil {
+			return
+		}
+		ops = append(ops, JournalOp{Type: JournalOpTrash, From: action.Target, To: trashedPath})
+
+		err = CopyPath(action.Source, action.Target, job)
+		if err != nil {
+			return
+		}
+
+		ops = append(ops, JournalOp{Type: JournalOpCopy, From: action.Source, To: action.Target})
+	case SyncDelete:
+		var trashedPath string
+		trashedPath, err = MoveToTrash(action.Target, nil)
+		if err != nil {
+			return
+		}
+
+		ops = append(ops, JournalOp{Type: JournalOpTrash, From: action.Target, To: trashedPath})
+	}
+
+	return
+}
+
+func (p *SyncPlan) Summary() string {
+	var parts []string
+	for _, part := range []struct {
+		actionType SyncActionType
+		singular   string
+		plural     string
+	}{
+		{SyncCopy, "copy", "copies"},
+		{SyncOverwrite, "replacement", "replacements"},
+		{SyncDelete, "deletion", "deletions"},
+		{SyncConflict, "conflict", "conflicts"},
+	} {
+		count := p.Count(part.actionType)
+		if count == 1 {
+			parts = append(parts, "1 "+part.singular)
+		} else if count > 1 {
+			parts = append(parts, fmt.Sprintf("%d %s", count, part.plural))
+		}
+	}
+
+	if len(parts) == 0 {
+		return "nothing to do"
+	}
+
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+type syncTestAction struct {
+	Type       SyncActionType
+	Relative   string
+	FromSource bool // Whether the source of the action is in the source folder
+}
+
+// Creates the files, the times are relative to now
+func writeSyncFiles(t *testing.T, directory string, files map[string]time.Duration) {
+	t.Helper()
+
+	now := time.Now()
+	for name, age := range files {
+		fullPath := path.Join(directory, name)
+		err := os.MkdirAll(path.Dir(fullPath), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = os.WriteFile(fullPath, []byte("data"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		modTime := now.Add(-age)
+		err = os.Chtimes(fullPath, modTime, modTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPlanSync(t *testing.T) {
+	sourceFiles := map[string]time.Duration{
+		"newer.txt":           0,
+		"older.txt":           time.Hour,
+		"same.txt":            time.Hour,
+		"missing.txt":         0,
+		"missing/a.txt":       0,
+		"missing/b.tmp":       0,
+		"folder/same.txt":     time.Hour,
+		"folder/newer.txt":    0,
+		"folder/skipped.tmp":  0,
+		"folder/nested/a.txt": time.Hour,
+	}
+
+	targetFiles := map[string]time.Duration{
+		"newer.txt":           time.Hour,
+		"older.txt":           0,
+		"same.txt":            time.Hour,
+		"extra.txt":           0,
+		"extra/a.txt":         0,
+		"extra/b.tmp":         0,
+		"folder/same.txt":     time.Hour,
+		"folder/newer.txt":    time.Hour,
+		"folder/extra.tmp":    0,
+		"folder/nested/a.txt": time.Hour,
+	}
+
+	tests := []struct {
+		name     string
+		twoWay   bool
+		filter   SyncFilter
+		expected []syncTestAction
+	}{
+		{
+			name: "mirror",
+			expected: []syncTestAction{
+				{SyncDelete, "extra", false},
+				{SyncDelete, "extra.txt", false},
+				{SyncDelete, "folder/extra.tmp", false},
+				{SyncOverwrite, "folder/newer.txt", true},
+				{SyncCopy, "folder/skipped.tmp", true},
+				{SyncCopy, "missing", true},
+				{SyncCopy, "missing.txt", true},
+				{SyncOverwrite, "newer.txt", true},
+				{SyncOverwrite, "older.txt", true},
+			},
+		},
+		{
+			name:   "mirror with excluded items",
+			filter: SyncFilter{Exclude: []string{"*.tmp"}},
+			expected: []syncTestAction{
+				{SyncDelete, "extra/a.txt", false},
+				{SyncDelete, "extra.txt", false},
+				{SyncOverwrite, "folder/newer.txt", true},
+				{SyncCreateFolder, "missing", true},
+				{SyncCopy, "missing/a.txt", true},
+				{SyncCopy, "missing.txt", true},
+				{SyncOverwrite, "newer.txt", true},
+				{SyncOverwrite, "older.txt", true},
+			},
+		},
+		{
+			name:   "both ways",
+			twoWay: true,
+			expected: []syncTestAction{
+				{SyncCopy, "extra", false},
+				{SyncCopy, "extra.txt", false},
+				{SyncCopy, "folder/extra.tmp", false},
+				{SyncOverwrite, "folder/newer.txt", true},
+				{SyncCopy, "folder/skipped.tmp", true},
+				{SyncCopy, "missing", true},
+				{SyncCopy, "missing.txt", true},
+				{SyncOverwrite, "newer.txt", true},
+				{SyncOverwrite, "older.txt", false},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			directory := t.TempDir()
+			source := path.Join(directory, "source")
+			target := path.Join(directory, "target")
+			writeSyncFiles(t, source, sourceFiles)
+			writeSyncFiles(t, target, targetFiles)
+
+			plan, err := PlanSync(source, target, test.twoWay, test.filter, &Job{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(plan.Actions) != len(test.expected) {
+				t.Fatalf("got %d actions, expected %d: %v", len(plan.Actions), len(test.expected), plan.Actions)
+			}
+
+			for index, action := range plan.Actions {
+				expected := test.expected[index]
+				from, to := source, target
+				if !expected.FromSource {
+					from, to = target, source
+				}
+
+				if expected.Type == SyncDelete {
+					from, to = "", target
+				} else {
+					from = path.Join(from, expected.Relative)
+				}
+
+				if action.Type != expected.Type || action.Relative != expected.Relative ||
+					action.Source != from || action.Target != path.Join(to, expected.Relative) {
+					t.Errorf("action %d is %v, expected %v", index, action, expected)
+				}
+			}
+
+			// Nothing is changed before the plan is carried out
+			_, err = os.Stat(path.Join(target, "missing.txt"))
+			if err == nil {
+				t.Error("planning copied missing.txt")
+			}
+		})
+	}
+
+	directory := t.TempDir()
+	_, err := PlanSync(directory, path.Join(directory, "inside"), false, SyncFilter{}, &Job{})
+	if err == nil {
+		t.Error("syncing a folder with a folder inside of it should be an error")
+	}
+}
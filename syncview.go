@@ -0,0 +1,299 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Shows the plan of a sync (see sync.go) before anything is changed. The plan is made again when the patterns change
+// and Enter is pressed, pressing Enter once more runs it.
+
+type SyncView struct {
+	IsOpen      bool
+	Source      string
+	Target      string
+	TwoWay      bool
+	Fields      [2]*InputField // Include, Exclude
+	ActiveField int
+	Plan        *SyncPlan
+	List        ListView
+	Error       string
+
+	planJob  *Job     // The planning that is running, nil if there's none
+	patterns []string // The patterns the plan was made with
+
+	MaxWidth     int32
+	Padding      int32
+	HeaderHeight int32
+	FieldHeight  int32
+	LabelWidth   int32
+	LineHeight   int32
+}
+
+func NewSyncView() *SyncView {
+	result := &SyncView{
+		List:         *NewListView(),
+		MaxWidth:     800,
+		Padding:      8,
+		HeaderHeight: 28,
+		FieldHeight:  40,
+		LabelWidth:   90,
+		LineHeight:   24,
+	}
+
+	result.List.ItemHeight = 24
+
+	for i := range result.Fields {
+		result.Fields[i] = NewInputField(sdl.Rect{H: result.FieldHeight}, nil)
+	}
+
+	return result
+}
+
+func (s *SyncView) Open(source string, target string, twoWay bool, app *App) {
+	s.IsOpen = true
+	s.Source = source
+	s.Target = target
+	s.TwoWay = twoWay
+	s.ActiveField = 0
+	s.List.ActiveItem = 0
+
+	// The patterns are kept, the same folders are usually synced with the same patterns
+	s.plan(app)
+}
+
+func (s *SyncView) Close() {
+	s.IsOpen = false
+	s.Plan = nil
+
+	if s.planJob != nil {
+		s.planJob.Cancel()
+		s.planJob = nil
+	}
+}
+
+func (s *SyncView) getPatterns() []string {
+	return []string{s.Fields[0].Value.String(), s.Fields[1].Value.String()}
+}
+
+func (s *SyncView) plan(app *App) {
+	s.Error = ""
+	s.Plan = nil
+	s.updateList()
+
+	include, err := ParseSyncPatterns(s.Fields[0].Value.String())
+	if err != nil {
+		s.Error = err.Error()
+		return
+	}
+
+	exclude, err := ParseSyncPatterns(s.Fields[1].Value.String())
+	if err != nil {
+		s.Error = err.Error()
+		return
+	}
+
+	if s.planJob != nil {
+		s.planJob.Cancel()
+	}
+
+	s.patterns = s.getPatterns()
+
+	source, target, twoWay := s.Source, s.Target, s.TwoWay
+	filter := SyncFilter{Include: include, Exclude: exclude}
+
+	var plan *SyncPlan
+	var planJob *Job
+	planJob = app.Jobs.Add("Planning the sync of "+source+" and "+target, func(job *Job) (err error) {
+		plan, err = PlanSync(source, target, twoWay, filter, job)
+		return
+	}, func(err error) {
+		// The view was closed or the plan was made again in the meantime
+		if s.planJob != planJob {
+			return
+		}
+
+		s.planJob = nil
+		if err != nil {
+			s.Error = err.Error()
+			return
+		}
+
+		s.Plan = plan
+		s.updateList()
+	})
+
+	s.planJob = planJob
+}
+
+func (s *SyncView) updateList() {
+	if s.Plan == nil {
+		s.List.SetItems(nil)
+		return
+	}
+
+	items := make([]ListItem, len(s.Plan.Actions))
+	for index, action := range s.Plan.Actions {
+		label := ""
+		colorKey := ""
+
+		switch action.Type {
+		case SyncCreateFolder:
+			label = "Create"
+		case SyncCopy:
+			label = "Copy"
+		case SyncOverwrite:
+			label = "Replace"
+		case SyncDelete:
+			label = "Delete"
+			colorKey = "error_color"
+		case SyncConflict:
+			label = "Skip"
+			colorKey = "secondary_text_color"
+		}
+
+		// Both ways, the items are copied in either direction
+		if s.Plan.TwoWay && action.Type != SyncConflict {
+			if IsSubPath(s.Plan.Source, action.Target) {
+				label += " <-"
+			} else {
+				label += " ->"
+			}
+		}
+
+		text := fmt.Sprintf("%-10s %s", label, action.Relative)
+		if action.Type == SyncCopy || action.Type == SyncOverwrite {
+			text += "  (" + bytesToString(action.Bytes) + ")"
+		}
+
+		items[index] = ListItem{Text: text, ColorKey: colorKey}
+	}
+
+	s.List.SetItems(items)
+}
+
+func (s *SyncView) Tick(input *Input, app *App) {
+	if input.Escape {
+		s.Close()
+		return
+	}
+
+	switch {
+	case input.TypedCharacter == '\t':
+		s.ActiveField = (s.ActiveField + 1) % len(s.Fields)
+	case input.TypedCharacter == '\n':
+		if s.planJob != nil {
+			return
+		}
+
+		if s.Plan == nil || strings.Join(s.patterns, "\n") != strings.Join(s.getPatterns(), "\n") {
+			s.plan(app)
+		} else {
+			s.run(app)
+		}
+	case input.Up:
+		if s.List.ActiveItem > 0 {
+			s.List.ActiveItem--
+		}
+	case input.Down:
+		if s.List.ActiveItem < int32(len(s.List.Items))-1 {
+			s.List.ActiveItem++
+		}
+	default:
+		s.Fields[s.ActiveField].Tick(input)
+	}
+}
+
+func (s *SyncView) run(app *App) {
+	plan := s.Plan
+	s.Close()
+
+	if len(plan.Actions) == plan.Count(SyncConflict) {
+		NotifyInfo("Nothing to sync")
+		return
+	}
+
+	var ops []JournalOp
+	app.Jobs.Add("Syncing "+plan.Source+" and "+plan.Target, func(job *Job) (err error) {
+		ops, err = RunSync(plan, job)
+		return
+	}, func(err error) {
+		app.Journal.Record("Sync "+path.Base(plan.Source), ops...)
+
+		for i := int32(0); i < app.ViewCount; i++ {
+			view := app.ItemViews[i]
+			if view.FS == nil && (IsSubPath(plan.Source, view.CurrentPath) || IsSubPath(plan.Target, view.CurrentPath)) {
+				view.Refresh()
+			}
+		}
+
+		if err == nil {
+			NotifyInfo("Synced " + path.Base(plan.Source) + ": " + plan.Summary())
+		}
+	})
+}
+
+func (s *SyncView) Render(renderer *sdl.Renderer, parentRect *sdl.Rect, app *App) {
+	theme := app.Theme.PanelTheme
+
+	width := s.MaxWidth
+	if width > parentRect.W-20 {
+		width = parentRect.W - 20
+	}
+
+	rect := sdl.Rect{
+		X: parentRect.X + (parentRect.W-width)/2,
+		Y: parentRect.Y + 40,
+		W: width,
+		H: parentRect.H - 80,
+	}
+
+	title := "Mirror " + s.Source + " into " + s.Target
+	if s.TwoWay {
+		title = "Sync " + s.Source + " and " + s.Target + " both ways"
+	}
+	title += " (Tab to switch fields, Enter to plan or to sync, Esc to cancel)"
+
+	insetRect := DrawPanel(renderer, &rect, title, s.HeaderHeight, s.Padding, &app.Font, theme)
+
+	y := insetRect.Y
+	labels := []string{"Include", "Exclude"}
+	for index, field := range s.Fields {
+		color := GetColor(theme, "secondary_text_color")
+		if index == s.ActiveField {
+			color = GetColor(theme, "header_color")
+		}
+
+		labelRect := sdl.Rect{X: insetRect.X + s.Padding, Y: y, W: s.LabelWidth, H: s.FieldHeight}
+		DrawTextInRect(renderer, &app.Font, labels[index], &labelRect, color)
+
+		field.Rect.W = insetRect.W - s.LabelWidth - s.Padding
+		field.Render(renderer, insetRect.X+s.LabelWidth, y, &app.Font, app.Theme.InputFieldTheme)
+
+		y += s.FieldHeight
+	}
+
+	summary := "Patterns are separated by spaces, e.g. *.jpg or build/*"
+	summaryColor := GetColor(theme, "secondary_text_color")
+	if s.Error != "" {
+		summary = s.Error
+		summaryColor = GetColor(theme, "error_color")
+	} else if s.Plan != nil {
+		summary = fmt.Sprintf("Plan: %s, %s to copy", s.Plan.Summary(), bytesToString(s.Plan.Bytes))
+	}
+
+	summaryRect := sdl.Rect{X: insetRect.X + s.Padding, Y: y, W: insetRect.W - s.Padding*2, H: s.LineHeight}
+	DrawTextInRect(renderer, &app.Font, summary, &summaryRect, summaryColor)
+	y += s.LineHeight
+
+	emptyText := "Nothing to sync"
+	if s.planJob != nil {
+		emptyText = "Planning..."
+	}
+
+	listRect := sdl.Rect{X: insetRect.X, Y: y, W: insetRect.W, H: insetRect.Y + insetRect.H - y}
+	s.List.Render(renderer, &listRect, &app.Font, theme, emptyText)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// A plain multi-line text buffer with a cursor. It only handles editing; closing it and doing something with the
+// text is up to the owner.
+
+type TextEditor struct {
+	Lines        []string
+	CursorLine   int
+	CursorColumn int
+
+	FirstLine   int
+	FirstColumn int
+
+	LineHeight int32
+	Padding    int32
+}
+
+func NewTextEditor() *TextEditor {
+	return &TextEditor{
+		LineHeight: 24,
+		Padding:    5,
+	}
+}
+
+func (t *TextEditor) SetText(text string) {
+	t.Lines = strings.Split(text, "\n")
+	t.CursorLine = 0
+	t.CursorColumn = 0
+	t.FirstLine = 0
+	t.FirstColumn = 0
+}
+
+func (t *TextEditor) GetText() string {
+	return strings.Join(t.Lines, "\n")
+}
+
+func (t *TextEditor) Tick(input *Input) {
+	line := t.Lines[t.CursorLine]
+
+	if input.Up {
+		if t.CursorLine > 0 {
+			t.CursorLine--
+		}
+	} else if input.Down {
+		if t.CursorLine < len(t.Lines)-1 {
+			t.CursorLine++
+		}
+	} else if input.Left {
+		if t.CursorColumn > 0 {
+			t.CursorColumn--
+		} else if t.CursorLine > 0 {
+			t.CursorLine--
+			t.CursorColumn = len(t.Lines[t.CursorLine])
+		}
+	} else if input.Right {
+		if t.CursorColumn < len(line) {
+			t.CursorColumn++
+		} else if t.CursorLine < len(t.Lines)-1 {
+			t.CursorLine++
+			t.CursorColumn = 0
+		}
+	} else if input.Backspace {
+		if input.Ctrl {
+			t.Lines[t.CursorLine] = line[t.CursorColumn:]
+			t.CursorColumn = 0
+		} else if t.CursorColumn > 0 {
+			t.Lines[t.CursorLine] = line[:t.CursorColumn-1] + line[t.CursorColumn:]
+			t.CursorColumn--
+		} else if t.CursorLine > 0 {
+			previous := t.Lines[t.CursorLine-1]
+			t.Lines[t.CursorLine-1] = previous + line
+			t.Lines = append(t.Lines[:t.CursorLine], t.Lines[t.CursorLine+1:]...)
+			t.CursorLine--
+			t.CursorColumn = len(previous)
+		}
+	} else if input.TypedCharacter == '\n' {
+		t.Lines[t.CursorLine] = line[:t.CursorColumn]
+		t.Lines = append(t.Lines[:t.CursorLine+1], append([]string{line[t.CursorColumn:]}, t.Lines[t.CursorLine+1:]...)...)
+		t.CursorLine++
+		t.CursorColumn = 0
+	} else if input.TypedCharacter != 0 && input.TypedCharacter != '\t' {
+		t.Lines[t.CursorLine] = line[:t.CursorColumn] + string(input.TypedCharacter) + line[t.CursorColumn:]
+		t.CursorColumn++
+	}
+
+	if t.CursorColumn > len(t.Lines[t.CursorLine]) {
+		t.CursorColumn = len(t.Lines[t.CursorLine])
+	}
+}
+
+func (t *TextEditor) Render(renderer *sdl.Renderer, rect *sdl.Rect, font *Font, theme Subtheme) {
+	visibleLines := int(rect.H / t.LineHeight)
+	if visibleLines < 1 {
+		visibleLines = 1
+	}
+
+	if t.CursorLine < t.FirstLine {
+		t.FirstLine = t.CursorLine
+	} else if t.CursorLine >= t.FirstLine+visibleLines {
+		t.FirstLine = t.CursorLine - visibleLines + 1
+	}
+
+	width := rect.W - t.Padding*2
+	visibleColumns := int(width / int32(font.CharacterWidth))
+	if visibleColumns < 1 {
+		visibleColumns = 1
+	}
+
+	if t.CursorColumn < t.FirstColumn {
+		t.FirstColumn = t.CursorColumn
+	} else if t.CursorColumn >= t.FirstColumn+visibleColumns {
+		t.FirstColumn = t.CursorColumn - visibleColumns + 1
+	}
+
+	for i := 0; i < visibleLines; i++ {
+		index := t.FirstLine + i
+		if index >= len(t.Lines) {
+			break
+		}
+
+		lineRect := sdl.Rect{
+			X: rect.X + t.Padding,
+			Y: rect.Y + int32(i)*t.LineHeight,
+			W: width,
+			H: t.LineHeight,
+		}
+
+		line := t.Lines[index]
+		if t.FirstColumn < len(line) {
+			text := font.ClipStringNoEllipsis(line[t.FirstColumn:], width)
+			textRect := sdl.Rect{
+				X: lineRect.X,
+				Y: lineRect.Y + (lineRect.H-font.Size)/2,
+				W: font.GetStringWidth(text),
+				H: font.Size,
+			}
+			DrawText(renderer, font, text, &textRect, GetColor(theme, "text_color"))
+		}
+
+		if index == t.CursorLine {
+			cursorRect := sdl.Rect{
+				X: lineRect.X + int32((t.CursorColumn-t.FirstColumn)*font.CharacterWidth),
+				Y: lineRect.Y + (lineRect.H-font.Size)/2,
+				W: 2,
+				H: font.Size,
+			}
+			DrawRect(renderer, &cursorRect, GetColor(theme, "cursor_color"))
+		}
+	}
+}
@@ -20,6 +20,7 @@ type Theme struct {
 	InfoViewTheme     Subtheme
 	PreviewTheme      Subtheme
 	ScrollbarTheme    Subtheme
+	PanelTheme        Subtheme
 }
 
 func GetAvailableThemes() (result []string) {
@@ -50,6 +51,7 @@ func LoadTheme(themeName string) (result *Theme) {
 		InfoViewTheme:     Subtheme{},
 		PreviewTheme:      Subtheme{},
 		ScrollbarTheme:    Subtheme{},
+		PanelTheme:        Subtheme{},
 	}
 	currentSubtheme := result.BreadcrumbsTheme
 
@@ -78,6 +80,8 @@ func LoadTheme(themeName string) (result *Theme) {
 				currentSubtheme = result.PreviewTheme
 			} else if strings.Contains(line, "Scrollbar") {
 				currentSubtheme = result.ScrollbarTheme
+			} else if strings.Contains(line, "Panel") {
+				currentSubtheme = result.PanelTheme
 			}
 		} else {
 			key, value := getKeyValue(line)
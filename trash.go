@@ -0,0 +1,233 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Deleted items are moved to a trash that follows the freedesktop.org Trash specification. A trash folder contains
+// a "files" folder with the trashed items and an "info" folder with a .trashinfo file for every item, which stores
+// the original path and the deletion date. Items are moved to the trash of the user's home folder, unless they are
+// on another mount, in which case the trash at the top of that mount is used (see trash_unix.go).
+
+const trashInfoDateFormat = "2006-01-02T15:04:05"
+
+type TrashItem struct {
+	Name         string // Name of the item inside the "files" folder of the trash
+	OriginalPath string
+	DeletionDate time.Time
+	TrashDir     string
+}
+
+func (t *TrashItem) FilesPath() string {
+	return path.Join(t.TrashDir, "files", t.Name)
+}
+
+func (t *TrashItem) InfoPath() string {
+	return path.Join(t.TrashDir, "info", t.Name+".trashinfo")
+}
+
+// Returns the trash item for a path inside the "files" folder of a trash
+func trashItemFromPath(trashedPath string) TrashItem {
+	return TrashItem{
+		Name:     path.Base(trashedPath),
+		TrashDir: path.Dir(path.Dir(trashedPath)),
+	}
+}
+
+// Moves the item to the trash. Returns the path of the item inside the trash.
+func MoveToTrash(fullPath string, progress ProgressReporter) (string, error) {
+	trashDir, topDir := findTrashDir(fullPath)
+	if trashDir == "" {
+		return "", errors.New("could not find the trash folder")
+	}
+
+	for _, dir := range []string{path.Join(trashDir, "files"), path.Join(trashDir, "info")} {
+		err := os.MkdirAll(dir, 0700)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// The info file is created first, because creating it with O_EXCL reserves the name in the trash
+	item, err := createTrashInfo(trashDir, topDir, fullPath)
+	if err != nil {
+		return "", err
+	}
+
+	err = MovePath(fullPath, item.FilesPath(), progress)
+	if err != nil {
+		os.Remove(item.InfoPath())
+		return "", err
+	}
+
+	return item.FilesPath(), nil
+}
+
+func createTrashInfo(trashDir string, topDir string, fullPath string) (result TrashItem, err error) {
+	result = TrashItem{
+		OriginalPath: fullPath,
+		DeletionDate: time.Now(),
+		TrashDir:     trashDir,
+	}
+
+	// Items on other mounts store their path relative to the top of the mount
+	infoPath := fullPath
+	if topDir != "" {
+		infoPath = strings.TrimPrefix(strings.TrimPrefix(fullPath, topDir), "/")
+	}
+
+	extension := path.Ext(fullPath)
+	base := strings.TrimSuffix(path.Base(fullPath), extension)
+
+	for count := 0; ; count++ {
+		result.Name = base + extension
+		if count > 0 {
+			result.Name = base + " (" + strconv.Itoa(count) + ")" + extension
+		}
+
+		if DoesFileExist(result.FilesPath()) {
+			continue
+		}
+
+		err = writeTrashInfo(result, infoPath)
+		if !errors.Is(err, os.ErrExist) {
+			return
+		}
+	}
+}
+
+// Fails with os.ErrExist if the info file already exists
+func writeTrashInfo(item TrashItem, infoPath string) error {
+	var sb strings.Builder
+	sb.WriteString("[Trash Info]\n")
+	sb.WriteString("Path=")
+	sb.WriteString((&url.URL{Path: infoPath}).EscapedPath())
+	sb.WriteString("\n")
+	sb.WriteString("DeletionDate=")
+	sb.WriteString(item.DeletionDate.Format(trashInfoDateFormat))
+	sb.WriteString("\n")
+
+	file, err := os.OpenFile(item.InfoPath(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.WriteString(sb.String())
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(item.InfoPath())
+	}
+
+	return err
+}
+
+// Moves the item back to the exact place in the trash where it was before it got restored. Used when redoing.
+func moveToTrashAt(fullPath string, trashedPath string, progress ProgressReporter) error {
+	item := trashItemFromPath(trashedPath)
+	item.OriginalPath = fullPath
+	item.DeletionDate = time.Now()
+
+	err := writeTrashInfo(item, fullPath)
+	if err != nil {
+		return err
+	}
+
+	err = MovePath(fullPath, trashedPath, progress)
+	if err != nil {
+		os.Remove(item.InfoPath())
+	}
+
+	return err
+}
+
+func RestoreFromTrash(item TrashItem, progress ProgressReporter) error {
+	err := MovePath(item.FilesPath(), item.OriginalPath, progress)
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(item.InfoPath())
+}
+
+func PurgeFromTrash(item TrashItem, progress ProgressReporter) error {
+	err := RemoveItem(item.FilesPath(), progress)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return os.Remove(item.InfoPath())
+}
+
+// Lists the items of all the trashes that can be found, newest first
+func ListTrash() (result []TrashItem) {
+	for _, trashDir := range getTrashDirs() {
+		entries, err := os.ReadDir(path.Join(trashDir.Path, "info"))
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			if !strings.HasSuffix(entry.Name(), ".trashinfo") {
+				continue
+			}
+
+			item, ok := parseTrashInfo(trashDir, strings.TrimSuffix(entry.Name(), ".trashinfo"))
+			if ok {
+				result = append(result, item)
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].DeletionDate.After(result[j].DeletionDate)
+	})
+
+	return
+}
+
+func parseTrashInfo(trashDir TrashDir, name string) (result TrashItem, ok bool) {
+	result = TrashItem{Name: name, TrashDir: trashDir.Path}
+
+	data, err := os.ReadFile(result.InfoPath())
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+
+		if strings.HasPrefix(line, "Path=") {
+			value, err := url.PathUnescape(line[5:])
+			if err != nil {
+				return
+			}
+
+			if trashDir.TopDir != "" && !strings.HasPrefix(value, "/") {
+				value = path.Join(trashDir.TopDir, value)
+			}
+
+			result.OriginalPath = value
+			ok = true
+		} else if strings.HasPrefix(line, "DeletionDate=") {
+			result.DeletionDate, _ = time.ParseInLocation(trashInfoDateFormat, line[13:], time.Local)
+		}
+	}
+
+	return
+}
+
+type TrashDir struct {
+	Path   string
+	TopDir string // Top of the mount for trashes that aren't in the home folder, empty otherwise
+}
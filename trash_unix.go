@@ -0,0 +1,115 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"path"
+	"strconv"
+	"syscall"
+)
+
+func getHomeTrashDir() string {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+
+		dataHome = path.Join(home, ".local", "share")
+	}
+
+	return path.Join(dataHome, "Trash")
+}
+
+// Items are moved to the home trash if they are on the same device. Otherwise the trash at the top of the mount that
+// contains the item is used, so the item doesn't need to be copied. If that trash can't be used, the home trash is
+// used anyway.
+func findTrashDir(fullPath string) (trashDir string, topDir string) {
+	homeTrash := getHomeTrashDir()
+
+	device, ok := getDevice(path.Dir(fullPath))
+	if !ok {
+		return homeTrash, ""
+	}
+
+	homeDevice, ok := getDevice(getExistingParent(homeTrash))
+	if !ok || homeDevice == device {
+		return homeTrash, ""
+	}
+
+	topDir = getMountTop(path.Dir(fullPath), device)
+	uid := strconv.Itoa(os.Getuid())
+
+	// An administrator can create a shared .Trash folder, which must have the sticky bit set and must not be a symlink
+	adminTrash := path.Join(topDir, ".Trash")
+	stats, err := os.Lstat(adminTrash)
+	if err == nil && stats.IsDir() && stats.Mode()&os.ModeSticky != 0 {
+		trashDir = path.Join(adminTrash, uid)
+		if os.MkdirAll(trashDir, 0700) == nil {
+			return
+		}
+	}
+
+	trashDir = path.Join(topDir, ".Trash-"+uid)
+	if os.MkdirAll(trashDir, 0700) == nil {
+		return
+	}
+
+	return homeTrash, ""
+}
+
+func getTrashDirs() []TrashDir {
+	result := []TrashDir{{Path: getHomeTrashDir()}}
+	uid := strconv.Itoa(os.Getuid())
+
+	for _, mount := range getMountPoints() {
+		for _, dir := range []string{path.Join(mount.Path, ".Trash", uid), path.Join(mount.Path, ".Trash-"+uid)} {
+			if DoesFileExist(dir) {
+				result = append(result, TrashDir{Path: dir, TopDir: mount.Path})
+			}
+		}
+	}
+
+	return result
+}
+
+func getDevice(fullPath string) (uint64, bool) {
+	stats, err := os.Stat(fullPath)
+	if err != nil {
+		return 0, false
+	}
+
+	sys, ok := stats.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+
+	return uint64(sys.Dev), true
+}
+
+func getExistingParent(fullPath string) string {
+	for !DoesFileExist(fullPath) && fullPath != "/" {
+		fullPath = path.Dir(fullPath)
+	}
+
+	return fullPath
+}
+
+// Walks up the path until the parent is on another device
+func getMountTop(fullPath string, device uint64) string {
+	for fullPath != "/" {
+		parent := path.Dir(fullPath)
+
+		parentDevice, ok := getDevice(parent)
+		if !ok || parentDevice != device {
+			break
+		}
+
+		fullPath = parent
+	}
+
+	return fullPath
+}
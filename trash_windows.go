@@ -0,0 +1,26 @@
+package main
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Windows doesn't have a freedesktop.org trash, so bonfire keeps its own in the local app data folder, using the
+// same layout. Items on other drives are copied there.
+func getHomeTrashDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	return path.Join(filepath.ToSlash(dir), "bonfire", "Trash")
+}
+
+func findTrashDir(fullPath string) (trashDir string, topDir string) {
+	return getHomeTrashDir(), ""
+}
+
+func getTrashDirs() []TrashDir {
+	return []TrashDir{{Path: getHomeTrashDir()}}
+}
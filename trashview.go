@@ -0,0 +1,104 @@
+package main
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+type TrashView struct {
+	IsOpen bool
+	Items  []TrashItem
+	List   ListView
+
+	MaxWidth     int32
+	Padding      int32
+	HeaderHeight int32
+}
+
+func NewTrashView() *TrashView {
+	return &TrashView{
+		List:         *NewListView(),
+		MaxWidth:     600,
+		Padding:      8,
+		HeaderHeight: 28,
+	}
+}
+
+func (t *TrashView) Open() {
+	t.IsOpen = true
+	t.List.ActiveItem = 0
+	t.Reload()
+}
+
+func (t *TrashView) Close() {
+	t.IsOpen = false
+}
+
+func (t *TrashView) Reload() {
+	t.Items = ListTrash()
+
+	items := make([]ListItem, len(t.Items))
+	for index, item := range t.Items {
+		items[index] = ListItem{
+			Text:   item.OriginalPath,
+			Detail: "Deleted " + item.DeletionDate.Format("2006-01-02 15:04:05"),
+		}
+	}
+
+	t.List.SetItems(items)
+}
+
+func (t *TrashView) Tick(input *Input, app *App) {
+	if input.Escape {
+		t.Close()
+		return
+	}
+
+	if t.List.Tick(input) || !t.List.HasActive() {
+		return
+	}
+
+	item := t.Items[t.List.ActiveItem]
+
+	switch input.TypedCharacter {
+	case 'r':
+		app.Jobs.Add("Restoring "+item.OriginalPath, func(job *Job) error {
+			job.SetTotal(MeasureItem(item.FilesPath()))
+			return RestoreFromTrash(item, job)
+		}, func(err error) {
+			t.Reload()
+
+			if err == nil {
+				directory := getParentPath(item.OriginalPath)
+				app.Journal.Record("Restore "+item.OriginalPath, JournalOp{Type: JournalOpRestore, From: item.FilesPath(), To: item.OriginalPath})
+				app.RefreshViewsShowing(directory)
+				NotifyInfo("Restored " + item.OriginalPath)
+			}
+		})
+	case 'X':
+		app.Jobs.Add("Deleting "+item.OriginalPath+" permanently", func(job *Job) error {
+			job.SetTotal(MeasureItem(item.FilesPath()))
+			return PurgeFromTrash(item, job)
+		}, func(err error) {
+			t.Reload()
+		})
+	}
+}
+
+func (t *TrashView) Render(renderer *sdl.Renderer, parentRect *sdl.Rect, app *App) {
+	theme := app.Theme.PanelTheme
+
+	width := t.MaxWidth
+	if width > parentRect.W-20 {
+		width = parentRect.W - 20
+	}
+
+	rect := sdl.Rect{
+		X: parentRect.X + (parentRect.W-width)/2,
+		Y: parentRect.Y + 40,
+		W: width,
+		H: parentRect.H - 80,
+	}
+
+	insetRect := DrawPanel(renderer, &rect, "Trash (r to restore, X to delete permanently)", t.HeaderHeight, t.Padding, &app.Font, theme)
+	t.List.Render(renderer, &insetRect, &app.Font, theme, "Trash is empty")
+}
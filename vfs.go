@@ -0,0 +1,496 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Locations that aren't folders on the local disk, such as archives, remote folders and buckets, are browsed through a
+// FileSystem. An ItemView that shows one of them keeps the path inside of it in CurrentPath. Paths inside a file
+// system always use forward slashes and start with "/". File systems that can be changed also implement
+// WritableFileSystem, the others are read-only.
+
+type FileSystem interface {
+	// Describes where the file system comes from, e.g. the full path of an archive. Used in the breadcrumbs and
+	// in messages.
+	Location() string
+	ReadDir(dirPath string) ([]fs.FileInfo, error)
+	Stat(fullPath string) (fs.FileInfo, error)
+	Open(fullPath string) (io.ReadCloser, error)
+}
+
+type WritableFileSystem interface {
+	FileSystem
+	// Creates a new file, fails if the file already exists
+	Create(fullPath string, perm fs.FileMode) (io.WriteCloser, error)
+	Mkdir(fullPath string, perm fs.FileMode) error
+	// Fails if the new path already exists
+	Rename(from string, to string) error
+	// Removes the item and everything inside of it permanently
+	RemoveAll(fullPath string) error
+	Chtimes(fullPath string, modTime time.Time) error
+}
+
+// Implemented by the file systems that can contain symbolic links
+type LinkReader interface {
+	ReadLink(fullPath string) (string, error)
+}
+
+// Links inside of archives point to items on the disk the archive was made on, so only the links of remote folders
+// can be followed
+func canFollowLinks(fsys FileSystem) bool {
+	_, ok := fsys.(*SFTPFileSystem)
+	return ok
+}
+
+// Same as ReadLink, for a link inside of a file system whose links can be followed
+func ReadLinkInFileSystem(fsys FileSystem, fullPath string) (target string, targetType ItemType, broken bool) {
+	reader, ok := fsys.(LinkReader)
+	if !ok {
+		return "", ItemTypeFile, true
+	}
+
+	target, err := reader.ReadLink(fullPath)
+	if err != nil {
+		return "", ItemTypeFile, true
+	}
+
+	stats, err := fsys.Stat(fullPath)
+	if err != nil {
+		return target, ItemTypeFile, true
+	}
+
+	if stats.IsDir() {
+		return target, ItemTypeFolder, false
+	}
+
+	return target, ItemTypeFile, false
+}
+
+// Used by the file systems that don't get the information about their entries from os.Stat
+type virtualFileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (v *virtualFileInfo) Name() string       { return v.name }
+func (v *virtualFileInfo) Size() int64        { return v.size }
+func (v *virtualFileInfo) Mode() fs.FileMode  { return v.mode }
+func (v *virtualFileInfo) ModTime() time.Time { return v.modTime }
+func (v *virtualFileInfo) IsDir() bool        { return v.mode.IsDir() }
+func (v *virtualFileInfo) Sys() interface{}   { return nil }
+
+// Remote locations are written as URLs, e.g. sftp://user@host/path or s3://bucket/prefix
+func IsRemoteLocation(location string) bool {
+	return isSFTPLocation(location) || isS3Location(location)
+}
+
+// Connects to a remote location and returns its file system together with the path the location points to inside
+// of it. Blocks until the connection is made, so it should be called from a job.
+func ConnectToLocation(location string) (FileSystem, string, error) {
+	if isS3Location(location) {
+		bucket, fullPath, err := ConnectS3(location)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return bucket, fullPath, nil
+	}
+
+	remote, err := ParseRemoteLocation(location)
+	if err != nil {
+		return nil, "", err
+	}
+
+	connection, err := ConnectSFTP(remote)
+	if err != nil {
+		return nil, "", err
+	}
+
+	fullPath, err := connection.GetStartPath(remote)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return connection, fullPath, nil
+}
+
+// Two connections to the same host are the same file system. nil stands for the local disk.
+func isSameFileSystem(a FileSystem, b FileSystem) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	return a.Location() == b.Location()
+}
+
+// Returns the full path of an item inside of a file system in a form that can be shown to the user
+func GetFileSystemPath(fsys FileSystem, fullPath string) string {
+	return fsys.Location() + fullPath
+}
+
+// Same as GetAvailableFileName, but for a folder inside of a file system
+func GetAvailableNameInFileSystem(fsys FileSystem, dirName string, filename string) string {
+	return getAvailableName(dirName, filename, func(fullPath string) bool {
+		_, err := fsys.Stat(fullPath)
+		return err == nil
+	})
+}
+
+// Links to folders are followed when copying, so a link to one of its own parents would be copied forever
+const maxFolderDepth = 256
+
+func checkFolderDepth(fsys FileSystem, fullPath string) error {
+	if strings.Count(fullPath, "/") > maxFolderDepth {
+		return errors.New(GetFileSystemPath(fsys, fullPath) + " is nested too deeply, it might be inside of a link to one of its parents")
+	}
+
+	return nil
+}
+
+// Lets the local disk be the source of CopyToFileSystem. Links are followed, so the items they point to are copied.
+type diskFileSystem struct{}
+
+func (diskFileSystem) Location() string {
+	return ""
+}
+
+func (diskFileSystem) ReadDir(dirPath string) ([]fs.FileInfo, error) {
+	items, err := os.ReadDir(dirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]fs.FileInfo, 0, len(items))
+	for _, item := range items {
+		info, err := os.Stat(path.Join(dirPath, item.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, info)
+	}
+
+	return result, nil
+}
+
+func (diskFileSystem) Stat(fullPath string) (fs.FileInfo, error) {
+	return os.Stat(fullPath)
+}
+
+func (diskFileSystem) Open(fullPath string) (io.ReadCloser, error) {
+	return os.Open(fullPath)
+}
+
+// Copies a file or a folder into a writable file system. The source is read from fsys, or from the local disk if
+// fsys is nil. The destination must not exist yet.
+func CopyToFileSystem(fsys FileSystem, source string, target WritableFileSystem, destination string, progress ProgressReporter) error {
+	if fsys == nil {
+		fsys = diskFileSystem{}
+	}
+
+	stats, err := fsys.Stat(source)
+	if err != nil {
+		return err
+	}
+
+	if !stats.IsDir() {
+		if !stats.Mode().IsRegular() {
+			return nil
+		}
+
+		return copyFileToFileSystem(fsys, source, target, destination, stats, progress)
+	}
+
+	err = checkFolderDepth(fsys, source)
+	if err != nil {
+		return err
+	}
+
+	err = target.Mkdir(destination, stats.Mode().Perm()|0700)
+	if err != nil {
+		return err
+	}
+
+	items, err := fsys.ReadDir(source)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, item := range items {
+		if progress != nil {
+			err = progress.AddBytes(0)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = CopyToFileSystem(fsys, path.Join(source, item.Name()), target, path.Join(destination, item.Name()), progress)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	err = target.Chtimes(destination, stats.ModTime())
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	return joinErrors(errs)
+}
+
+func copyFileToFileSystem(fsys FileSystem, source string, target WritableFileSystem, destination string, stats fs.FileInfo, progress ProgressReporter) error {
+	in, err := fsys.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := target.Create(destination, stats.Mode().Perm()|0200)
+	if err != nil {
+		return err
+	}
+
+	_, err = copyStream(out, in, progress)
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		target.RemoveAll(destination)
+		return err
+	}
+
+	err = target.Chtimes(destination, stats.ModTime())
+	if err != nil {
+		return err
+	}
+
+	if progress != nil {
+		return progress.AddFile()
+	}
+
+	return nil
+}
+
+// Copies a file or a folder out of the file system to the exact destination path on the local disk, which must not
+// exist yet. Files are streamed, so nothing is kept in memory. Nothing is ever written outside of the destination:
+// links that point outside of the copied item and special files, such as devices, are skipped.
+func CopyFromFileSystem(fsys FileSystem, source string, destination string, progress ProgressReporter) error {
+	return copyFromFileSystem(fsys, path.Dir(source), source, destination, progress)
+}
+
+func copyFromFileSystem(fsys FileSystem, root string, source string, destination string, progress ProgressReporter) error {
+	stats, err := fsys.Stat(source)
+	if err != nil {
+		return err
+	}
+
+	if stats.Mode()&fs.ModeSymlink != 0 {
+		return copyLinkFromFileSystem(fsys, root, source, destination)
+	}
+
+	if !stats.IsDir() && !stats.Mode().IsRegular() {
+		return nil
+	}
+
+	if !stats.IsDir() {
+		return copyFileFromFileSystem(fsys, source, destination, stats, progress)
+	}
+
+	err = checkFolderDepth(fsys, source)
+	if err != nil {
+		return err
+	}
+
+	err = os.Mkdir(destination, stats.Mode().Perm()|0700)
+	if err != nil {
+		return err
+	}
+
+	items, err := fsys.ReadDir(source)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, item := range items {
+		if progress != nil {
+			err = progress.AddBytes(0)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = copyFromFileSystem(fsys, root, path.Join(source, item.Name()), path.Join(destination, item.Name()), progress)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	err = os.Chtimes(destination, stats.ModTime(), stats.ModTime())
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	return joinErrors(errs)
+}
+
+func copyLinkFromFileSystem(fsys FileSystem, root string, source string, destination string) error {
+	reader, ok := fsys.(LinkReader)
+	if !ok {
+		return nil
+	}
+
+	target, err := reader.ReadLink(source)
+	if err != nil {
+		return err
+	}
+
+	if !isLinkInside(root, source, target) {
+		return errors.New("skipped " + GetFileSystemPath(fsys, source) + ", it links outside of the extracted items (" + target + ")")
+	}
+
+	return os.Symlink(filepath.FromSlash(target), destination)
+}
+
+// Checks that a link at linkPath that points to target stays inside of root. Absolute targets never do.
+func isLinkInside(root string, linkPath string, target string) bool {
+	if target == "" || path.IsAbs(target) || filepath.IsAbs(filepath.FromSlash(target)) || strings.Contains(target, ":") {
+		return false
+	}
+
+	relativeDir := strings.TrimPrefix(strings.TrimPrefix(path.Dir(linkPath), root), "/")
+	resolved := path.Join(relativeDir, strings.ReplaceAll(target, "\\", "/"))
+
+	return resolved != ".." && !strings.HasPrefix(resolved, "../")
+}
+
+func copyFileFromFileSystem(fsys FileSystem, source string, destination string, stats fs.FileInfo, progress ProgressReporter) error {
+	in, err := fsys.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	perm := stats.Mode().Perm()
+	if perm == 0 {
+		perm = 0644
+	}
+
+	out, err := os.OpenFile(destination, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm|0200)
+	if err != nil {
+		return err
+	}
+
+	_, err = copyStream(out, in, progress)
+	closeErr := out.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(destination)
+		return err
+	}
+
+	err = os.Chtimes(destination, stats.ModTime(), stats.ModTime())
+	if err != nil {
+		return err
+	}
+
+	if progress != nil {
+		return progress.AddFile()
+	}
+
+	return nil
+}
+
+func copyStream(out io.Writer, in io.Reader, progress ProgressReporter) (copied int64, err error) {
+	buffer := make([]byte, copyChunkSize)
+	for {
+		n, readErr := in.Read(buffer)
+		if n > 0 {
+			written, err := out.Write(buffer[:n])
+			copied += int64(written)
+			if err != nil {
+				return copied, err
+			}
+
+			if progress != nil {
+				err = progress.AddBytes(int64(written))
+				if err != nil {
+					return copied, err
+				}
+			}
+		}
+
+		if readErr == io.EOF {
+			return copied, nil
+		}
+
+		if readErr != nil {
+			return copied, readErr
+		}
+	}
+}
+
+// Same as MeasureItem, but for an item inside of a file system
+func MeasureInFileSystem(fsys FileSystem, fullPath string) (files int64, bytes int64) {
+	stats, err := fsys.Stat(fullPath)
+	if err != nil {
+		return
+	}
+
+	if !stats.IsDir() {
+		return 1, stats.Size()
+	}
+
+	if checkFolderDepth(fsys, fullPath) != nil {
+		return
+	}
+
+	items, err := fsys.ReadDir(fullPath)
+	if err != nil {
+		return
+	}
+
+	for _, item := range items {
+		f, b := MeasureInFileSystem(fsys, path.Join(fullPath, item.Name()))
+		files += f
+		bytes += b
+	}
+
+	return
+}
+
+// Reads a whole file from the file system. Fails if the file is bigger than maxSize, so that a huge file doesn't end
+// up in memory by accident.
+func ReadFromFileSystem(fsys FileSystem, fullPath string, maxSize int64) ([]byte, error) {
+	stats, err := fsys.Stat(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if stats.Size() > maxSize {
+		return nil, errors.New(path.Base(fullPath) + " is too big to be read (" + bytesToString(stats.Size()) + ")")
+	}
+
+	reader, err := fsys.Open(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(io.LimitReader(reader, maxSize))
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// FLAC files are a list of metadata blocks followed by the frames. Ogg files are pages, which carry the packets of
+// one or more streams, the first packets of a stream are its headers. Both keep their tags as Vorbis comments.
+
+const (
+	flacBlockStreamInfo    = 0
+	flacBlockVorbisComment = 4
+
+	// Large enough for the tags, the pictures that some files embed are skipped
+	maxMetadataBlockSize = 1024 * 1024
+)
+
+func readFLAC(file *os.File, result *MediaInfo) error {
+	_, err := file.Seek(4, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	result.addCodec("FLAC")
+
+	header := make([]byte, 4)
+	for {
+		_, err = io.ReadFull(file, header)
+		if err != nil {
+			return err
+		}
+
+		isLast := header[0]&0x80 != 0
+		blockType := header[0] & 0x7F
+		size := int64(header[1])<<16 | int64(header[2])<<8 | int64(header[3])
+
+		if (blockType == flacBlockStreamInfo || blockType == flacBlockVorbisComment) && size <= maxMetadataBlockSize {
+			data := make([]byte, size)
+			_, err = io.ReadFull(file, data)
+			if err != nil {
+				return err
+			}
+
+			if blockType == flacBlockStreamInfo {
+				readFLACStreamInfo(data, result)
+			} else {
+				readVorbisComments(data, result)
+			}
+		} else {
+			_, err = file.Seek(size, io.SeekCurrent)
+			if err != nil {
+				return err
+			}
+		}
+
+		if isLast {
+			return nil
+		}
+	}
+}
+
+// The stream info packs the sample rate (20 bits), the channels (3 bits), the bits per sample (5 bits) and the
+// count of samples (36 bits) after the sizes of the blocks and the frames
+func readFLACStreamInfo(data []byte, result *MediaInfo) {
+	if len(data) < 18 {
+		return
+	}
+
+	packed := binary.BigEndian.Uint64(data[10:])
+	result.SampleRate = int(packed >> 44)
+	result.Channels = int(packed>>41&0x7) + 1
+	result.BitsPerSample = int(packed>>36&0x1F) + 1
+
+	samples := packed & 0xFFFFFFFFF
+	if result.SampleRate > 0 && samples > 0 {
+		result.Duration = time.Duration(float64(samples) / float64(result.SampleRate) * float64(time.Second))
+	}
+}
+
+// Vorbis comments are a vendor string followed by "NAME=value" strings, every string is preceded by its length
+func readVorbisComments(data []byte, result *MediaInfo) {
+	readString := func() (string, bool) {
+		if len(data) < 4 {
+			return "", false
+		}
+
+		size := binary.LittleEndian.Uint32(data)
+		if uint64(size) > uint64(len(data)-4) {
+			return "", false
+		}
+
+		value := string(data[4 : 4+size])
+		data = data[4+size:]
+		return value, true
+	}
+
+	_, ok := readString()
+	if !ok || len(data) < 4 {
+		return
+	}
+
+	count := binary.LittleEndian.Uint32(data)
+	data = data[4:]
+
+	for i := uint32(0); i < count; i++ {
+		comment, ok := readString()
+		if !ok {
+			return
+		}
+
+		name, value, found := strings.Cut(comment, "=")
+		if found {
+			result.addTag(mediaTagNames[strings.ToUpper(name)], value)
+		}
+	}
+}
+
+type oggPage struct {
+	Granule  int64
+	Serial   uint32
+	Segments []byte
+	Data     []byte
+}
+
+func readOggPage(reader io.Reader) (result oggPage, err error) {
+	header := make([]byte, 27)
+	_, err = io.ReadFull(reader, header)
+	if err != nil {
+		return
+	}
+
+	if string(header[:4]) != "OggS" {
+		return result, errors.New("invalid Ogg page")
+	}
+
+	result.Granule = int64(binary.LittleEndian.Uint64(header[6:]))
+	result.Serial = binary.LittleEndian.Uint32(header[14:])
+
+	result.Segments = make([]byte, header[26])
+	_, err = io.ReadFull(reader, result.Segments)
+	if err != nil {
+		return
+	}
+
+	size := 0
+	for _, segment := range result.Segments {
+		size += int(segment)
+	}
+
+	result.Data = make([]byte, size)
+	_, err = io.ReadFull(reader, result.Data)
+	return
+}
+
+// Reads the identification header and the comment header, which are the first two packets of the first stream,
+// and the duration from the position of the last page
+func readOgg(file *os.File, size int64, result *MediaInfo) error {
+	reader := io.LimitReader(file, maxMetadataBlockSize)
+
+	var packets [][]byte
+	var packet []byte
+	var serial uint32
+	isFirst := true
+
+	for len(packets) < 2 {
+		page, err := readOggPage(reader)
+		if err != nil {
+			return err
+		}
+
+		if isFirst {
+			serial = page.Serial
+			isFirst = false
+		}
+
+		if page.Serial != serial {
+			continue
+		}
+
+		// A packet ends with a segment shorter than 255 bytes, longer packets go on in the next page
+		offset := 0
+		for _, segment := range page.Segments {
+			packet = append(packet, page.Data[offset:offset+int(segment)]...)
+			offset += int(segment)
+
+			if segment < 255 {
+				packets = append(packets, packet)
+				packet = nil
+			}
+		}
+	}
+
+	identification, comments := packets[0], packets[1]
+	sampleRate := 0
+	preSkip := int64(0)
+
+	switch {
+	case bytes.HasPrefix(identification, []byte("\x01vorbis")) && len(identification) >= 28:
+		result.addCodec("Vorbis")
+		result.Channels = int(identification[11])
+		result.SampleRate = int(binary.LittleEndian.Uint32(identification[12:]))
+		result.Bitrate = int64(int32(binary.LittleEndian.Uint32(identification[20:])))
+		sampleRate = result.SampleRate
+
+		if bytes.HasPrefix(comments, []byte("\x03vorbis")) {
+			readVorbisComments(comments[7:], result)
+		}
+	case bytes.HasPrefix(identification, []byte("OpusHead")) && len(identification) >= 16:
+		// Opus always runs at 48 kHz, the input rate is only what the file was made from
+		result.addCodec("Opus")
+		result.Channels = int(identification[9])
+		result.SampleRate = int(binary.LittleEndian.Uint32(identification[12:]))
+		preSkip = int64(binary.LittleEndian.Uint16(identification[10:]))
+		sampleRate = 48000
+
+		if bytes.HasPrefix(comments, []byte("OpusTags")) {
+			readVorbisComments(comments[8:], result)
+		}
+	default:
+		return errors.New("unsupported Ogg stream")
+	}
+
+	// A nominal bitrate of 0 or less means it isn't known
+	if result.Bitrate < 0 {
+		result.Bitrate = 0
+	}
+
+	granule := findLastOggGranule(file, size, serial)
+	if granule > preSkip && sampleRate > 0 {
+		result.Duration = time.Duration(float64(granule-preSkip) / float64(sampleRate) * float64(time.Second))
+	}
+
+	return nil
+}
+
+// The granule position of the last page of the stream is the count of samples in the whole stream
+func findLastOggGranule(file *os.File, size int64, serial uint32) int64 {
+	start := max(size-64*1024, 0)
+	data := make([]byte, size-start)
+	_, err := file.ReadAt(data, start)
+	if err != nil && err != io.EOF {
+		return 0
+	}
+
+	for index := bytes.LastIndex(data, []byte("OggS")); index >= 0; index = bytes.LastIndex(data[:index], []byte("OggS")) {
+		if index+27 > len(data) {
+			continue
+		}
+
+		granule := int64(binary.LittleEndian.Uint64(data[index+6:]))
+		if binary.LittleEndian.Uint32(data[index+14:]) == serial && granule > 0 {
+			return granule
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// The local folders shown in the views are watched, so that the items created, removed or renamed by other programs
+// show up without refreshing by hand. Programs often change many files in a row, e.g. when extracting or compiling,
+// so the events are collected and a folder is refreshed only once it has been quiet for a moment. A folder that
+// keeps changing is still refreshed every now and then.
+
+const (
+	watchQuietDelay = 200 * time.Millisecond
+	watchMaxDelay   = time.Second
+)
+
+type folderChange struct {
+	First time.Time
+	Last  time.Time
+}
+
+type FolderWatcher struct {
+	watcher *fsnotify.Watcher
+
+	mutex   sync.Mutex
+	watched map[string]bool
+	changes map[string]*folderChange
+}
+
+// Folders are compared without the trailing slash, so that a root, such as "/" or "C:/", matches the folder its items
+// are reported in
+func getWatchKey(folder string) string {
+	return strings.TrimSuffix(folder, "/")
+}
+
+// Returns nil if the platform can't watch folders, in which case the views are only refreshed by hand
+func NewFolderWatcher() *FolderWatcher {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil
+	}
+
+	result := &FolderWatcher{
+		watcher: watcher,
+		watched: make(map[string]bool),
+		changes: make(map[string]*folderChange),
+	}
+
+	go result.collect()
+
+	return result
+}
+
+func (w *FolderWatcher) collect() {
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+
+			// Writing to a file doesn't change the list of items
+			if event.Op == fsnotify.Write {
+				continue
+			}
+
+			name := filepath.ToSlash(event.Name)
+			w.markChanged(getWatchKey(path.Dir(name)))
+
+			// The watched folder itself might have been removed or renamed
+			if (event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)) && w.isWatched(name) {
+				w.markChanged(getWatchKey(name))
+			}
+		case _, ok := <-w.watcher.Errors:
+			// The events that were lost can't be recovered, the next change refreshes the view anyway
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+func (w *FolderWatcher) isWatched(fullPath string) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for folder := range w.watched {
+		if getWatchKey(folder) == getWatchKey(fullPath) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w *FolderWatcher) markChanged(folder string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	now := time.Now()
+	change, ok := w.changes[folder]
+	if !ok {
+		change = &folderChange{First: now}
+		w.changes[folder] = change
+	}
+	change.Last = now
+}
+
+// Watches exactly the given folders, the folders that were watched before and aren't in the list anymore are let go
+func (w *FolderWatcher) Watch(folders []string) {
+	wanted := make(map[string]bool, len(folders))
+	for _, folder := range folders {
+		wanted[folder] = true
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for folder := range w.watched {
+		if !wanted[folder] {
+			w.watcher.Remove(folder)
+			delete(w.watched, folder)
+		}
+	}
+
+	for folder := range wanted {
+		if w.watched[folder] {
+			continue
+		}
+
+		// Folders that can't be watched, e.g. on some network drives, are refreshed by hand. They are still marked
+		// as watched, so that adding them isn't tried again every frame.
+		w.watcher.Add(folder)
+		w.watched[folder] = true
+	}
+}
+
+// Returns the folders that changed and haven't changed again for a moment, or that have been changing for too long.
+// The folders are returned as keys, see getWatchKey.
+func (w *FolderWatcher) TakeChanged() (result []string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	now := time.Now()
+	for folder, change := range w.changes {
+		if now.Sub(change.Last) >= watchQuietDelay || now.Sub(change.First) >= watchMaxDelay {
+			result = append(result, folder)
+			delete(w.changes, folder)
+		}
+	}
+
+	return
+}
+
+func (w *FolderWatcher) Close() {
+	w.watcher.Close()
+}
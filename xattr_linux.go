@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+var errXattrNotFound = unix.ENODATA
+
+// Fails if the file system doesn't support extended attributes
+func listXattrs(fullPath string) ([]Xattr, error) {
+	size, err := unix.Listxattr(fullPath, nil)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+
+	buffer := make([]byte, size)
+	size, err = unix.Listxattr(fullPath, buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Xattr
+	for _, name := range strings.Split(string(buffer[:size]), "\x00") {
+		if name == "" {
+			continue
+		}
+
+		value, err := getXattr(fullPath, name)
+		if err != nil {
+			continue
+		}
+
+		result = append(result, Xattr{Name: name, Value: value})
+	}
+
+	return result, nil
+}
+
+func getXattr(fullPath string, name string) (string, error) {
+	size, err := unix.Getxattr(fullPath, name, nil)
+	if err != nil || size == 0 {
+		return "", err
+	}
+
+	buffer := make([]byte, size)
+	size, err = unix.Getxattr(fullPath, name, buffer)
+	if err != nil {
+		return "", err
+	}
+
+	return string(buffer[:size]), nil
+}
+
+func setXattr(fullPath string, name string, value string) error {
+	return unix.Setxattr(fullPath, name, []byte(value), 0)
+}
+
+func removeXattr(fullPath string, name string) error {
+	return unix.Removexattr(fullPath, name)
+}
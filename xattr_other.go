@@ -0,0 +1,24 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import "errors"
+
+var errXattrNotFound = errors.New("no such attribute")
+
+// Other platforms have extended attributes too, but each with its own calls, and NTFS has alternate data streams
+// instead, which are files of their own
+var errXattrsNotSupported = errors.New("extended attributes aren't supported on this platform")
+
+func listXattrs(fullPath string) ([]Xattr, error) {
+	return nil, errXattrsNotSupported
+}
+
+func setXattr(fullPath string, name string, value string) error {
+	return errXattrsNotSupported
+}
+
+func removeXattr(fullPath string, name string) error {
+	return errXattrsNotSupported
+}